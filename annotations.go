@@ -0,0 +1,52 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+ Unless required by applicable law or agreed to in writing,
+ software distributed under the License is distributed on an
+ "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ KIND, either express or implied.  See the License for the
+ specific language governing permissions and limitations
+ under the License.
+*/
+
+package gh0ffice
+
+import (
+	"time"
+
+	"github.com/WhityGhost/gh0ffice/lib/pdf"
+)
+
+// Annotation is a comment, a text markup or a link put on a page of a pdf file
+type Annotation struct {
+	Page     int       `json:"page"`
+	Type     string    `json:"type"`             // Text (sticky note), FreeText, Highlight, Underline, StrikeOut, Ink, Link...
+	Author   string    `json:"author,omitempty"` // name of the reviewer
+	Contents string    `json:"contents,omitempty"`
+	Modified time.Time `json:"modified"`
+	Text     string    `json:"text,omitempty"`     // page text highlighted, underlined, struck out or linked
+	URI      string    `json:"uri,omitempty"`      // target of a link to a web page, or file opened by a link
+	DestPage int       `json:"destPage,omitempty"` // page a link leads to in the document
+}
+
+// Read the annotations of the pages of a pdf file and insert into the interface
+func insertPDFAnnotations(data *Document, reader *pdf.Reader) {
+	for _, a := range reader.Annotations() {
+		data.Annotations = append(data.Annotations, Annotation{
+			Page:     a.Page,
+			Type:     a.Subtype,
+			Author:   a.Author,
+			Contents: a.Contents,
+			Modified: a.Modified,
+			Text:     a.Text,
+			URI:      a.URI,
+			DestPage: a.Dest,
+		})
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+ Unless required by applicable law or agreed to in writing,
+ software distributed under the License is distributed on an
+ "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ KIND, either express or implied.  See the License for the
+ specific language governing permissions and limitations
+ under the License.
+*/
+
+package gh0ffice
+
+import (
+	"os"
+	"path"
+	"time"
+
+	"github.com/WhityGhost/gh0ffice/lib/archive"
+
+	"github.com/charmbracelet/log"
+)
+
+// Read the members of an archive (*.zip, *.tar, *.tar.gz, *.tgz, *.gz) and insert them as child documents
+func insertArchiveData(data *Document, cfg *settings, depth int) (bool, error) {
+	if depth >= cfg.maxDepth {
+		if DEBUG {
+			log.Warnf("⚠️ %s: maximum nesting depth reached, members are not inspected", data.RePath)
+		}
+		return false, nil
+	}
+	if cfg.unpacked >= cfg.maxTotalSize {
+		return false, archive.ErrTotalTooLarge
+	}
+	file, err := os.Open(data.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	fileinfo, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	limits := archive.Limits{
+		MaxMembers:    cfg.maxMembers,
+		MaxMemberSize: cfg.maxMemberSize,
+		MaxTotalSize:  cfg.maxTotalSize - cfg.unpacked,
+	}
+	err = archive.Walk(file, fileinfo.Size(), data.Filename, limits, func(m archive.Member) error {
+		cfg.unpacked += int64(len(m.Data))
+		data.Children = append(data.Children, inspectChild(data, m.Name, m.Data, m.ModTime, cfg, depth+1))
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Inspect a file held in memory (e.g. an archive member) as a child of the parent document.
+// The child gets a virtual path such as "bundle.zip!/reports/q3.xlsx", its content is spooled
+// to a temporary file so that every format reader can open it by name.
+func inspectChild(parent *Document, name string, content []byte, modTime time.Time, cfg *settings, depth int) *Document {
+	filename := path.Base(name)
+	child := Document{
+		RePath:     parent.RePath + "!/" + name,
+		Filename:   filename,
+		Title:      filename,
+		Modifytime: modTime,
+		Size:       len(content),
+	}
+	tmp, err := os.CreateTemp("", "gh0ffice-*"+path.Ext(filename))
+	if err != nil {
+		if DEBUG {
+			log.Warnf("⚠️ %s", err.Error())
+		}
+		return &child
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(content)
+	tmp.Close()
+	if err != nil {
+		if DEBUG {
+			log.Warnf("⚠️ %s", err.Error())
+		}
+		return &child
+	}
+	child.path = tmp.Name()
+	if err := inspectContent(&child, cfg, depth); err != nil && DEBUG {
+		log.Warnf("⚠️ %s: %s", child.RePath, err.Error())
+	}
+	return &child
+}
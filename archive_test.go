@@ -0,0 +1,80 @@
+package gh0ffice
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/WhityGhost/gh0ffice/lib/archive"
+)
+
+// zipArchive returns a zip archive of the members, given as name and content pairs, in that order
+func zipArchive(t *testing.T, members ...string) []byte {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for i := 0; i+1 < len(members); i += 2 {
+		w, err := z.Create(members[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(members[i+1]))
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// children lists the virtual paths and contents of the documents nested into data
+func children(data *Document) string {
+	var list []string
+	var walk func(doc *Document)
+	walk = func(doc *Document) {
+		for _, child := range doc.Children {
+			list = append(list, child.RePath+"="+strings.TrimSpace(child.Content))
+			walk(child)
+		}
+	}
+	walk(data)
+	return strings.Join(list, "|")
+}
+
+func TestInsertArchiveData(t *testing.T) {
+	inner := zipArchive(t, "b.rtf", `{\rtf1 inner}`)
+	name := filepath.Join(t.TempDir(), "bundle.zip")
+	content := zipArchive(t, "notes/a.rtf", `{\rtf1 outer}`, "../up.rtf", `{\rtf1 up}`, "inner.zip", string(inner),
+		"big.rtf", `{\rtf1 `+strings.Repeat("x", 1000)+`}`)
+	if err := os.WriteFile(name, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		opts []Option
+		err  error
+		want string
+	}{
+		{nil, nil, "/bundle.zip!/notes/a.rtf=outer|/bundle.zip!/up.rtf=up|/bundle.zip!/inner.zip=|" +
+			"/bundle.zip!/inner.zip!/b.rtf=inner|/bundle.zip!/big.rtf=" + strings.Repeat("x", 1000)},
+		// the members of inner.zip are not inspected, big.rtf is skipped
+		{[]Option{WithArchiveLimits(1, 10, 500, 1<<20)}, nil,
+			"/bundle.zip!/notes/a.rtf=outer|/bundle.zip!/up.rtf=up|/bundle.zip!/inner.zip="},
+		{[]Option{WithArchiveLimits(4, 2, 500, 1<<20)}, archive.ErrTooManyMembers,
+			"/bundle.zip!/notes/a.rtf=outer|/bundle.zip!/up.rtf=up"},
+		// inner.zip and then its members count in the total, which b.rtf (13 bytes) exceeds by one byte
+		{[]Option{WithArchiveLimits(4, 10, 500, int64(13+10+len(inner)+12))}, archive.ErrTotalTooLarge,
+			"/bundle.zip!/notes/a.rtf=outer|/bundle.zip!/up.rtf=up|/bundle.zip!/inner.zip="},
+		{[]Option{WithArchiveLimits(4, 10, 500, int64(13+10+len(inner)+13))}, archive.ErrTotalTooLarge,
+			"/bundle.zip!/notes/a.rtf=outer|/bundle.zip!/up.rtf=up|/bundle.zip!/inner.zip=|/bundle.zip!/inner.zip!/b.rtf=inner"},
+	} {
+		data := Document{path: name, RePath: "/bundle.zip", Filename: "bundle.zip"}
+		err := inspectContent(&data, newSettings(c.opts), 0)
+		if err != c.err {
+			t.Errorf("%d options: error %v, want %v", len(c.opts), err, c.err)
+		}
+		if got := children(&data); got != c.want {
+			t.Errorf("%d options: got %q\nwant %q", len(c.opts), got, c.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+ Unless required by applicable law or agreed to in writing,
+ software distributed under the License is distributed on an
+ "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ KIND, either express or implied.  See the License for the
+ specific language governing permissions and limitations
+ under the License.
+*/
+
+package gh0ffice
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/WhityGhost/gh0ffice/lib"
+	"github.com/WhityGhost/gh0ffice/lib/archive"
+
+	"github.com/charmbracelet/log"
+)
+
+type EmbeddedReader func(io.ReaderAt, int64) ([]lib.EmbeddedObject, error)
+
+// Read the objects embedded into an office file (OLE objects, packages, attachments) and insert them as child documents
+func insertEmbeddedData(data *Document, cfg *settings, depth int, reader EmbeddedReader) (bool, error) {
+	if depth >= cfg.maxDepth {
+		return false, nil
+	}
+	file, err := os.Open(data.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	fileinfo, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	objects, err := reader(file, fileinfo.Size())
+	for i, obj := range objects {
+		if i >= cfg.maxMembers {
+			return false, archive.ErrTooManyMembers
+		}
+		if int64(len(obj.Data)) > cfg.maxMemberSize {
+			if DEBUG {
+				log.Warnf("⚠️ %s!/%s: embedded object exceeds size limit", data.RePath, obj.Name)
+			}
+			continue
+		}
+		if cfg.unpacked+int64(len(obj.Data)) > cfg.maxTotalSize {
+			return false, archive.ErrTotalTooLarge
+		}
+		cfg.unpacked += int64(len(obj.Data))
+		data.Children = append(data.Children, inspectChild(data, obj.Name, obj.Data, time.Time{}, cfg, depth+1))
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(objects) > 0, nil
+}
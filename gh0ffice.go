@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"html"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -48,31 +49,44 @@ var DEBUG bool = false
 
 type Document struct {
 	path           string
-	RePath         string    `json:"path"`
-	Filename       string    `json:"filename"`
-	Title          string    `json:"title"`
-	Subject        string    `json:"subject"`
-	Creator        string    `json:"creator"`
-	Keywords       string    `json:"keywords"`
-	Description    string    `json:"description"`
-	Lastmodifiedby string    `json:"lastModifiedBy"`
-	Revision       string    `json:"revision"`
-	Category       string    `json:"category"`
-	Content        string    `json:"content"`
-	Modifytime     time.Time `json:"modified"`
-	Createtime     time.Time `json:"created"`
-	Accesstime     time.Time `json:"accessed"`
-	Size           int       `json:"size"`
+	RePath         string                `json:"path"`
+	Filename       string                `json:"filename"`
+	Title          string                `json:"title"`
+	Subject        string                `json:"subject"`
+	Creator        string                `json:"creator"`
+	Keywords       string                `json:"keywords"`
+	Description    string                `json:"description"`
+	Lastmodifiedby string                `json:"lastModifiedBy"`
+	Revision       string                `json:"revision"`
+	Category       string                `json:"category"`
+	Content        string                `json:"content"`
+	Modifytime     time.Time             `json:"modified"`
+	Createtime     time.Time             `json:"created"`
+	Accesstime     time.Time             `json:"accessed"`
+	Size           int                   `json:"size"`
+	Encrypted      bool                  `json:"encrypted,omitempty"`
+	Encryption     string                `json:"encryption,omitempty"` // mechanism of an encrypted file, see the lib.Encryption* constants
+	Repairs        []string              `json:"repairs,omitempty"`    // damage worked around to read a malformed file
+	Fields         map[string]string     `json:"fields,omitempty"`     // values of the form fields of a pdf file, by qualified name
+	Annotations    []Annotation          `json:"annotations,omitempty"`
+	Outline        []Bookmark            `json:"outline,omitempty"`
+	PageLabels     []string              `json:"pageLabels,omitempty"` // label of each page of a pdf file, e.g. "iv" or "A-3"
+	HasVBAProject  bool                  `json:"hasVBAProject,omitempty"`
+	Macros         []lib.VBAProject      `json:"macros,omitempty"`
+	Images         []metagoffice.Image   `json:"images,omitempty"`
+	Threats        []lib.ThreatIndicator `json:"threats,omitempty"`
+	Children       []*Document           `json:"children,omitempty"`
 }
 
 type DocReader func(string) (string, error)
+type MetaReader func(*os.File) (metagoffice.XMLContent, error)
 
 func SetDebug(dbg bool) {
 	DEBUG = dbg
 }
 
 // Make a struct of documentation involves content and metadata, file information
-func InspectDocument(pathname string, target_abpath string) (*Document, error) {
+func InspectDocument(pathname string, target_abpath string, opts ...Option) (*Document, error) {
 	abPath, err := filepath.Abs(pathname)
 	if err != nil {
 		return nil, err
@@ -81,60 +95,250 @@ func InspectDocument(pathname string, target_abpath string) (*Document, error) {
 	rePath = strings.TrimPrefix(rePath, target_abpath)
 	filename := path.Base(pathname)
 	data := Document{path: pathname, RePath: rePath, Title: filename}
-	extension := path.Ext(pathname)
 	_, err = insertFileInfoData(&data)
 	if err != nil {
 		return &data, err
 	}
+	err = inspectContent(&data, newSettings(opts), 0)
+	if err != nil {
+		return &data, err
+	}
+	if DEBUG {
+		log.Infof("✔️ successfully read content of file: %s", data.Filename)
+		printFileInfoData(&data)
+	}
+	return &data, nil
+}
+
+// Read the metadata and content of a document according to its extension, depth is the nesting level inside containers
+func inspectContent(data *Document, cfg *settings, depth int) error {
+	var err error
+	var msg *lib.Message // read once for the content and the attachments of emails
+	extension := path.Ext(data.path)
+	if extension == ".doc" && isRTF(data.path) { // Word happily opens RTF saved with a .doc extension
+		extension = ".rtf"
+	}
+	passwords := cfg.passwordsFor(data.RePath)
+	restore, decryptErr := decryptDocument(data, passwords, extension)
+	defer restore()
+	if decryptErr != nil {
+		extension = "" // nothing can be read but the encryption, which the threat scan reports
+		err = decryptErr
+	}
+	if _, ok := ooxmlFamilies[extension]; ok { // macro-enabled, template and slideshow variants are routed by content type
+		kind, e := insertPackageData(data)
+		if e != nil && DEBUG {
+			log.Warnf("⚠️ %s", e.Error())
+		}
+		if kind == "" {
+			kind = ooxmlFamilies[extension]
+		}
+		extension = kind
+	}
 	switch extension {
 	case ".docx":
-		_, e := insertMetaData(&data)
+		_, e := insertMetaData(data, metagoffice.GetContent)
 		if e != nil && DEBUG {
 			log.Warnf("⚠️ %s", e.Error())
 		}
-		_, err = insertContentData(&data, docx2txt)
+		_, err = insertContentData(data, docx2txt)
 	case ".pptx":
-		_, e := insertMetaData(&data)
+		_, e := insertMetaData(data, metagoffice.GetContent)
 		if e != nil && DEBUG {
 			log.Warnf("⚠️ %s", e.Error())
 		}
-		_, err = insertContentData(&data, pptx2txt)
+		_, err = insertContentData(data, pptx2txt)
 	case ".xlsx":
-		_, e := insertMetaData(&data)
+		_, e := insertMetaData(data, metagoffice.GetContent)
+		if e != nil && DEBUG {
+			log.Warnf("⚠️ %s", e.Error())
+		}
+		_, err = insertContentData(data, xlsx2txt)
+	case ".odt", ".ods", ".odp":
+		_, e := insertMetaData(data, metagoffice.GetODFContent)
 		if e != nil && DEBUG {
 			log.Warnf("⚠️ %s", e.Error())
 		}
-		_, err = insertContentData(&data, xlsx2txt)
+		_, err = insertContentData(data, odf2txt)
+	case ".rtf":
+		_, err = insertRTFData(data)
+	case ".msg":
+		msg, err = insertMessageData(data, lib.ReadMSG)
+	case ".eml":
+		msg, err = insertMessageData(data, lib.ReadEML)
 	case ".pdf":
-		_, err = insertContentData(&data, pdf2txt)
+		_, err = insertPDFData(data, passwords)
 	case ".doc":
-		_, err = insertContentData(&data, doc2txt)
+		_, err = insertContentData(data, doc2txt)
 	case ".ppt":
-		_, err = insertContentData(&data, ppt2txt)
+		_, err = insertContentData(data, ppt2txt)
 	case ".xls":
-		_, err = insertContentData(&data, xls2txt)
+		_, err = insertContentData(data, xls2txt)
+	case ".zip", ".tar", ".gz", ".tgz":
+		_, err = insertArchiveData(data, cfg, depth)
+	}
+	var e error
+	if data.HasVBAProject || extension == ".doc" || extension == ".xls" {
+		_, e = insertVBAData(data)
+		if e != nil && DEBUG {
+			log.Warnf("⚠️ %s", e.Error())
+		}
+	}
+	switch extension {
+	case ".docx", ".pptx", ".xlsx":
+		_, e = insertImageData(data, cfg)
+		if e != nil && DEBUG {
+			log.Warnf("⚠️ %s", e.Error())
+		}
+		_, e = insertEmbeddedData(data, cfg, depth, lib.ExtractEmbeddedOOXML)
+	case ".doc", ".ppt", ".xls":
+		_, e = insertEmbeddedData(data, cfg, depth, lib.ExtractEmbeddedCFB)
+	case ".msg", ".eml":
+		_, e = insertEmbeddedData(data, cfg, depth, attachments(msg))
+	case ".pdf":
+		_, e = insertEmbeddedData(data, cfg, depth, func(r io.ReaderAt, size int64) ([]lib.EmbeddedObject, error) {
+			return lib.ExtractEmbeddedPDF(r, size, passwords)
+		})
+	}
+	if e != nil && DEBUG {
+		log.Warnf("⚠️ %s", e.Error())
+	}
+	if cfg.threatScan {
+		var scanner ThreatScanner
+		switch extension {
+		case ".docx", ".pptx", ".xlsx":
+			scanner = lib.ScanOOXMLThreats
+		case ".doc", ".ppt", ".xls":
+			scanner = lib.ScanCFBThreats
+		case ".pdf":
+			scanner = func(r io.ReaderAt, size int64) ([]lib.ThreatIndicator, error) {
+				return lib.ScanEncryptedPDFThreats(r, size, passwords)
+			}
+		}
+		_, e = insertThreatData(data, scanner)
+		if e != nil && DEBUG {
+			log.Warnf("⚠️ %s", e.Error())
+		}
+		insertEncryptionThreat(data, decryptErr)
+	}
+	return err
+}
+
+// Extensions of the OOXML packages and of the document family (main content type) they usually belong to
+var ooxmlFamilies = map[string]string{
+	".docx": ".docx", ".docm": ".docx", ".dotx": ".docx", ".dotm": ".docx",
+	".xlsx": ".xlsx", ".xlsm": ".xlsx", ".xltx": ".xlsx", ".xltm": ".xlsx",
+	".pptx": ".pptx", ".pptm": ".pptx", ".ppsx": ".pptx", ".ppsm": ".pptx", ".potx": ".pptx", ".potm": ".pptx",
+}
+
+// Read the content types of OOXML packages, insert whether they hold a VBA project into the interface
+// and return the extension of the document family given by the main part (empty if unknown)
+func insertPackageData(data *Document) (string, error) {
+	file, err := os.Open(data.path)
+	if err != nil {
+		return "", err
 	}
+	defer file.Close()
+	pkg, err := metagoffice.GetPackage(file)
 	if err != nil {
-		return &data, err
+		return "", err
 	}
-	if DEBUG {
-		log.Infof("✔️ successfully read content of file: %s", data.Filename)
-		printFileInfoData(&data)
+	data.HasVBAProject = pkg.HasVBAProject
+	if pkg.Kind == "" {
+		return "", nil
 	}
-	return &data, nil
+	return "." + pkg.Kind, nil
+}
+
+// Decrypt password protected documents (encrypted OOXML packages, RC4 or XOR encrypted *.doc, *.xls and *.ppt)
+// into a temporary file which stands for the document until restore is called, and record the encryption
+func decryptDocument(data *Document, passwords lib.PasswordCallback, extension string) (restore func(), err error) {
+	restore = func() {}
+	_, ooxml := ooxmlFamilies[extension]
+	if !ooxml && extension != ".doc" && extension != ".xls" && extension != ".ppt" {
+		return restore, nil
+	}
+	file, err := os.Open(data.path)
+	if err != nil {
+		return restore, err
+	}
+	defer file.Close()
+	var plain []byte
+	if ooxml {
+		if !lib.IsEncryptedOOXML(file) {
+			return restore, nil
+		}
+		data.Encrypted = true
+		plain, data.Encryption, err = lib.DecryptOOXML(file, passwords)
+	} else {
+		fileinfo, e := file.Stat()
+		if e != nil {
+			return restore, e
+		}
+		plain, data.Encryption, err = lib.DecryptCFB(file, fileinfo.Size(), passwords)
+		data.Encrypted = data.Encryption != ""
+	}
+	if err != nil || plain == nil {
+		return restore, err
+	}
+	tmp, err := os.CreateTemp("", "gh0ffice-*"+path.Ext(data.path))
+	if err != nil {
+		return restore, err
+	}
+	_, err = tmp.Write(plain)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return restore, err
+	}
+	original := data.path
+	data.path = tmp.Name()
+	return func() {
+		data.path = original
+		os.Remove(tmp.Name())
+	}, nil
+}
+
+// Read the source of the VBA macros of office files (vbaProject.bin of OOXML, Macros and _VBA_PROJECT_CUR storages
+// of *.doc and *.xls) and insert into the interface
+func insertVBAData(data *Document) (bool, error) {
+	file, err := os.Open(data.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	fileinfo, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	projects, err := lib.ExtractVBA(file, fileinfo.Size())
+	data.Macros = projects
+	if err != nil {
+		return false, err
+	}
+	if len(projects) > 0 {
+		data.HasVBAProject = true
+	}
+	return len(projects) > 0, nil
 }
 
-// Read the meta data of office files (only *.docx, *.xlsx, *.pptx) and insert into the interface
-func insertMetaData(data *Document) (bool, error) {
+// Read the meta data of office files (only *.docx, *.xlsx, *.pptx, *.odt, *.ods, *.odp) and insert into the interface
+func insertMetaData(data *Document, reader MetaReader) (bool, error) {
 	file, err := os.Open(data.path)
 	if err != nil {
 		return false, err
 	}
 	defer file.Close()
-	meta, err := metagoffice.GetContent(file)
+	meta, err := reader(file)
 	if err != nil {
 		return false, errors.New("failed to get office meta data")
 	}
+	setMetaData(data, meta)
+	return true, nil
+}
+
+// Copy the office meta data into the interface
+func setMetaData(data *Document, meta metagoffice.XMLContent) {
 	if meta.Title != "" {
 		data.Title = meta.Title
 	}
@@ -146,9 +350,71 @@ func insertMetaData(data *Document) (bool, error) {
 	data.Revision = meta.Revision
 	data.Category = meta.Category
 	data.Content = meta.Category
+}
+
+// Read the pictures of office files (only *.docx, *.xlsx, *.pptx) with their alt text and insert into the interface
+func insertImageData(data *Document, cfg *settings) (bool, error) {
+	file, err := os.Open(data.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	images, err := metagoffice.GetImages(file, cfg.imageData)
+	if err != nil {
+		return false, err
+	}
+	data.Images = images
 	return true, nil
 }
 
+// Read the text and the \info group of RTF files, parsed together, and insert into the interface
+func insertRTFData(data *Document) (bool, error) {
+	file, err := os.Open(data.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	text, info, err := lib.RTF2Text(file)
+	if err != nil {
+		return false, err
+	}
+	setMetaData(data, rtfMetaData(info))
+	data.Content = text
+	return true, nil
+}
+
+// Convert the \info group of RTF files to office meta data
+func rtfMetaData(info lib.RTFInfo) metagoffice.XMLContent {
+	var fields metagoffice.XMLContent
+	fields.Title = info.Title
+	fields.Subject = info.Subject
+	fields.Creator = info.Author
+	fields.Keywords = info.Keywords
+	fields.Description = info.Comment
+	fields.LastModifiedBy = info.Operator
+	fields.Revision = info.Version
+	fields.Category = info.Category
+	if !info.Created.IsZero() {
+		fields.Created = info.Created.Format(ISO)
+	}
+	if !info.Revised.IsZero() {
+		fields.Modified = info.Revised.Format(ISO)
+	}
+	return fields
+}
+
+// Check the signature of a file for RTF
+func isRTF(filename string) bool {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	head := make([]byte, 5)
+	n, _ := file.Read(head)
+	return lib.IsFileRTF(head[:n])
+}
+
 // Read the content of office files and insert into the interface
 func insertContentData(data *Document, reader DocReader) (bool, error) {
 	content, err := reader(data.path)
@@ -303,15 +569,69 @@ func xlsx2txt(filename string) (string, error) {
 	return rows_xlsx, nil
 }
 
-func pdf2txt(filename string) (string, error) { // BUG: Cannot get text from specific (or really malformed?) pages
-	file_pdf, data_pdf, err := pdf.Open(filename) // Read data from pdf file
+func odf2txt(filename string) (string, error) {
+	file_odf, err := os.Open(filename) // Open odt, ods or odp file
+	if err != nil {
+		return "", err
+	}
+	defer file_odf.Close()
+
+	info_odf, err := file_odf.Stat()
+	if err != nil {
+		return "", err
+	}
+	text_odf, err := lib.ODF2Text(file_odf, info_odf.Size()) // Read text from content.xml
 	if err != nil {
 		return "", err
 	}
-	defer file_pdf.Close()
+	return text_odf, nil
+}
+
+// Read the text, meta data (XMP metadata, else the Info dictionary), form field values, annotations, outline and
+// page labels of a pdf file, opening encrypted ones with the given passwords, and insert into the interface
+func insertPDFData(data *Document, passwords lib.PasswordCallback) (bool, error) {
+	file, err := os.Open(data.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	fileinfo, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	reader, mechanism, err := lib.OpenPDF(file, fileinfo.Size(), passwords)
+	data.Encrypted = mechanism != "" || err == lib.ErrPasswordRequired || err == lib.ErrInvalidPassword
+	data.Encryption = mechanism
+	if err != nil {
+		return false, err
+	}
+	data.Repairs = reader.Repairs()
+	meta := reader.Metadata()
+	if meta.Title != "" {
+		data.Title = meta.Title
+	}
+	data.Subject = meta.Subject
+	data.Creator = meta.Author
+	data.Keywords = meta.Keywords
+	for _, field := range reader.Fields() {
+		if data.Fields == nil {
+			data.Fields = map[string]string{}
+		}
+		data.Fields[field.Name] = field.Value
+	}
+	insertPDFAnnotations(data, reader)
+	insertPDFOutline(data, reader)
+	content, err := pdf2txt(reader)
+	if err != nil {
+		return false, err
+	}
+	data.Content = content
+	return true, nil
+}
 
+func pdf2txt(data_pdf *pdf.Reader) (string, error) { // BUG: Cannot get text from specific (or really malformed?) pages
 	var buff_pdf bytes.Buffer
-	bytes_pdf, err := data_pdf.GetPlainText() // Get text of entire pdf file
+	bytes_pdf, err := data_pdf.GetLayoutText() // Get text of entire pdf file, in reading order
 	if err != nil {
 		return "", err
 	}
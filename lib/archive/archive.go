@@ -0,0 +1,172 @@
+// Package archive walks the members of container files (zip, tar and gzip)
+// so that every member can be passed back through the document pipeline.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTooManyMembers = errors.New("archive: too many members")
+	ErrTotalTooLarge  = errors.New("archive: total unpacked size exceeds limit")
+)
+
+// Limits bounds the amount of work done for one archive, protecting against
+// archive bombs. A zero value disables the corresponding check.
+type Limits struct {
+	MaxMembers    int   // maximum number of regular file members to read
+	MaxMemberSize int64 // maximum unpacked size of one member, in bytes
+	MaxTotalSize  int64 // maximum unpacked size of all members together, in bytes
+}
+
+// Member is a single regular file read from an archive.
+type Member struct {
+	Name    string    // slash separated path of the member inside the archive
+	ModTime time.Time // modification time recorded in the archive
+	Data    []byte    // unpacked content
+}
+
+// WalkFunc is called for every member read from an archive. Returning an
+// error stops the walk and the error is returned to the caller.
+type WalkFunc func(m Member) error
+
+// IsArchive reports whether the file name has an extension handled by Walk.
+func IsArchive(filename string) bool {
+	return kindOf(filename) != ""
+}
+
+func kindOf(filename string) string {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tgz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	case strings.HasSuffix(lower, ".gz"):
+		return "gz"
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	}
+	return ""
+}
+
+// Walk reads the archive at r (of the given size) whose kind is deduced from
+// filename and calls fn for every regular file member, in archive order.
+// Members larger than limits.MaxMemberSize are skipped; exceeding the member
+// count or the total size stops the walk with ErrTooManyMembers or ErrTotalTooLarge.
+func Walk(r io.ReaderAt, size int64, filename string, limits Limits, fn WalkFunc) error {
+	w := &walker{limits: limits, fn: fn}
+	switch kindOf(filename) {
+	case "zip":
+		return w.walkZip(r, size)
+	case "tar":
+		return w.walkTar(io.NewSectionReader(r, 0, size))
+	case "tgz":
+		zr, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return w.walkTar(zr)
+	case "gz":
+		zr, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		name := zr.Name // original file name stored in the gzip header, if any
+		if name == "" {
+			name = strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+		}
+		return w.visit(name, zr.ModTime, zr)
+	}
+	return errors.New("archive: unsupported archive type")
+}
+
+type walker struct {
+	limits  Limits
+	fn      WalkFunc
+	members int
+	total   int64
+}
+
+func (w *walker) walkZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = w.visit(f.Name, f.Modified, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) walkTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := w.visit(hdr.Name, hdr.ModTime, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// visit reads one member while enforcing the limits. The declared size in the
+// archive headers is never trusted: the data itself is counted as it is read.
+func (w *walker) visit(name string, modTime time.Time, r io.Reader) error {
+	if w.limits.MaxMembers > 0 && w.members >= w.limits.MaxMembers {
+		return ErrTooManyMembers
+	}
+	w.members++
+
+	max := int64(-1)
+	if w.limits.MaxMemberSize > 0 {
+		max = w.limits.MaxMemberSize
+	}
+	if w.limits.MaxTotalSize > 0 && (max < 0 || w.limits.MaxTotalSize-w.total < max) {
+		max = w.limits.MaxTotalSize - w.total
+	}
+	if max >= 0 {
+		r = io.LimitReader(r, max+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if max >= 0 && int64(len(data)) > max {
+		if w.limits.MaxTotalSize > 0 && w.total+int64(len(data)) > w.limits.MaxTotalSize {
+			return ErrTotalTooLarge
+		}
+		return nil // skip oversized member, keep walking; only the members passed to fn count in the total
+	}
+	w.total += int64(len(data))
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	return w.fn(Member{Name: name, ModTime: modTime, Data: data})
+}
@@ -0,0 +1,91 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func makeZip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWalkZip(t *testing.T) {
+	data := makeZip(t, map[string]string{"reports/q3.txt": "hello", "../evil.txt": "x"})
+	names := map[string]string{}
+	err := Walk(bytes.NewReader(data), int64(len(data)), "bundle.zip", Limits{}, func(m Member) error {
+		names[m.Name] = string(m.Data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names["reports/q3.txt"] != "hello" || names["evil.txt"] != "x" || len(names) != 2 {
+		t.Fatalf("unexpected members %v", names)
+	}
+}
+
+func TestWalkTarGz(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	tw.WriteHeader(&tar.Header{Name: "a/b.txt", Mode: 0644, Size: 3, Typeflag: tar.TypeReg})
+	tw.Write([]byte("abc"))
+	tw.Close()
+	gw.Close()
+
+	var got []string
+	err := Walk(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "bundle.tar.gz", Limits{}, func(m Member) error {
+		got = append(got, m.Name+"="+string(m.Data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "a/b.txt=abc" {
+		t.Fatalf("unexpected members %v", got)
+	}
+}
+
+func TestWalkLimits(t *testing.T) {
+	data := makeZip(t, map[string]string{"a": "1234567890", "b": "12", "c": "34", "d": "56"})
+	size := int64(len(data))
+	count := 0
+	err := Walk(bytes.NewReader(data), size, "x.zip", Limits{MaxMemberSize: 5}, func(m Member) error {
+		if m.Name == "a" {
+			t.Fatal("oversized member was not skipped")
+		}
+		count++
+		return nil
+	})
+	if err != nil || count != 3 {
+		t.Fatalf("MaxMemberSize: err=%v count=%d", err, count)
+	}
+	// the skipped member does not count in the total
+	err = Walk(bytes.NewReader(data), size, "x.zip", Limits{MaxMemberSize: 5, MaxTotalSize: 11}, func(m Member) error { return nil })
+	if err != nil {
+		t.Fatalf("MaxMemberSize and MaxTotalSize: %v", err)
+	}
+	err = Walk(bytes.NewReader(data), size, "x.zip", Limits{MaxMembers: 2}, func(m Member) error { return nil })
+	if err != ErrTooManyMembers {
+		t.Fatalf("MaxMembers: expected ErrTooManyMembers, got %v", err)
+	}
+	err = Walk(bytes.NewReader(data), size, "x.zip", Limits{MaxTotalSize: 12}, func(m Member) error { return nil })
+	if err != ErrTotalTooLarge {
+		t.Fatalf("MaxTotalSize: expected ErrTotalTooLarge, got %v", err)
+	}
+}
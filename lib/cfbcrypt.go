@@ -0,0 +1,616 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ---- file cfbcrypt.go ----
+// Encrypted Word, Excel and PowerPoint binary files ([MS-OFFCRYPTO] 2.3.5 to 2.3.7): RC4 with a 40-bit
+// MD5 key, RC4 CryptoAPI and XOR obfuscation. Both ciphers keep the length of the data, so the streams
+// are decrypted in place in a copy of the compound file which the usual readers then parse.
+
+// ErrEncrypted is returned for encrypted legacy documents when no password is given
+var ErrEncrypted = fmt.Errorf("legacy document is encrypted: %w", ErrPasswordRequired)
+
+// size of the blocks after which the RC4 key is derived again
+const (
+	wordRC4Block  = 512
+	excelRC4Block = 1024
+)
+
+// records of the Excel workbook stream which are never encrypted ([MS-XLS] 2.2.10)
+var excelPlainRecords = map[uint16]bool{
+	0x0809: true, // BOF
+	0x002F: true, // FILEPASS
+	0x0194: true, // USREXCL
+	0x0195: true, // FILELOCK
+	0x00E1: true, // INTERFACEHDR
+	0x0196: true, // RRDINFO
+	0x0138: true, // RRDHEAD
+}
+
+const (
+	excelBoundSheet  = 0x0085
+	excelFilePass    = 0x002F
+	excelUnused      = 0xFFFF // record type which replaces FILEPASS once the workbook is decrypted
+	pptPlainToken    = 0xE391C05F
+	pptEncryptToken  = 0xF3D1C4DF
+	wordFibBaseSize  = 68
+	wordFEncrypted   = 0x0100
+	wordFObfuscated  = 0x8000
+	wordFWhichTblStm = 0x0200
+)
+
+// DecryptCFB returns a copy of a Word, Excel or PowerPoint binary file in which the encrypted streams are
+// decrypted and the encryption flags cleared, or nil if the file is not an encrypted compound file, and the
+// encryption mechanism (EncryptionRC4, EncryptionCryptoAPI or EncryptionXOR). Workbooks are first
+// tried with the default password of Excel, then the passwords are asked to the callback: ErrEncrypted
+// is returned if it has none (or is nil), ErrInvalidPassword if none fits.
+func DecryptCFB(r io.ReaderAt, size int64, passwords PasswordCallback) ([]byte, string, error) {
+	data := make([]byte, size)
+	if _, err := r.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, "", err
+	}
+	if !bytes.HasPrefix(data, cfbSignature) {
+		return nil, "", nil
+	}
+	dir, err := readCFBDirectory(data)
+	if err != nil {
+		return nil, "", err
+	}
+	streams := make(map[string]int)
+	for i, e := range dir {
+		if e.typ == cfbStream && len(e.path) == 0 {
+			streams[e.name] = i
+		}
+	}
+	c := &cfbCrypt{data: data, dir: dir, streams: streams, passwords: passwords}
+	if _, ok := streams["WordDocument"]; ok {
+		err = c.decryptWord()
+	} else if _, ok := streams["PowerPoint Document"]; ok {
+		err = c.decryptPowerPoint()
+	} else if _, ok := streams["Workbook"]; ok {
+		err = c.decryptWorkbook("Workbook")
+	} else if _, ok := streams["Book"]; ok {
+		err = c.decryptWorkbook("Book")
+	}
+	if err != nil || !c.decrypted {
+		return nil, c.mechanism, err
+	}
+	return data, c.mechanism, nil
+}
+
+// cfbCrypt is a compound file held in memory whose streams are decrypted in place
+type cfbCrypt struct {
+	data      []byte
+	dir       []cfbDirEntry
+	streams   map[string]int // root streams by name
+	passwords PasswordCallback
+	decrypted bool   // some stream was decrypted
+	mechanism string // see the Encryption constants
+}
+
+// stream returns the content of a root stream and where it lies in the file
+func (c *cfbCrypt) stream(name string) ([]byte, []cfbExtent, error) {
+	id, ok := c.streams[name]
+	if !ok {
+		return nil, nil, nil
+	}
+	extents, err := cfbStreamExtents(c.data, c.dir, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	content := make([]byte, 0, c.dir[id].size)
+	for _, e := range extents {
+		content = append(content, c.data[e.off:e.off+e.n]...)
+	}
+	return content, extents, nil
+}
+
+// store writes back the content of a stream read with stream
+func (c *cfbCrypt) store(content []byte, extents []cfbExtent) {
+	c.decrypted = true
+	for _, e := range extents {
+		content = content[copy(c.data[e.off:e.off+e.n], content):]
+	}
+}
+
+// decryptWord decrypts the WordDocument, table and Data streams ([MS-DOC] 2.2.6), the FibBase at the start
+// of WordDocument and the encryption header at the start of the table stream being left in clear
+func (c *cfbCrypt) decryptWord() error {
+	wordDoc, wordExtents, err := c.stream("WordDocument")
+	if err != nil || len(wordDoc) < wordFibBaseSize {
+		return err
+	}
+	flags := binary.LittleEndian.Uint16(wordDoc[0x0A:])
+	if flags&wordFEncrypted == 0 {
+		return nil
+	}
+	tableName := "0Table"
+	if flags&wordFWhichTblStm != 0 {
+		tableName = "1Table"
+	}
+	table, tableExtents, err := c.stream(tableName)
+	if err != nil {
+		return err
+	}
+	data, dataExtents, err := c.stream("Data")
+	if err != nil {
+		return err
+	}
+	lKey := binary.LittleEndian.Uint32(wordDoc[0x0E:])
+
+	var decrypt func(b []byte, skip int)
+	if flags&wordFObfuscated != 0 {
+		c.mechanism = EncryptionXOR
+		password, err := findPassword(c.passwords, ErrEncrypted, func(p string) bool { return xorVerifier(p) == uint16(lKey) })
+		if err != nil {
+			return err
+		}
+		key := xorArray(password)
+		decrypt = func(b []byte, skip int) {
+			for i := skip; i < len(b); i++ {
+				if v := b[i] ^ key[i%16]; b[i] != 0 && v != 0 { // zeros and bytes equal to the key are left as they are
+					b[i] = v
+				}
+			}
+		}
+	} else {
+		if int(lKey) > len(table) {
+			return errEncryptionInfo
+		}
+		params, err := parseRC4Header(table[:lKey])
+		if err != nil {
+			return err
+		}
+		c.mechanism = params.mechanism()
+		password, err := findPassword(c.passwords, ErrEncrypted, params.verify)
+		if err != nil {
+			return err
+		}
+		decrypt = func(b []byte, skip int) {
+			params.decrypt(password, b, 0, skip, wordRC4Block)
+		}
+	}
+
+	fibBase := append([]byte{}, wordDoc[:wordFibBaseSize]...)
+	decrypt(wordDoc, wordFibBaseSize)
+	copy(wordDoc, fibBase)
+	binary.LittleEndian.PutUint16(wordDoc[0x0A:], flags&^(wordFEncrypted|wordFObfuscated))
+	c.store(wordDoc, wordExtents)
+	if flags&wordFObfuscated == 0 {
+		decrypt(table, int(lKey))
+	} else {
+		decrypt(table, 0)
+	}
+	c.store(table, tableExtents)
+	decrypt(data, 0)
+	c.store(data, dataExtents)
+	return nil
+}
+
+// decryptWorkbook decrypts the data of the records following FILEPASS ([MS-XLS] 2.2.10), the record
+// headers, a few records and the stream position of BOUNDSHEET records being left in clear. FILEPASS
+// itself is relabelled with an unused record type so that readers skip it.
+func (c *cfbCrypt) decryptWorkbook(name string) error {
+	book, extents, err := c.stream(name)
+	if err != nil {
+		return err
+	}
+	var decrypt func(body []byte, pos int, skip int)
+	biff8 := true
+	for pos := 0; pos+4 <= len(book); {
+		id := binary.LittleEndian.Uint16(book[pos:])
+		size := int(binary.LittleEndian.Uint16(book[pos+2:]))
+		body := book[pos+4 : min(pos+4+size, len(book))]
+		switch {
+		case id == 0x0809 && len(body) >= 2 && decrypt == nil:
+			biff8 = binary.LittleEndian.Uint16(body) == 0x0600
+		case id == excelFilePass && decrypt == nil:
+			if decrypt, err = c.workbookDecrypter(body, biff8, book); err != nil {
+				return err
+			}
+			binary.LittleEndian.PutUint16(book[pos:], excelUnused)
+		case decrypt != nil && !excelPlainRecords[id]:
+			skip := 0
+			if id == excelBoundSheet {
+				skip = 4 // lbPlyPos
+			}
+			decrypt(body, pos+4, skip)
+		}
+		pos += 4 + size
+	}
+	if decrypt != nil {
+		c.store(book, extents)
+	}
+	return nil
+}
+
+// workbookDecrypter reads a FILEPASS record and returns the function which decrypts the data of a record
+// at a given position of the stream, but its first skip bytes
+func (c *cfbCrypt) workbookDecrypter(filePass []byte, biff8 bool, book []byte) (func(body []byte, pos int, skip int), error) {
+	xor := !biff8
+	if biff8 {
+		if len(filePass) < 2 {
+			return nil, errEncryptionInfo
+		}
+		xor = binary.LittleEndian.Uint16(filePass) == 0
+		filePass = filePass[2:]
+	}
+	if xor {
+		if len(filePass) < 4 {
+			return nil, errEncryptionInfo
+		}
+		verifier := binary.LittleEndian.Uint16(filePass[2:])
+		c.mechanism = EncryptionXOR
+		password, err := findPassword(c.passwords, ErrEncrypted, func(p string) bool { return xorVerifier(p) == verifier }, DefaultPassword)
+		if err != nil {
+			return nil, err
+		}
+		key := xorArray(password)
+		return func(body []byte, pos int, skip int) {
+			index := pos + len(body) // the key index of a record depends on its end
+			for i := skip; i < len(body); i++ {
+				v := body[i] ^ key[(index+i)%16]
+				body[i] = v<<3 | v>>5
+			}
+		}, nil
+	}
+	params, err := parseRC4Header(filePass)
+	if err != nil {
+		return nil, err
+	}
+	c.mechanism = params.mechanism()
+	password, err := findPassword(c.passwords, ErrEncrypted, params.verify, DefaultPassword)
+	if err != nil {
+		return nil, err
+	}
+	// the key stream runs over the whole stream, record headers included
+	stream := make([]byte, len(book))
+	params.decrypt(password, stream, 0, 0, excelRC4Block)
+	return func(body []byte, pos int, skip int) {
+		for i := skip; i < len(body); i++ {
+			body[i] ^= stream[pos+i]
+		}
+	}, nil
+}
+
+// decryptPowerPoint decrypts the persist objects of the PowerPoint Document stream ([MS-PPT] 2.3.7), each
+// with the key of its persist identifier; the user edits, persist directories and the CryptSession10Container
+// are left in clear
+func (c *cfbCrypt) decryptPowerPoint() error {
+	currentUser, userExtents, err := c.stream("Current User")
+	if err != nil || len(currentUser) < 20 || binary.LittleEndian.Uint32(currentUser[12:]) != pptEncryptToken {
+		return err
+	}
+	document, documentExtents, err := c.stream("PowerPoint Document")
+	if err != nil {
+		return err
+	}
+	reader := bytes.NewReader(document)
+	offsetLastEdit := binary.LittleEndian.Uint32(currentUser[16:])
+	userEdit, err := readRecord(reader, int64(offsetLastEdit), recordTypeUserEditAtom)
+	if err != nil {
+		return err
+	}
+	if len(userEdit.recordData) < 32 {
+		return errEncryptionInfo
+	}
+	cryptSessionID := userEdit.recordData.LongAt(28)
+	offsets, _, err := getUserEditAtomsData(bytes.NewReader(currentUser), reader)
+	if err != nil {
+		return err
+	}
+	persistDirEntries, err := getPersistDirectoryEntries(reader, offsets)
+	if err != nil {
+		return err
+	}
+	session, err := readRecord(reader, persistDirEntries[cryptSessionID], recordTypeCryptSession10Container)
+	if err != nil {
+		return err
+	}
+	params, err := parseRC4Header(session.recordData)
+	if err != nil {
+		return err
+	}
+	c.mechanism = params.mechanism()
+	password, err := findPassword(c.passwords, ErrEncrypted, params.verify)
+	if err != nil {
+		return err
+	}
+	for id, offset := range persistDirEntries {
+		if id == cryptSessionID || offset < 0 || offset+headerSize > int64(len(document)) {
+			continue
+		}
+		header := append([]byte{}, document[offset:offset+headerSize]...)
+		params.decrypt(password, header, id, 0, 0)
+		end := offset + headerSize + int64(binary.LittleEndian.Uint32(header[4:]))
+		if end > int64(len(document)) {
+			continue
+		}
+		params.decrypt(password, document[offset:end], id, 0, 0)
+	}
+	binary.LittleEndian.PutUint32(currentUser[12:], pptPlainToken)
+	c.store(document, documentExtents)
+	c.store(currentUser, userExtents)
+	return nil
+}
+
+// rc4Params is the encryption header of RC4 ([MS-OFFCRYPTO] 2.3.6.1) or RC4 CryptoAPI (2.3.5.1) encryption
+type rc4Params struct {
+	cryptoAPI    bool
+	keyBits      int
+	salt         []byte
+	verifier     []byte
+	verifierHash []byte
+}
+
+func (p *rc4Params) mechanism() string {
+	if p.cryptoAPI {
+		return EncryptionCryptoAPI
+	}
+	return EncryptionRC4
+}
+
+func parseRC4Header(b []byte) (*rc4Params, error) {
+	if len(b) < 4 {
+		return nil, errEncryptionInfo
+	}
+	major := binary.LittleEndian.Uint16(b)
+	minor := binary.LittleEndian.Uint16(b[2:])
+	switch {
+	case major == 1 && minor == 1:
+		if len(b) < 4+48 {
+			return nil, errEncryptionInfo
+		}
+		return &rc4Params{keyBits: 128, salt: b[4:20], verifier: b[20:36], verifierHash: b[36:52]}, nil
+	case (major == 2 || major == 3 || major == 4) && minor == 2:
+		if len(b) < 12 {
+			return nil, errEncryptionInfo
+		}
+		headerSize := int(binary.LittleEndian.Uint32(b[8:]))
+		if headerSize < 32 || 12+headerSize+4+16+16+4+20 > len(b) {
+			return nil, errEncryptionInfo
+		}
+		header := b[12 : 12+headerSize]
+		if algID := binary.LittleEndian.Uint32(header[8:]); algID != 0x6801 { // RC4
+			return nil, fmt.Errorf("%w: algorithm 0x%04X", errEncryptionInfo, algID)
+		}
+		keyBits := int(binary.LittleEndian.Uint32(header[16:]))
+		if keyBits == 0 {
+			keyBits = 40
+		}
+		verifier := b[12+headerSize:]
+		if binary.LittleEndian.Uint32(verifier) != 16 {
+			return nil, errEncryptionInfo
+		}
+		return &rc4Params{cryptoAPI: true, keyBits: keyBits, salt: verifier[4:20], verifier: verifier[20:36], verifierHash: verifier[40:60]}, nil
+	}
+	return nil, fmt.Errorf("%w: version %d.%d", errEncryptionInfo, major, minor)
+}
+
+// key derives the RC4 key of a block from the password
+func (p *rc4Params) key(password string, block uint32) []byte {
+	var blockLE [4]byte
+	binary.LittleEndian.PutUint32(blockLE[:], block)
+	if p.cryptoAPI {
+		h := sha1.New()
+		h.Write(p.salt)
+		h.Write(utf16LE(password))
+		h0 := h.Sum(nil)
+		h.Reset()
+		h.Write(h0)
+		h.Write(blockLE[:])
+		key := h.Sum(nil)[:p.keyBits/8]
+		if p.keyBits == 40 { // 40-bit keys are used as 128-bit keys padded with zeros
+			key = append(key, make([]byte, 11)...)
+		}
+		return key
+	}
+	h0 := md5.Sum(utf16LE(password))
+	var buf bytes.Buffer
+	for i := 0; i < 16; i++ {
+		buf.Write(h0[:5])
+		buf.Write(p.salt)
+	}
+	h1 := md5.Sum(buf.Bytes())
+	h2 := md5.Sum(append(h1[:5], blockLE[:]...))
+	return h2[:]
+}
+
+// verify tells whether the password decrypts the verifier into the value of the verifier hash
+func (p *rc4Params) verify(password string) bool {
+	c, err := rc4.NewCipher(p.key(password, 0))
+	if err != nil {
+		return false
+	}
+	verifier := make([]byte, len(p.verifier))
+	c.XORKeyStream(verifier, p.verifier)
+	hash := make([]byte, len(p.verifierHash))
+	c.XORKeyStream(hash, p.verifierHash)
+	var sum []byte
+	if p.cryptoAPI {
+		s := sha1.Sum(verifier)
+		sum = s[:]
+	} else {
+		s := md5.Sum(verifier)
+		sum = s[:]
+	}
+	return subtle.ConstantTimeCompare(sum, hash) == 1
+}
+
+// decrypt decrypts b in place, b[skip:] only, as the data starting at the beginning of block firstBlock
+// and rekeyed every blockSize bytes (0 for a single block)
+func (p *rc4Params) decrypt(password string, b []byte, firstBlock uint32, skip int, blockSize int) {
+	if blockSize == 0 {
+		blockSize = len(b)
+	}
+	for start, block := 0, firstBlock; start < len(b); start, block = start+blockSize, block+1 {
+		end := min(start+blockSize, len(b))
+		c, err := rc4.NewCipher(p.key(password, block))
+		if err != nil {
+			return
+		}
+		stream := make([]byte, end-start)
+		c.XORKeyStream(stream, stream)
+		for i := max(start, skip); i < end; i++ {
+			b[i] ^= stream[i-start]
+		}
+	}
+}
+
+// initial values and matrix of the XOR obfuscation key ([MS-OFFCRYPTO] 2.3.7.2)
+var (
+	xorInitialCode = [15]uint16{0xE1F0, 0x1D0F, 0xCC9C, 0x84C0, 0x110C, 0x0E10, 0xF1CE, 0x313E, 0x1872, 0xE139,
+		0xD40F, 0x84F9, 0x280C, 0xA96A, 0x4EC3}
+	xorMatrix = [105]uint16{
+		0xAEFC, 0x4DD9, 0x9BB2, 0x2745, 0x4E8A, 0x9D14, 0x2A09, 0x7B61, 0xF6C2, 0xFDA5, 0xEB6B, 0xC6F7, 0x9DCF, 0x2BBF,
+		0x4563, 0x8AC6, 0x05AD, 0x0B5A, 0x16B4, 0x2D68, 0x5AD0, 0x0375, 0x06EA, 0x0DD4, 0x1BA8, 0x3750, 0x6EA0, 0xDD40,
+		0xD849, 0xA0B3, 0x5147, 0xA28E, 0x553D, 0xAA7A, 0x44D5, 0x6F45, 0xDE8A, 0xAD35, 0x4A4B, 0x9496, 0x390D, 0x721A,
+		0xEB23, 0xC667, 0x9CEF, 0x29FF, 0x53FE, 0xA7FC, 0x5FD9, 0x47D3, 0x8FA6, 0x0F6D, 0x1EDA, 0x3DB4, 0x7B68, 0xF6D0,
+		0xB861, 0x60E3, 0xC1C6, 0x93AD, 0x377B, 0x6EF6, 0xDDEC, 0x45A0, 0x8B40, 0x06A1, 0x0D42, 0x1A84, 0x3508, 0x6A10,
+		0xAA51, 0x4483, 0x8906, 0x022D, 0x045A, 0x08B4, 0x1168, 0x76B4, 0xED68, 0xCAF1, 0x85C3, 0x1BA7, 0x374E, 0x6E9C,
+		0x3730, 0x6E60, 0xDCC0, 0xA9A1, 0x4363, 0x86C6, 0x1DAD, 0x3331, 0x6662, 0xCCC4, 0x89A9, 0x0373, 0x06E6, 0x0DCC,
+		0x1021, 0x2042, 0x4084, 0x8108, 0x1231, 0x2462, 0x48C4,
+	}
+	xorPad = [15]byte{0xBB, 0xFF, 0xFF, 0xBA, 0xFF, 0xFF, 0xB9, 0x80, 0x00, 0xBE, 0x0F, 0x00, 0xBF, 0x0F, 0x00}
+)
+
+// xorPassword returns the bytes of a password as used by XOR obfuscation: at most 15 single-byte characters
+func xorPassword(password string) []byte {
+	var b []byte
+	for _, r := range password {
+		if len(b) == 15 {
+			break
+		}
+		if r&0xFF != 0 {
+			b = append(b, byte(r))
+		} else {
+			b = append(b, byte(r>>8))
+		}
+	}
+	return b
+}
+
+// xorVerifier is the password verifier of XOR obfuscation ([MS-OFFCRYPTO] 2.3.7.1)
+func xorVerifier(password string) uint16 {
+	pw := xorPassword(password)
+	var verifier uint16
+	for i := len(pw); i >= 0; i-- {
+		b := byte(len(pw))
+		if i > 0 {
+			b = pw[i-1]
+		}
+		verifier = ((verifier>>14)&1 | (verifier<<1)&0x7FFF) ^ uint16(b)
+	}
+	return verifier ^ 0xCE4B
+}
+
+// xorKey is the 16-bit key of XOR obfuscation ([MS-OFFCRYPTO] 2.3.7.2)
+func xorKey(pw []byte) uint16 {
+	if len(pw) == 0 {
+		return 0
+	}
+	key := xorInitialCode[len(pw)-1]
+	element := len(xorMatrix) - 1
+	for i := len(pw) - 1; i >= 0; i-- {
+		c := pw[i]
+		for bit := 0; bit < 7; bit++ {
+			if c&0x40 != 0 {
+				key ^= xorMatrix[element]
+			}
+			c <<= 1
+			element--
+		}
+	}
+	return key
+}
+
+// xorArray is the 16-byte obfuscation array of XOR obfuscation ([MS-OFFCRYPTO] 2.3.7.2): the password
+// padded to 16 bytes, each byte combined with the high (odd positions) or low (even positions) byte of the key
+// and rotated right by one bit. Word XORs the data with it, Excel rotates the result of the XOR left by 3 bits
+// (the same as XORing the rotated data with the combined bytes rotated left by 2 bits, as other readers do).
+func xorArray(password string) [16]byte {
+	pw := xorPassword(password)
+	key := xorKey(pw)
+	var array [16]byte
+	for i := range array {
+		b := byte(0)
+		if i < len(pw) {
+			b = pw[i]
+		} else if i-len(pw) < len(xorPad) {
+			b = xorPad[i-len(pw)]
+		}
+		k := byte(key)
+		if i%2 == 1 {
+			k = byte(key >> 8)
+		}
+		b ^= k
+		array[i] = b>>1 | b<<7
+	}
+	return array
+}
+
+// cfbExtent is a run of bytes of a stream in the file
+type cfbExtent struct {
+	off, n int
+}
+
+// cfbStreamExtents returns where the content of the stream dir[id] lies in the file, following the FAT or,
+// for small streams, the mini FAT into the mini stream held by the root entry
+func cfbStreamExtents(data []byte, dir []cfbDirEntry, id int) ([]cfbExtent, error) {
+	sectorSize, fat, err := readCFBFAT(data)
+	if err != nil {
+		return nil, err
+	}
+	chain := func(start uint32, size int64) ([]cfbExtent, error) {
+		var extents []cfbExtent
+		for sn, left := start, size; left > 0; sn = fat[sn] {
+			if cfbSector(data, sectorSize, sn) == nil || int(sn) >= len(fat) || len(extents) > len(fat) {
+				return nil, errCFBInvalid
+			}
+			n := min(int64(sectorSize), left)
+			extents = append(extents, cfbExtent{(int(sn) + 1) * sectorSize, int(n)})
+			left -= n
+		}
+		return extents, nil
+	}
+	e := dir[id]
+	if e.size >= int64(binary.LittleEndian.Uint32(data[0x38:])) {
+		return chain(e.start, e.size)
+	}
+
+	miniSize := 1 << binary.LittleEndian.Uint16(data[0x20:])
+	miniStream, err := chain(dir[0].start, dir[0].size)
+	if err != nil {
+		return nil, err
+	}
+	miniFATExtents, err := chain(binary.LittleEndian.Uint32(data[0x3C:]), int64(binary.LittleEndian.Uint32(data[0x40:]))*int64(sectorSize))
+	if err != nil {
+		return nil, err
+	}
+	var miniFAT []uint32
+	for _, x := range miniFATExtents {
+		for i := 0; i+4 <= x.n; i += 4 {
+			miniFAT = append(miniFAT, binary.LittleEndian.Uint32(data[x.off+i:]))
+		}
+	}
+	var extents []cfbExtent
+	for sn, left := e.start, e.size; left > 0; sn = miniFAT[sn] {
+		pos := int(sn) * miniSize
+		if int(sn) >= len(miniFAT) || pos/sectorSize >= len(miniStream) || len(extents) > len(miniFAT) {
+			return nil, errCFBInvalid
+		}
+		n := min(int64(miniSize), left)
+		extents = append(extents, cfbExtent{miniStream[pos/sectorSize].off + pos%sectorSize, int(n)})
+		left -= n
+	}
+	return extents, nil
+}
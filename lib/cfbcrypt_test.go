@@ -0,0 +1,323 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// obfuscation array of the password "abc" as Excel applies it, from the tests of Apache POI
+var excelXORArrayABC = []byte{0xAC, 0xCC, 0xA4, 0xAB, 0xD6, 0xBA, 0xC3, 0xBA, 0xD6, 0xA3, 0x2B, 0x45, 0xD3, 0x79, 0x29, 0xBB}
+
+func rotateLeft(b byte, n int) byte {
+	return b<<n | b>>(8-n)
+}
+
+func TestXORObfuscation(t *testing.T) {
+	// [MS-XLS] 2.5.343 XORObfuscation: key 20810 and verifier 52250 for "abc"
+	if key := xorKey(xorPassword("abc")); key != 20810 {
+		t.Errorf("key %d", key)
+	}
+	if verifier := xorVerifier("abc"); verifier != 52250 {
+		t.Errorf("verifier %d", verifier)
+	}
+	array := xorArray("abc")
+	for i, b := range array {
+		if rotateLeft(b, 3) != excelXORArrayABC[i] {
+			t.Fatalf("array % X", array)
+		}
+	}
+}
+
+// testRC4Key derives the key of a block as [MS-OFFCRYPTO] 2.3.6.2 (RC4) and 2.3.5.2 (RC4 CryptoAPI) describe it
+func testRC4Key(password string, salt []byte, cryptoAPI bool, keyBits int, block uint32) []byte {
+	blockLE := binary.LittleEndian.AppendUint32(nil, block)
+	if cryptoAPI {
+		h0 := sha1.Sum(append(append([]byte{}, salt...), utf16LE(password)...))
+		h := sha1.Sum(append(h0[:], blockLE...))
+		if keyBits == 40 {
+			return append(h[:5:5], make([]byte, 11)...)
+		}
+		return h[:keyBits/8]
+	}
+	h0 := md5.Sum(utf16LE(password))
+	var intermediate []byte
+	for i := 0; i < 16; i++ {
+		intermediate = append(append(intermediate, h0[:5]...), salt...)
+	}
+	h1 := md5.Sum(intermediate)
+	h := md5.Sum(append(h1[:5:5], blockLE...))
+	return h[:]
+}
+
+// rc4Fixture is an RC4 or RC4 CryptoAPI encryption header and the keys of its password
+type rc4Fixture struct {
+	header []byte
+	key    func(block uint32) []byte
+}
+
+func newRC4Fixture(password string, cryptoAPI bool, keyBits int) rc4Fixture {
+	salt := []byte("0123456789abcdef")
+	verifier := []byte("fedcba9876543210")
+	key := func(block uint32) []byte { return testRC4Key(password, salt, cryptoAPI, keyBits, block) }
+	c, _ := rc4.NewCipher(key(0))
+	var header []byte
+	if cryptoAPI {
+		hash := sha1.Sum(verifier)
+		encrypted := append(append([]byte{}, verifier...), hash[:]...)
+		c.XORKeyStream(encrypted, encrypted)
+		header = binary.LittleEndian.AppendUint16(header, 4)
+		header = binary.LittleEndian.AppendUint16(header, 2)
+		header = binary.LittleEndian.AppendUint32(header, 0x04) // fCryptoAPI
+		header = binary.LittleEndian.AppendUint32(header, 32)
+		for _, v := range []uint32{0x04, 0, 0x6801, 0x8004, uint32(keyBits), 1, 0, 0} {
+			header = binary.LittleEndian.AppendUint32(header, v)
+		}
+		header = binary.LittleEndian.AppendUint32(header, 16)
+		header = append(append(header, salt...), encrypted[:16]...)
+		header = binary.LittleEndian.AppendUint32(header, 20)
+		header = append(header, encrypted[16:]...)
+	} else {
+		hash := md5.Sum(verifier)
+		encrypted := append(append([]byte{}, verifier...), hash[:]...)
+		c.XORKeyStream(encrypted, encrypted)
+		header = append([]byte{1, 0, 1, 0}, salt...)
+		header = append(header, encrypted...)
+	}
+	return rc4Fixture{header, key}
+}
+
+// encrypt encrypts b[skip:] with the key stream starting at the beginning of b, rekeyed every blockSize bytes
+func (f rc4Fixture) encrypt(b []byte, skip, blockSize int) {
+	for start := 0; start < len(b); start += blockSize {
+		c, _ := rc4.NewCipher(f.key(uint32(start / blockSize)))
+		stream := make([]byte, min(blockSize, len(b)-start))
+		c.XORKeyStream(stream, stream)
+		for i := range stream {
+			if start+i >= skip {
+				b[start+i] ^= stream[i]
+			}
+		}
+	}
+}
+
+// testData returns n bytes of a pattern with zeros
+func testData(n int, seed byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i*7) + seed
+	}
+	return b
+}
+
+// rootStreams returns the streams of the root storage of a compound file
+func rootStreams(t *testing.T, data []byte) map[string][]byte {
+	dir, err := readCFBDirectory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streams := map[string][]byte{}
+	for i, e := range dir {
+		if e.typ != cfbStream || len(e.path) != 0 {
+			continue
+		}
+		extents, err := cfbStreamExtents(data, dir, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var content []byte
+		for _, x := range extents {
+			content = append(content, data[x.off:x.off+x.n]...)
+		}
+		streams[e.name] = content
+	}
+	return streams
+}
+
+func checkDecrypted(t *testing.T, name string, encrypted []byte, passwords PasswordCallback, mechanism string, want map[string][]byte) {
+	t.Helper()
+	data, got, err := DecryptCFB(bytes.NewReader(encrypted), int64(len(encrypted)), passwords)
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	if got != mechanism {
+		t.Errorf("%s: mechanism %q, want %q", name, got, mechanism)
+	}
+	streams := rootStreams(t, data)
+	for stream, content := range want {
+		if !bytes.Equal(streams[stream], content) {
+			t.Errorf("%s: %s differs", name, stream)
+		}
+	}
+}
+
+func TestDecryptWord(t *testing.T) {
+	for _, c := range []struct {
+		mechanism string
+		password  string
+		rc4       rc4Fixture
+	}{
+		{EncryptionXOR, "abc", rc4Fixture{}},
+		{EncryptionRC4, "s3cret", newRC4Fixture("s3cret", false, 128)},
+		{EncryptionCryptoAPI, "s3cret", newRC4Fixture("s3cret", true, 40)},
+	} {
+		word, table, data := testData(1500, 1), testData(900, 2), testData(700, 3)
+		flags := uint16(wordFWhichTblStm)
+		if c.mechanism == EncryptionXOR {
+			binary.LittleEndian.PutUint32(word[0x0E:], uint32(xorVerifier(c.password)))
+		} else {
+			table = append(append([]byte{}, c.rc4.header...), table...)
+			binary.LittleEndian.PutUint32(word[0x0E:], uint32(len(c.rc4.header)))
+		}
+		binary.LittleEndian.PutUint16(word[0x0A:], flags)
+		plain := map[string][]byte{"WordDocument": word, "1Table": table, "Data": data}
+
+		encrypted := map[string][]byte{}
+		for name, content := range plain {
+			encrypted[name] = append([]byte{}, content...)
+		}
+		skip := map[string]int{"WordDocument": wordFibBaseSize, "1Table": 0, "Data": 0}
+		if c.mechanism == EncryptionXOR {
+			flags |= wordFEncrypted | wordFObfuscated
+			for name, b := range encrypted {
+				for i := skip[name]; i < len(b); i++ {
+					// Word XORs the bytes with the array of Excel rotated back, but zeros and the key itself
+					k := rotateLeft(excelXORArrayABC[i%16], 5)
+					if b[i] != 0 && b[i] != k {
+						b[i] ^= k
+					}
+				}
+			}
+		} else {
+			flags |= wordFEncrypted
+			skip["1Table"] = len(c.rc4.header)
+			for name, b := range encrypted {
+				c.rc4.encrypt(b, skip[name], 512) // [MS-DOC] 2.2.6.2
+			}
+		}
+		binary.LittleEndian.PutUint16(encrypted["WordDocument"][0x0A:], flags)
+		file := buildCFB(t, encrypted)
+
+		checkDecrypted(t, "word "+c.mechanism, file, Password(c.password), c.mechanism, plain)
+		if _, _, err := DecryptCFB(bytes.NewReader(file), int64(len(file)), nil); !errors.Is(err, ErrEncrypted) {
+			t.Errorf("word %s without password: %v", c.mechanism, err)
+		}
+		if _, _, err := DecryptCFB(bytes.NewReader(file), int64(len(file)), Password("wrong")); err != ErrInvalidPassword {
+			t.Errorf("word %s with a wrong password: %v", c.mechanism, err)
+		}
+	}
+}
+
+// excelRecord returns a BIFF record
+func excelRecord(id uint16, body []byte) []byte {
+	b := binary.LittleEndian.AppendUint16(nil, id)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(body)))
+	return append(b, body...)
+}
+
+func TestDecryptWorkbook(t *testing.T) {
+	for _, c := range []struct {
+		mechanism string
+		password  string
+		rc4       rc4Fixture
+	}{
+		{EncryptionXOR, "abc", rc4Fixture{}},
+		{EncryptionRC4, "s3cret", newRC4Fixture("s3cret", false, 128)},
+		{EncryptionCryptoAPI, "s3cret", newRC4Fixture("s3cret", true, 128)},
+		{EncryptionRC4, DefaultPassword, newRC4Fixture(DefaultPassword, false, 128)},
+	} {
+		var filePass []byte
+		if c.mechanism == EncryptionXOR {
+			filePass = binary.LittleEndian.AppendUint16([]byte{0, 0}, xorKey(xorPassword(c.password)))
+			filePass = binary.LittleEndian.AppendUint16(filePass, xorVerifier(c.password))
+		} else {
+			filePass = append([]byte{1, 0}, c.rc4.header...)
+		}
+		records := [][]byte{
+			excelRecord(0x0809, append([]byte{0, 6}, make([]byte, 14)...)), // BOF of BIFF8
+			excelRecord(excelFilePass, filePass),
+			excelRecord(excelBoundSheet, append([]byte{1, 2, 3, 4, 0, 0, 6, 0}, "Sheet1"...)),
+			excelRecord(0x00FC, testData(1500, 1)), // SST, across the blocks of the RC4 keys
+			excelRecord(0x00FD, testData(10, 2)),   // LABELSST
+			excelRecord(0x000A, nil),               // EOF
+		}
+		encrypted := bytes.Join(records, nil)
+		var stream []byte
+		if c.mechanism != EncryptionXOR {
+			stream = make([]byte, len(encrypted))
+			c.rc4.encrypt(stream, 0, 1024) // [MS-XLS] 2.2.10
+		}
+		for pos, i := 0, 0; i < len(records); pos, i = pos+len(records[i]), i+1 {
+			id := binary.LittleEndian.Uint16(records[i])
+			if i < 2 {
+				continue
+			}
+			skip := 0
+			if id == excelBoundSheet {
+				skip = 4
+			}
+			body := encrypted[pos+4 : pos+len(records[i])]
+			for j := skip; j < len(body); j++ {
+				if c.mechanism == EncryptionXOR {
+					body[j] = rotateLeft(body[j]^excelXORArrayABC[(pos+len(records[i])+j)%16], 5)
+				} else {
+					body[j] ^= stream[pos+4+j]
+				}
+			}
+		}
+		records[1] = excelRecord(excelUnused, filePass)
+		plain := map[string][]byte{"Workbook": bytes.Join(records, nil)}
+		file := buildCFB(t, map[string][]byte{"Workbook": encrypted})
+
+		name := "workbook " + c.mechanism + " " + c.password
+		passwords := Password(c.password)
+		if c.password == DefaultPassword {
+			passwords = nil
+		}
+		checkDecrypted(t, name, file, passwords, c.mechanism, plain)
+	}
+}
+
+func TestDecryptPowerPoint(t *testing.T) {
+	const (
+		documentID = 1
+		sessionID  = 2
+	)
+	f := newRC4Fixture("s3cret", true, 128)
+	header := func(typ recordType, length int) []byte {
+		b := binary.LittleEndian.AppendUint16([]byte{0x0F, 0}, uint16(typ))
+		return binary.LittleEndian.AppendUint32(b, uint32(length))
+	}
+	document := append(header(recordTypeDocument, 100), testData(100, 1)...)
+	session := append(header(recordTypeCryptSession10Container, len(f.header)), f.header...)
+	persistDir := append(header(recordTypePersistDirectoryAtom, 12), binary.LittleEndian.AppendUint32(nil, documentID|2<<20)...)
+	persistDir = binary.LittleEndian.AppendUint32(persistDir, 0)
+	persistDir = binary.LittleEndian.AppendUint32(persistDir, uint32(len(document)))
+	userEdit := make([]byte, 32)
+	binary.LittleEndian.PutUint32(userEdit[12:], uint32(len(document)+len(session)))
+	binary.LittleEndian.PutUint32(userEdit[16:], documentID)
+	binary.LittleEndian.PutUint32(userEdit[28:], sessionID)
+	userEdit = append(header(recordTypeUserEditAtom, len(userEdit)), userEdit...)
+	plainDocument := bytes.Join([][]byte{document, session, persistDir, userEdit}, nil)
+
+	currentUser := func(token uint32) []byte {
+		b := append(header(0x0FF6, 20), make([]byte, 20)...)
+		binary.LittleEndian.PutUint32(b[8:], 20)
+		binary.LittleEndian.PutUint32(b[12:], token)
+		binary.LittleEndian.PutUint32(b[16:], uint32(len(plainDocument)-len(userEdit)))
+		return b
+	}
+	encryptedDocument := append([]byte{}, plainDocument...)
+	// each persist object is encrypted with the key of its identifier, from the start of its record
+	c, _ := rc4.NewCipher(f.key(documentID))
+	c.XORKeyStream(encryptedDocument[:len(document)], encryptedDocument[:len(document)])
+	file := buildCFB(t, map[string][]byte{"PowerPoint Document": encryptedDocument, "Current User": currentUser(pptEncryptToken)})
+
+	checkDecrypted(t, "powerpoint", file, Password("s3cret"), EncryptionCryptoAPI,
+		map[string][]byte{"PowerPoint Document": plainDocument, "Current User": currentUser(pptPlainToken)})
+}
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"unicode/utf16"
 	"unicode/utf8"
 
+	"github.com/WhityGhost/gh0ffice/lib/ioadapters"
 	"github.com/mattetti/filebuffer"
 	"github.com/richardlehane/mscfb"
 )
@@ -29,7 +32,7 @@ type allReader interface {
 }
 
 func wrapError(e error) error {
-	return errors.New("Error processing file: " + e.Error())
+	return fmt.Errorf("Error processing file: %w", e)
 }
 
 // DOC2Text converts a standard io.Reader from a Microsoft Word .doc binary file and returns a reader (actually a bytes.Buffer) which will output the plain text found in the .doc file
@@ -64,7 +67,40 @@ func DOC2Text(r io.Reader) (io.Reader, error) {
 		return nil, wrapError(err)
 	}
 
-	return getText(wordDoc, clx)
+	return getText(wordDoc, clx, nil)
+}
+
+// DOCFieldCodes returns the instructions of the fields of a Word .doc file, e.g. `HYPERLINK "http://..."` or `DDEAUTO ...`
+func DOCFieldCodes(r io.Reader) ([]string, error) {
+	ra := ioadapters.ToReaderAt(r)
+	d, err := mscfb.New(ra)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	wordDoc, table0, table1 := getWordDocAndTables(d)
+	fib, err := getFib(wordDoc)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	table := getActiveTable(table0, table1, fib)
+	if table == nil {
+		return nil, wrapError(errTable)
+	}
+	clx, err := getClx(table, fib)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	var fields bytes.Buffer
+	if _, err := getText(wordDoc, clx, &fields); err != nil {
+		return nil, wrapError(err)
+	}
+	var codes []string
+	for _, code := range strings.Split(fields.String(), "\n") {
+		if code = strings.TrimSpace(code); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
 }
 
 func toMemoryBuffer(r io.Reader) (allReader, int64, error) {
@@ -77,7 +113,7 @@ func toMemoryBuffer(r io.Reader) (allReader, int64, error) {
 	return fb, size, nil
 }
 
-func getText(wordDoc *mscfb.File, clx *clx) (io.Reader, error) {
+func getText(wordDoc *mscfb.File, clx *clx, fields *bytes.Buffer) (io.Reader, error) {
 	var buf bytes.Buffer
 	for i := 0; i < len(clx.pcdt.PlcPcd.aPcd); i++ {
 		pcd := clx.pcdt.PlcPcd.aPcd[i]
@@ -101,13 +137,14 @@ func getText(wordDoc *mscfb.File, clx *clx) (io.Reader, error) {
 		if err != nil {
 			return nil, err
 		}
-		translateText(b, &buf, pcd.fc.fCompressed)
+		translateText(b, &buf, fields, pcd.fc.fCompressed)
 	}
 	return &buf, nil
 }
 
 // translateText translates the buffer into text. fCompressed = 0 for 16-bit Unicode, 1 = 8-bit ANSI characters.
-func translateText(b []byte, buf *bytes.Buffer, fCompressed bool) {
+// The field instructions, which are not part of the text, are written to fields (one per line) unless it is nil.
+func translateText(b []byte, buf *bytes.Buffer, fields *bytes.Buffer, fCompressed bool) {
 	u16s := make([]uint16, 1)
 	b8buf := make([]byte, 4)
 
@@ -142,13 +179,16 @@ func translateText(b []byte, buf *bytes.Buffer, fCompressed bool) {
 			isFieldChar = true
 			fieldLevel++
 			continue
-		} else if char == 0x14 {
-			isFieldChar = false
-			continue
-		} else if char == 0x15 {
+		} else if char == 0x14 || char == 0x15 {
+			if isFieldChar && fields != nil {
+				fields.WriteByte('\n')
+			}
 			isFieldChar = false
 			continue
 		} else if isFieldChar {
+			if fields != nil && char >= 32 {
+				fields.WriteRune(char)
+			}
 			continue
 		}
 
@@ -312,6 +352,9 @@ func getFib(wordDoc *mscfb.File) (*fib, error) {
 	if err != nil {
 		return nil, err
 	}
+	if getInt16(b, 0x0A)&wordFEncrypted != 0 { // the rest of the FIB is encrypted
+		return nil, ErrEncrypted
+	}
 
 	fibBase := getFibBase(b[0:32])
 
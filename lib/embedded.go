@@ -0,0 +1,637 @@
+package lib
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// ---- file embedded.go ----
+// Embedded OLE objects, packages and attachments stored inside OOXML packages, legacy compound files and
+// PDF files.
+
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+var errCFBInvalid = errors.New("invalid compound file")
+
+// EmbeddedObject is a file embedded into a host document
+type EmbeddedObject struct {
+	Name string // path of the object inside the host, e.g. "word/embeddings/oleObject1.bin!/report.pdf"
+	Data []byte // content of the object, unwrapped from its OLE container
+
+	Packaged bool // the object was wrapped by the OLE Packager (\x01Ole10Native), which runs it when activated
+}
+
+// ExtractEmbeddedOOXML returns the objects stored in the embeddings folders (word/embeddings, ppt/embeddings,
+// xl/embeddings) of an OOXML package. OLE objects (oleObject*.bin) are unwrapped to the file they contain.
+func ExtractEmbeddedOOXML(r io.ReaderAt, size int64) ([]EmbeddedObject, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	var objects []EmbeddedObject
+	for _, f := range z.File {
+		if path.Base(path.Dir(f.Name)) != "embeddings" || f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return objects, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return objects, err
+		}
+		if bytes.HasPrefix(data, cfbSignature) {
+			objects = append(objects, unwrapOLE(f.Name, data)...)
+		} else {
+			objects = append(objects, EmbeddedObject{Name: f.Name, Data: data})
+		}
+	}
+	return objects, nil
+}
+
+// ExtractEmbeddedCFB returns the objects embedded into a legacy document: the ObjectPool storages of Word,
+// the MBD storages of Excel and the ExOleObjStg records of PowerPoint
+func ExtractEmbeddedCFB(r io.ReaderAt, size int64) ([]EmbeddedObject, error) {
+	data := make([]byte, size)
+	if _, err := r.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	dir, err := readCFBDirectory(data)
+	if err != nil {
+		return nil, err
+	}
+	var objects []EmbeddedObject
+	for i, e := range dir {
+		if e.typ != cfbStorage {
+			continue
+		}
+		location := strings.Join(append(append([]string{}, e.path...), e.name), "/")
+		isWordObject := len(e.path) == 1 && e.path[0] == "ObjectPool" && strings.HasPrefix(e.name, "_")
+		isExcelObject := len(e.path) == 0 && strings.HasPrefix(e.name, "MBD")
+		if !isWordObject && !isExcelObject {
+			continue
+		}
+		objects = append(objects, unwrapOLE(location, rerootCFB(data, dir, i))...)
+	}
+
+	d, err := mscfb.New(bytes.NewReader(data))
+	if err != nil {
+		return objects, err
+	}
+	for _, f := range d.File {
+		if f.Name == "PowerPoint Document" && len(f.Path) == 0 {
+			stg, err := readExOleObjStgs(f)
+			if err != nil {
+				return objects, err
+			}
+			objects = append(objects, stg...)
+		}
+	}
+	return objects, nil
+}
+
+// ExtractEmbeddedPDF returns the files stored in a PDF file: the associated files of the document
+// and of its pages (named "pageN/" followed by the file name) and the attachments of the EmbeddedFiles
+// name tree. Encrypted files are opened with the passwords of the callback.
+func ExtractEmbeddedPDF(r io.ReaderAt, size int64, passwords PasswordCallback) ([]EmbeddedObject, error) {
+	reader, _, err := OpenPDF(r, size, passwords)
+	if err != nil {
+		return nil, err
+	}
+	var objects []EmbeddedObject
+	for i, f := range reader.EmbeddedFiles() {
+		rc := f.V.Reader()
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil && len(data) == 0 {
+			continue
+		}
+		name := path.Base(strings.ReplaceAll(f.Name, "\\", "/"))
+		if name == "." || name == "/" {
+			name = fmt.Sprintf("file%d%s", i+1, sniffExtension(data))
+		}
+		if f.Page > 0 {
+			name = fmt.Sprintf("page%d/%s", f.Page, name)
+		}
+		objects = append(objects, EmbeddedObject{Name: name, Data: data})
+	}
+	return objects, nil
+}
+
+// readExOleObjStgs walks the top-level records of the "PowerPoint Document" stream and unwraps the compound
+// files stored in ExOleObjStg records ([MS-PPT] 2.10.34 ExOleObjStg), numbered from 1 in the order of the
+// records ("ExOleObjStg1", "ExOleObjStg2"...)
+func readExOleObjStgs(pptDocument *mscfb.File) ([]EmbeddedObject, error) {
+	const compressedInstance = 1
+	var objects []EmbeddedObject
+	n := 0
+	for offset := int64(0); offset+headerSize <= pptDocument.Size; {
+		rec, err := readRecordHeaderOnly(pptDocument, offset, recordTypeUnspecified)
+		if err != nil {
+			return objects, err
+		}
+		if rec.Type() == recordTypeExOleObjStg {
+			rec, err = readRecord(pptDocument, offset, recordTypeExOleObjStg)
+			if err != nil {
+				return objects, err
+			}
+			data := rec.Data()
+			if binary.LittleEndian.Uint16(rec.header[0:2])>>4 == compressedInstance && len(data) > 4 {
+				zr, err := zlib.NewReader(bytes.NewReader(data[4:])) // skip decompressedSize
+				if err != nil {
+					return objects, err
+				}
+				data, err = io.ReadAll(zr)
+				if err != nil {
+					return objects, err
+				}
+			}
+			n++
+			objects = append(objects, unwrapOLE(fmt.Sprintf("ExOleObjStg%d", n), data)...)
+		}
+		offset += int64(rec.Length()) + headerSize
+	}
+	return objects, nil
+}
+
+// unwrapOLE returns the file held by an OLE object storage: the file of a packager object (\x01Ole10Native),
+// the raw content of a "Package" or "CONTENTS" stream, or the compound file itself for native Office objects
+func unwrapOLE(location string, data []byte) []EmbeddedObject {
+	d, err := mscfb.New(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	var objects []EmbeddedObject
+	for _, f := range d.File {
+		if len(f.Path) != 0 || f.FileInfo().IsDir() {
+			continue
+		}
+		switch {
+		case f.Name == "Ole10Native" && f.Initial == 0x01:
+			stream, err := io.ReadAll(f) // grown with the data, the size in the directory is not to be trusted
+			if err != nil {
+				continue
+			}
+			name, content := parseOle10Native(stream)
+			objects = append(objects, EmbeddedObject{Name: location + "!/" + name, Data: content, Packaged: true})
+		case f.Name == "Package" || f.Name == "CONTENTS":
+			stream, err := io.ReadAll(f)
+			if err != nil {
+				continue
+			}
+			objects = append(objects, EmbeddedObject{Name: location + "!/object" + sniffExtension(stream), Data: stream})
+		case f.Name == "WordDocument":
+			objects = append(objects, EmbeddedObject{Name: location + "!/object.doc", Data: data})
+		case f.Name == "Workbook" || f.Name == "Book":
+			objects = append(objects, EmbeddedObject{Name: location + "!/object.xls", Data: data})
+		case f.Name == "PowerPoint Document":
+			objects = append(objects, EmbeddedObject{Name: location + "!/object.ppt", Data: data})
+		}
+	}
+	return objects
+}
+
+// parseOle10Native reads the OLE Packager format of a \x01Ole10Native stream and returns the original
+// file name and content. Streams of other native formats are returned whole, without a known name.
+func parseOle10Native(stream []byte) (string, []byte) {
+	if len(stream) < 6 {
+		return "object.bin", stream
+	}
+	size := int(binary.LittleEndian.Uint32(stream))
+	b := stream[4:]
+	if size <= len(b) {
+		b = b[:size]
+	}
+	raw := b
+	if len(b) < 2 {
+		return "object.bin", raw
+	}
+
+	readString := func() (string, bool) {
+		i := bytes.IndexByte(b, 0)
+		if i < 0 {
+			return "", false
+		}
+		s := string(b[:i])
+		b = b[i+1:]
+		return s, true
+	}
+	b = b[2:] // type of the packager object
+	label, ok1 := readString()
+	srcPath, ok2 := readString()
+	if !ok1 || !ok2 || len(b) < 8 {
+		return "object" + sniffExtension(raw), raw
+	}
+	b = b[8:] // unknown, temporary path length
+	_, ok3 := readString()
+	if !ok3 || len(b) < 4 {
+		return "object" + sniffExtension(raw), raw
+	}
+	n := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+	if n > len(b) {
+		n = len(b)
+	}
+	name := path.Base(strings.ReplaceAll(srcPath, "\\", "/"))
+	if name == "" || name == "." || name == "/" {
+		name = label
+	}
+	if name == "" {
+		name = "object" + sniffExtension(b[:n])
+	}
+	return name, b[:n]
+}
+
+// extensions of the OpenDocument mimetypes
+var odfExtensions = map[string]string{
+	"application/vnd.oasis.opendocument.text":         ".odt",
+	"application/vnd.oasis.opendocument.spreadsheet":  ".ods",
+	"application/vnd.oasis.opendocument.presentation": ".odp",
+}
+
+// odfMimetype reads the mimetype member which OpenDocument packages store first
+func odfMimetype(f *zip.File) string {
+	rc, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	b, _ := io.ReadAll(io.LimitReader(rc, 128))
+	return strings.TrimSpace(string(b))
+}
+
+// sniffExtension guesses the file extension of data from its signature, it returns ".bin" when unknown
+func sniffExtension(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return ".pdf"
+	case bytes.HasPrefix(data, []byte(`{\rtf`)):
+		return ".rtf"
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return ".zip"
+		}
+		for _, f := range z.File {
+			switch {
+			case strings.HasPrefix(f.Name, "word/"):
+				return ".docx"
+			case strings.HasPrefix(f.Name, "xl/"):
+				return ".xlsx"
+			case strings.HasPrefix(f.Name, "ppt/"):
+				return ".pptx"
+			case f.Name == "mimetype":
+				if ext, ok := odfExtensions[odfMimetype(f)]; ok {
+					return ext
+				}
+			}
+		}
+		return ".zip"
+	case bytes.HasPrefix(data, cfbSignature):
+		d, err := mscfb.New(bytes.NewReader(data))
+		if err != nil {
+			return ".bin"
+		}
+		for _, f := range d.File {
+			switch f.Name {
+			case "WordDocument":
+				return ".doc"
+			case "Workbook", "Book":
+				return ".xls"
+			case "PowerPoint Document":
+				return ".ppt"
+			case "__properties_version1.0":
+				return ".msg"
+			}
+		}
+	}
+	return ".bin"
+}
+
+// ---- compound file directory ----
+// mscfb does not expose directory entry identifiers, which are needed to turn an embedded storage into
+// a standalone compound file, so the directory is read here ([MS-CFB] 2.6 Compound File Directory Sectors)
+
+const (
+	cfbStorage       = 1
+	cfbStream        = 2
+	cfbRootStorage   = 5
+	cfbDirEntrySize  = 128
+	cfbNoStream      = 0xFFFFFFFF
+	cfbEndOfChain    = 0xFFFFFFFE
+	cfbMaxRegSector  = 0xFFFFFFFA
+	cfbHeaderDIFATs  = 109
+	cfbChildIDOffset = 0x4C
+)
+
+type cfbDirEntry struct {
+	name   string
+	typ    byte
+	left   uint32
+	right  uint32
+	child  uint32
+	start  uint32   // first sector of the stream, in the mini stream if size is below the cutoff
+	size   int64    // size of the stream
+	offset int      // position of the entry in the file
+	path   []string // names of the parent storages (empty for entries of the root storage)
+}
+
+// readCFBFAT parses the header and the FAT of a compound file held in memory
+func readCFBFAT(data []byte) (sectorSize int, fat []uint32, err error) {
+	if len(data) < 512 || !bytes.HasPrefix(data, cfbSignature) {
+		return 0, nil, errCFBInvalid
+	}
+	shift := binary.LittleEndian.Uint16(data[0x1E:])
+	if shift != 9 && shift != 12 {
+		return 0, nil, errCFBInvalid
+	}
+	sectorSize = 1 << shift
+	sectorAt := func(sn uint32) []byte { return cfbSector(data, sectorSize, sn) }
+
+	// DIFAT: the first 109 FAT sector locations are in the header, the others in a chain of DIFAT sectors
+	var fatSectors []uint32
+	for i := 0; i < cfbHeaderDIFATs; i++ {
+		fatSectors = append(fatSectors, binary.LittleEndian.Uint32(data[0x4C+4*i:]))
+	}
+	numDIFAT := binary.LittleEndian.Uint32(data[0x48:])
+	for sn, i := binary.LittleEndian.Uint32(data[0x44:]), uint32(0); sn <= cfbMaxRegSector && i < numDIFAT; i++ {
+		sector := sectorAt(sn)
+		if sector == nil {
+			return 0, nil, errCFBInvalid
+		}
+		for j := 0; j < sectorSize/4-1; j++ {
+			fatSectors = append(fatSectors, binary.LittleEndian.Uint32(sector[4*j:]))
+		}
+		sn = binary.LittleEndian.Uint32(sector[sectorSize-4:])
+	}
+	for _, sn := range fatSectors {
+		if sn > cfbMaxRegSector {
+			continue
+		}
+		sector := sectorAt(sn)
+		if sector == nil {
+			return 0, nil, errCFBInvalid
+		}
+		for j := 0; j < sectorSize/4; j++ {
+			fat = append(fat, binary.LittleEndian.Uint32(sector[4*j:]))
+		}
+	}
+	return sectorSize, fat, nil
+}
+
+// cfbSector returns the sector sn of a compound file, nil if it is out of the file
+func cfbSector(data []byte, sectorSize int, sn uint32) []byte {
+	off := (int(sn) + 1) * sectorSize
+	if sn > cfbMaxRegSector || off < 0 || off+sectorSize > len(data) {
+		return nil
+	}
+	return data[off : off+sectorSize]
+}
+
+// readCFBDirectory parses the header, FAT and directory of a compound file held in memory
+func readCFBDirectory(data []byte) ([]cfbDirEntry, error) {
+	sectorSize, fat, err := readCFBFAT(data)
+	if err != nil {
+		return nil, err
+	}
+	var dir []cfbDirEntry
+	for sn, n := binary.LittleEndian.Uint32(data[0x30:]), 0; sn != cfbEndOfChain; n++ {
+		sector := cfbSector(data, sectorSize, sn)
+		if sector == nil || n > len(fat) {
+			return nil, errCFBInvalid
+		}
+		base := (int(sn) + 1) * sectorSize
+		for off := 0; off < sectorSize; off += cfbDirEntrySize {
+			e := sector[off : off+cfbDirEntrySize]
+			nameLength := int(binary.LittleEndian.Uint16(e[0x40:]))
+			if nameLength > 64 {
+				nameLength = 64
+			}
+			u16 := make([]uint16, 0, 32)
+			for k := 0; k+1 < nameLength-2; k += 2 {
+				u16 = append(u16, binary.LittleEndian.Uint16(e[k:]))
+			}
+			dir = append(dir, cfbDirEntry{
+				name:   strings.TrimLeft(string(utf16.Decode(u16)), "\x01\x02\x03\x04\x05"),
+				typ:    e[0x42],
+				left:   binary.LittleEndian.Uint32(e[0x44:]),
+				right:  binary.LittleEndian.Uint32(e[0x48:]),
+				child:  binary.LittleEndian.Uint32(e[cfbChildIDOffset:]),
+				start:  binary.LittleEndian.Uint32(e[0x74:]),
+				size:   int64(binary.LittleEndian.Uint32(e[0x78:])), // version 3 files may leave garbage in the high part
+				offset: base + off,
+			})
+		}
+		if int(sn) >= len(fat) {
+			return nil, errCFBInvalid
+		}
+		sn = fat[sn]
+	}
+	if len(dir) == 0 {
+		return nil, errCFBInvalid
+	}
+
+	// resolve the storage path of every entry reachable from the root
+	visited := make([]bool, len(dir))
+	var walk func(id uint32, parents []string)
+	walk = func(id uint32, parents []string) {
+		if id == cfbNoStream || int(id) >= len(dir) || visited[id] {
+			return
+		}
+		visited[id] = true
+		e := &dir[id]
+		e.path = parents
+		walk(e.left, parents)
+		walk(e.right, parents)
+		walk(e.child, append(append([]string{}, parents...), e.name))
+	}
+	visited[0] = true
+	walk(dir[0].child, nil)
+	for i := range dir {
+		if !visited[i] {
+			dir[i].typ = 0 // unreachable entries are treated as unused
+		}
+	}
+	return dir, nil
+}
+
+// rerootCFB returns the storage dir[id] of a compound file as a standalone compound file, so that an
+// embedded object can be read on its own: the entries below the storage are copied with their names,
+// CLSID and times into a new file which holds only their streams, under a root entry which takes the
+// CLSID of the storage. It returns nil if the directory or a stream is malformed.
+func rerootCFB(data []byte, dir []cfbDirEntry, id int) []byte {
+	var entries, streams [][]byte
+	seen := make([]bool, len(dir))
+	ok := true
+	var copyEntry func(id uint32) uint32
+	copyEntry = func(id uint32) uint32 {
+		// links to entries already copied are cut, so that the copy is a tree
+		if id == cfbNoStream || int(id) >= len(dir) || seen[id] || dir[id].typ == 0 {
+			return cfbNoStream
+		}
+		seen[id] = true
+		n := len(entries)
+		e := make([]byte, cfbDirEntrySize)
+		copy(e, data[dir[id].offset:])
+		entries = append(entries, e)
+		streams = append(streams, nil)
+		if dir[id].typ == cfbStream {
+			extents, err := cfbStreamExtents(data, dir, int(id))
+			if err != nil {
+				ok = false
+			}
+			var content []byte
+			for _, x := range extents {
+				content = append(content, data[x.off:x.off+x.n]...)
+			}
+			streams[n] = content
+		}
+		binary.LittleEndian.PutUint32(e[0x44:], copyEntry(dir[id].left))
+		binary.LittleEndian.PutUint32(e[0x48:], copyEntry(dir[id].right))
+		binary.LittleEndian.PutUint32(e[cfbChildIDOffset:], copyEntry(dir[id].child))
+		return uint32(n)
+	}
+	root := make([]byte, cfbDirEntrySize)
+	copy(root, data[dir[id].offset:])
+	clear(root[:0x40])
+	for i, c := range "Root Entry" {
+		binary.LittleEndian.PutUint16(root[2*i:], uint16(c))
+	}
+	binary.LittleEndian.PutUint16(root[0x40:], uint16(2*len("Root Entry")+2))
+	root[0x42] = cfbRootStorage
+	binary.LittleEndian.PutUint32(root[0x44:], cfbNoStream)
+	binary.LittleEndian.PutUint32(root[0x48:], cfbNoStream)
+	entries = append(entries, root)
+	streams = append(streams, nil)
+	seen[id] = true
+	binary.LittleEndian.PutUint32(root[cfbChildIDOffset:], copyEntry(dir[id].child))
+	if !ok {
+		return nil
+	}
+	return writeCFB(entries, streams)
+}
+
+// writeCFB returns a version 4 compound file (4096 bytes sectors) of the given directory entries, the
+// first one the root storage, and of the content of the streams of the entries. The sibling and child
+// links of the entries are kept, their first sector and size are set. It returns nil if the FAT does
+// not fit into the header, which leaves some 450 MB of data.
+func writeCFB(entries [][]byte, streams [][]byte) []byte {
+	const (
+		sectorSize     = 4096
+		miniSectorSize = 64
+		miniCutoff     = 4096
+		freeSector     = 0xFFFFFFFF
+		fatSector      = 0xFFFFFFFD
+	)
+	var (
+		body       bytes.Buffer
+		fat        []uint32
+		miniFAT    []uint32
+		miniStream bytes.Buffer
+	)
+	// chain appends the content to a stream of sectors of the given size and returns its first sector
+	chain := func(buf *bytes.Buffer, table *[]uint32, size int, content []byte) uint32 {
+		if len(content) == 0 {
+			return cfbEndOfChain
+		}
+		start := uint32(len(*table))
+		n := (len(content) + size - 1) / size
+		for i := 1; i < n; i++ {
+			*table = append(*table, start+uint32(i))
+		}
+		*table = append(*table, cfbEndOfChain)
+		buf.Write(content)
+		buf.Write(make([]byte, n*size-len(content)))
+		return start
+	}
+	uint32s := func(values []uint32) []byte {
+		b := make([]byte, 4*len(values))
+		for i, v := range values {
+			binary.LittleEndian.PutUint32(b[4*i:], v)
+		}
+		return b
+	}
+
+	for i, e := range entries {
+		if e[0x42] != cfbStream {
+			clear(e[0x74:0x80])
+			continue
+		}
+		if len(streams[i]) < miniCutoff {
+			binary.LittleEndian.PutUint32(e[0x74:], chain(&miniStream, &miniFAT, miniSectorSize, streams[i]))
+		}
+		binary.LittleEndian.PutUint64(e[0x78:], uint64(len(streams[i])))
+	}
+	for len(miniFAT)%(sectorSize/4) != 0 {
+		miniFAT = append(miniFAT, freeSector)
+	}
+	miniFATStart := chain(&body, &fat, sectorSize, uint32s(miniFAT))
+	binary.LittleEndian.PutUint32(entries[0][0x74:], chain(&body, &fat, sectorSize, miniStream.Bytes()))
+	binary.LittleEndian.PutUint64(entries[0][0x78:], uint64(miniStream.Len()))
+	for i, e := range entries {
+		if e[0x42] == cfbStream && len(streams[i]) >= miniCutoff {
+			binary.LittleEndian.PutUint32(e[0x74:], chain(&body, &fat, sectorSize, streams[i]))
+		}
+	}
+	directory := bytes.Join(entries, nil)
+	for len(directory)%sectorSize != 0 {
+		unused := make([]byte, cfbDirEntrySize)
+		binary.LittleEndian.PutUint32(unused[0x44:], cfbNoStream)
+		binary.LittleEndian.PutUint32(unused[0x48:], cfbNoStream)
+		binary.LittleEndian.PutUint32(unused[cfbChildIDOffset:], cfbNoStream)
+		directory = append(directory, unused...)
+	}
+	dirStart := chain(&body, &fat, sectorSize, directory)
+
+	// the FAT covers its own sectors
+	numFAT := (len(fat) + sectorSize/4 - 1) / (sectorSize / 4)
+	for (len(fat)+numFAT+sectorSize/4-1)/(sectorSize/4) > numFAT {
+		numFAT++
+	}
+	if numFAT > cfbHeaderDIFATs {
+		return nil
+	}
+	fatStart := uint32(len(fat))
+	for i := 0; i < numFAT; i++ {
+		fat = append(fat, fatSector)
+	}
+	for len(fat)%(sectorSize/4) != 0 {
+		fat = append(fat, freeSector)
+	}
+	body.Write(uint32s(fat))
+
+	header := make([]byte, sectorSize)
+	copy(header, cfbSignature)
+	binary.LittleEndian.PutUint16(header[0x18:], 0x3E) // minor version
+	binary.LittleEndian.PutUint16(header[0x1A:], 4)    // major version
+	binary.LittleEndian.PutUint16(header[0x1C:], 0xFFFE)
+	binary.LittleEndian.PutUint16(header[0x1E:], 12) // sector shift
+	binary.LittleEndian.PutUint16(header[0x20:], 6)  // mini sector shift
+	binary.LittleEndian.PutUint32(header[0x28:], uint32(len(directory)/sectorSize))
+	binary.LittleEndian.PutUint32(header[0x2C:], uint32(numFAT))
+	binary.LittleEndian.PutUint32(header[0x30:], dirStart)
+	binary.LittleEndian.PutUint32(header[0x38:], miniCutoff)
+	binary.LittleEndian.PutUint32(header[0x3C:], miniFATStart)
+	binary.LittleEndian.PutUint32(header[0x40:], uint32(len(miniFAT)*4/sectorSize))
+	binary.LittleEndian.PutUint32(header[0x44:], cfbEndOfChain)
+	for i := 0; i < cfbHeaderDIFATs; i++ {
+		sn := uint32(freeSector)
+		if i < numFAT {
+			sn = fatStart + uint32(i)
+		}
+		binary.LittleEndian.PutUint32(header[0x4C+4*i:], sn)
+	}
+	return append(header, body.Bytes()...)
+}
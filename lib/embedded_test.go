@@ -0,0 +1,214 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// buildCFB returns a compound file of the given streams, by path with "/" between the storages
+func buildCFB(t *testing.T, files map[string][]byte) []byte {
+	type node struct {
+		name  string
+		data  []byte
+		kids  map[string]*node
+		isDir bool
+	}
+	root := &node{kids: map[string]*node{}, isDir: true}
+	for p, data := range files {
+		n := root
+		parts := strings.Split(p, "/")
+		for _, part := range parts[:len(parts)-1] {
+			if n.kids[part] == nil {
+				n.kids[part] = &node{name: part, kids: map[string]*node{}, isDir: true}
+			}
+			n = n.kids[part]
+		}
+		n.kids[parts[len(parts)-1]] = &node{name: parts[len(parts)-1], data: data}
+	}
+	var entries, streams [][]byte
+	var add func(n *node) uint32
+	add = func(n *node) uint32 {
+		id := uint32(len(entries))
+		e := make([]byte, cfbDirEntrySize)
+		name := utf16.Encode([]rune(n.name))
+		for i, c := range name {
+			binary.LittleEndian.PutUint16(e[2*i:], c)
+		}
+		binary.LittleEndian.PutUint16(e[0x40:], uint16(2*len(name)+2))
+		e[0x42], e[0x43] = cfbStream, 1 // black
+		if n.isDir {
+			e[0x42] = cfbStorage
+		}
+		for _, off := range []int{0x44, 0x48, cfbChildIDOffset} {
+			binary.LittleEndian.PutUint32(e[off:], cfbNoStream)
+		}
+		entries = append(entries, e)
+		streams = append(streams, n.data)
+		// the siblings make a balanced tree, in the order of [MS-CFB] 2.6.4: shorter names first
+		var kids []*node
+		for _, k := range n.kids {
+			kids = append(kids, k)
+		}
+		slices.SortFunc(kids, func(a, b *node) int {
+			if len(a.name) != len(b.name) {
+				return len(a.name) - len(b.name)
+			}
+			return strings.Compare(strings.ToUpper(a.name), strings.ToUpper(b.name))
+		})
+		var tree func(kids []*node) uint32
+		tree = func(kids []*node) uint32 {
+			if len(kids) == 0 {
+				return cfbNoStream
+			}
+			mid := len(kids) / 2
+			kid := add(kids[mid])
+			binary.LittleEndian.PutUint32(entries[kid][0x44:], tree(kids[:mid]))
+			binary.LittleEndian.PutUint32(entries[kid][0x48:], tree(kids[mid+1:]))
+			return kid
+		}
+		binary.LittleEndian.PutUint32(e[cfbChildIDOffset:], tree(kids))
+		return id
+	}
+	add(root)
+	entries[0][0x42] = cfbRootStorage
+	data := writeCFB(entries, streams)
+	if data == nil {
+		t.Fatal("compound file too large")
+	}
+	return data
+}
+
+// ole10Native returns the \x01Ole10Native stream of a file wrapped by the OLE Packager
+func ole10Native(name string, content []byte) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, uint16(2))
+	b.WriteString(name + "\x00" + `C:\` + name + "\x00")
+	binary.Write(&b, binary.LittleEndian, uint32(0x00030000))
+	binary.Write(&b, binary.LittleEndian, uint32(len(name)+4))
+	b.WriteString(`C:\` + name + "\x00")
+	binary.Write(&b, binary.LittleEndian, uint32(len(content)))
+	b.Write(content)
+	stream := binary.LittleEndian.AppendUint32(nil, uint32(b.Len()))
+	return append(stream, b.Bytes()...)
+}
+
+func TestExtractEmbeddedCFBWord(t *testing.T) {
+	large := bytes.Repeat([]byte("0123456789"), 1000) // in regular sectors, not in the mini stream
+	host := buildCFB(t, map[string][]byte{
+		"WordDocument":                    bytes.Repeat([]byte{0}, 200000),
+		"1Table":                          []byte("table"),
+		"ObjectPool/_1/\x01Ole10Native":   ole10Native("hello.txt", []byte("hello")),
+		"ObjectPool/_1/\x01CompObj":       []byte("compobj"),
+		"ObjectPool/_2/CONTENTS":          append([]byte("%PDF-1.7\n"), large...),
+		"ObjectPool/_2/Sub/Deeper":        []byte("deeper"),
+		"Macros/VBA/dir":                  []byte("not an object"),
+		"ObjectPool/_3/\x01Ole10Native":   ole10Native("other.txt", []byte("other")),
+		"ObjectPool/_3/\x03ObjInfo":       []byte("objinfo"),
+		"ObjectPool/_4/WordDocument":      []byte("nested document"),
+		"ObjectPool/_4/1Table":            []byte("nested table"),
+		"ObjectPool/_4/\x05SummaryInform": []byte("summary"),
+	})
+	objects, err := ExtractEmbeddedCFB(bytes.NewReader(host), int64(len(host)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, o := range objects {
+		got[o.Name] = string(o.Data)
+		if len(o.Data) > len(large)+2*4096*4 {
+			t.Errorf("%s: %d bytes, the host is copied", o.Name, len(o.Data))
+		}
+	}
+	for name, want := range map[string]string{
+		"ObjectPool/_1!/hello.txt":  "hello",
+		"ObjectPool/_2!/object.pdf": "%PDF-1.7\n" + string(large),
+		"ObjectPool/_3!/other.txt":  "other",
+	} {
+		if got[name] != want {
+			t.Errorf("%s: got %.20q, want %.20q", name, got[name], want)
+		}
+	}
+	nested, ok := got["ObjectPool/_4!/object.doc"]
+	if !ok || len(objects) != 4 {
+		t.Fatalf("got %d objects: %v", len(objects), objects)
+	}
+	// the nested document is a compound file of its own streams only
+	dir, err := readCFBDirectory([]byte(nested))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range dir {
+		if e.typ != 0 {
+			names = append(names, strings.Join(append(e.path, e.name), "/"))
+		}
+	}
+	slices.Sort(names)
+	if want := []string{"1Table", "Root Entry", "SummaryInform", "WordDocument"}; !slices.Equal(names, want) {
+		t.Errorf("nested document: %q, want %q", names, want)
+	}
+}
+
+func TestExtractEmbeddedCFBPowerPoint(t *testing.T) {
+	var document bytes.Buffer
+	for _, content := range []string{"first", "second"} {
+		object := buildCFB(t, map[string][]byte{"\x01Ole10Native": ole10Native(content+".txt", []byte(content))})
+		binary.Write(&document, binary.LittleEndian, uint16(0))
+		binary.Write(&document, binary.LittleEndian, uint16(recordTypeExOleObjStg))
+		binary.Write(&document, binary.LittleEndian, uint32(len(object)))
+		document.Write(object)
+	}
+	host := buildCFB(t, map[string][]byte{"PowerPoint Document": document.Bytes()})
+	objects, err := ExtractEmbeddedCFB(bytes.NewReader(host), int64(len(host)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, o := range objects {
+		names = append(names, o.Name)
+	}
+	if want := []string{"ExOleObjStg1!/first.txt", "ExOleObjStg2!/second.txt"}; !slices.Equal(names, want) {
+		t.Errorf("got %q, want %q", names, want)
+	}
+}
+
+func TestExtractEmbeddedPDFCorruptStream(t *testing.T) {
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R /Names << /EmbeddedFiles << /Names [(bad.txt) 3 0 R (good.txt) 5 0 R] >> >> >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		"<< /Type /Filespec /F (bad.txt) /EF << /F 4 0 R >> >>",
+		"<< /Type /EmbeddedFile /Filter /FlateDecode /Length 8 >>\nstream\nnot zlib\nendstream",
+		"<< /Type /Filespec /F (good.txt) /EF << /F 6 0 R >> >>",
+		"<< /Type /EmbeddedFile /Length 5 >>\nstream\nhello\nendstream",
+	)
+	objects, err := ExtractEmbeddedPDF(bytes.NewReader(data), int64(len(data)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Name != "good.txt" || string(objects[0].Data) != "hello" {
+		t.Errorf("got %+v", objects)
+	}
+}
+
+func TestUnwrapOLEStreamSize(t *testing.T) {
+	data := buildCFB(t, map[string][]byte{"CONTENTS": []byte("%PDF-1.7\n")})
+	dir, err := readCFBDirectory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range dir {
+		if e.name == "CONTENTS" {
+			// the stream claims to be 1 TB long
+			binary.LittleEndian.PutUint64(data[e.offset+0x78:], 1<<40)
+		}
+	}
+	for _, o := range unwrapOLE("oleObject1.bin", data) {
+		if len(o.Data) > len(data) {
+			t.Errorf("%s: %d bytes", o.Name, len(o.Data))
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// ---- file eml.go ----
+// Internet messages (.eml): RFC 5322 headers with RFC 2047 encoded words and a MIME body (RFC 2045, 2046).
+
+// maximum nesting level of multipart bodies
+const emlMaxDepth = 16
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// ReadEML parses the MIME message represented by r of the given size
+func ReadEML(r io.ReaderAt, size int64) (*Message, error) {
+	msg, err := mail.ReadMessage(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	m := &Message{
+		Subject: decodeHeader(msg.Header.Get("Subject")),
+		From:    strings.Join(addressList(msg.Header.Get("From")), ", "),
+		To:      addressList(msg.Header.Get("To")),
+		Cc:      addressList(msg.Header.Get("Cc")),
+		Bcc:     addressList(msg.Header.Get("Bcc")),
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		m.Date = date
+	}
+	err = m.readPart(mimeHeader(msg.Header), msg.Body, 0)
+	return m, err
+}
+
+// ExtractEMLAttachments returns the attachments of a MIME message, attached messages become .eml files
+func ExtractEMLAttachments(r io.ReaderAt, size int64) ([]EmbeddedObject, error) {
+	m, err := ReadEML(r, size)
+	if m == nil {
+		return nil, err
+	}
+	return m.Attachments, err
+}
+
+// mimeHeader is the part of a header which describes a MIME entity
+type mimeHeader interface {
+	Get(key string) string
+}
+
+// readPart reads the body of one MIME entity: the first text/plain and text/html parts which are not
+// attachments are the bodies, every other leaf part is an attachment
+func (m *Message) readPart(header mimeHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if strings.HasPrefix(mediaType, "multipart/") && depth < emlMaxDepth {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return wrapError(err)
+			}
+			if err := m.readPart(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(transferDecoder(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return wrapError(err)
+	}
+	disposition, dparams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dparams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+	isAttachment := disposition == "attachment" || filename != ""
+	switch {
+	case mediaType == "text/plain" && !isAttachment && m.Body == "":
+		m.Body = decodeCharset(data, params["charset"])
+	case mediaType == "text/html" && !isAttachment && m.HTMLBody == "":
+		m.HTMLBody = decodeCharset(data, params["charset"])
+	case !isAttachment && strings.HasPrefix(mediaType, "text/"):
+		// alternative or additional text parts
+	default:
+		filename = path.Base(strings.ReplaceAll(filename, "\\", "/"))
+		isMessage := mediaType == "message/rfc822" || mediaType == "message/global"
+		if filename == "" || filename == "." || filename == "/" {
+			if isMessage {
+				filename = "message"
+			} else {
+				filename = fmt.Sprintf("attachment%d%s", len(m.Attachments)+1, sniffExtension(data))
+			}
+		}
+		if isMessage && !strings.EqualFold(path.Ext(filename), ".eml") { // attached messages are read by their extension
+			filename += ".eml"
+		}
+		m.Attachments = append(m.Attachments, EmbeddedObject{Name: filename, Data: data})
+	}
+	return nil
+}
+
+// transferDecoder undoes the Content-Transfer-Encoding of a part
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// base64Cleaner drops the characters which are not part of the base64 alphabet (line breaks, spaces)
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	for {
+		n, err := c.r.Read(p)
+		j := 0
+		for _, b := range p[:n] {
+			if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '+' || b == '/' || b == '=' {
+				p[j] = b
+				j++
+			}
+		}
+		if j > 0 || err != nil {
+			return j, err
+		}
+	}
+}
+
+// charsetReader converts text of the named charset to UTF-8
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// decodeCharset converts a body to UTF-8, bodies of unknown charsets are returned as they are
+func decodeCharset(data []byte, charset string) string {
+	if charset == "" {
+		return string(data)
+	}
+	r, err := charsetReader(charset, bytes.NewReader(data))
+	if err != nil {
+		return string(data)
+	}
+	s, err := io.ReadAll(r)
+	if err != nil {
+		return string(data)
+	}
+	return string(s)
+}
+
+// decodeHeader decodes the RFC 2047 encoded words of a header value
+func decodeHeader(s string) string {
+	decoded, err := wordDecoder.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// addressList formats the mailboxes of an address header as "Name <address>"
+func addressList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	list, err := parser.ParseList(s)
+	if err != nil {
+		return []string{decodeHeader(s)}
+	}
+	var result []string
+	for _, a := range list {
+		result = append(result, mailbox(a.Name, a.Address))
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEML parses a message written with \n line ends
+func readEML(t *testing.T, s string) *Message {
+	t.Helper()
+	s = strings.ReplaceAll(s, "\n", "\r\n")
+	m, err := ReadEML(strings.NewReader(s), int64(len(s)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// example of RFC 2047 section 8
+func TestReadEMLHeaders(t *testing.T) {
+	m := readEML(t, `From: =?US-ASCII?Q?Keith_Moore?= <moore@cs.utk.edu>
+To: =?ISO-8859-1?Q?Keld_J=F8rn_Simonsen?= <keld@dkuug.dk>, bob@example.com
+CC: =?ISO-8859-1?Q?Andr=E9?= Pirard <PIRARD@vm1.ulg.ac.be>
+Subject: =?ISO-8859-1?B?SWYgeW91IGNhbiByZWFkIHRoaXMgeW8=?=
+ =?ISO-8859-2?B?dSB1bmRlcnN0YW5kIHRoZSBleGFtcGxlLg==?=
+Date: Tue, 1 Jul 2003 10:52:37 +0200
+Content-Type: text/plain; charset=iso-8859-1
+Content-Transfer-Encoding: quoted-printable
+
+Caf=E9 cr=
+=E8me
+`)
+	if m.From != "Keith Moore <moore@cs.utk.edu>" {
+		t.Errorf("From %q", m.From)
+	}
+	if strings.Join(m.To, "|") != "Keld Jørn Simonsen <keld@dkuug.dk>|bob@example.com" {
+		t.Errorf("To %q", m.To)
+	}
+	if strings.Join(m.Cc, "|") != "André Pirard <PIRARD@vm1.ulg.ac.be>" {
+		t.Errorf("Cc %q", m.Cc)
+	}
+	if m.Subject != "If you can read this you understand the example." {
+		t.Errorf("Subject %q", m.Subject)
+	}
+	if want := time.Date(2003, 7, 1, 8, 52, 37, 0, time.UTC); !m.Date.Equal(want) {
+		t.Errorf("Date %v", m.Date)
+	}
+	if m.Body != "Café crème\r\n" {
+		t.Errorf("Body %q", m.Body)
+	}
+}
+
+func TestReadEMLMultipart(t *testing.T) {
+	m := readEML(t, `From: alice@example.com
+Subject: report
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+
+plain text
+--inner
+Content-Type: text/html; charset=utf-8
+
+<p>html text</p>
+--inner--
+--outer
+Content-Type: application/pdf; name="=?UTF-8?Q?r=C3=A9sum=C3=A9.pdf?="
+Content-Disposition: attachment; filename="=?UTF-8?Q?r=C3=A9sum=C3=A9.pdf?="
+Content-Transfer-Encoding: base64
+
+JVBERi0x
+LjQK
+--outer
+Content-Type: application/octet-stream
+Content-Disposition: attachment
+Content-Transfer-Encoding: base64
+
+UEsDBAo=
+--outer
+Content-Type: text/plain
+Content-Disposition: attachment; filename="../../notes.txt"
+
+notes
+--outer
+Content-Type: message/rfc822; name="Fwd: minutes"
+
+From: bob@example.com
+Subject: minutes
+
+forwarded body
+--outer
+Content-Type: message/rfc822
+
+Subject: unnamed
+
+--outer--
+`)
+	if m.Body != "plain text" || m.HTMLBody != "<p>html text</p>" {
+		t.Errorf("bodies %q, %q", m.Body, m.HTMLBody)
+	}
+	want := []struct{ name, data string }{
+		{"résumé.pdf", "%PDF-1.4\n"},
+		{"attachment2.zip", "PK\x03\x04\n"},
+		{"notes.txt", "notes"},
+		{"Fwd: minutes.eml", "From: bob@example.com\r\nSubject: minutes\r\n\r\nforwarded body"},
+		{"message.eml", "Subject: unnamed\r\n"},
+	}
+	if len(m.Attachments) != len(want) {
+		t.Fatalf("%d attachments, want %d", len(m.Attachments), len(want))
+	}
+	for i, a := range m.Attachments {
+		if a.Name != want[i].name || string(a.Data) != want[i].data {
+			t.Errorf("attachment %d: %q, %q, want %q, %q", i, a.Name, a.Data, want[i].name, want[i].data)
+		}
+	}
+
+	// the attached message is read like the file it was extracted to
+	fwd := m.Attachments[3].Data
+	inner, err := ReadEML(strings.NewReader(string(fwd)), int64(len(fwd)))
+	if err != nil || inner.Subject != "minutes" || inner.Body != "forwarded body" {
+		t.Errorf("attached message: %+v, %v", inner, err)
+	}
+}
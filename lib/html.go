@@ -0,0 +1,40 @@
+package lib
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// ---- file html.go ----
+// Plain text of HTML bodies (emails), without a full HTML parser.
+
+var (
+	htmlHiddenRE  = regexp.MustCompile(`(?is)<(script|style|head|title)\b.*?</(script|style|head|title)\s*>|<!--.*?-->`)
+	htmlBlockRE   = regexp.MustCompile(`(?i)<(br|/p|/div|/tr|/li|/h[1-6]|/table|/blockquote|/pre|hr)\b[^>]*>`)
+	htmlCellRE    = regexp.MustCompile(`(?i)</t[dh]\s*>`)
+	htmlTagRE     = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlSpaceRE   = regexp.MustCompile(`[ \t\f\v\x{a0}]+`)
+	htmlNewlineRE = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTML2Text returns the text of an HTML document: hidden elements are dropped, block elements end lines,
+// table cells are tab separated and entities are decoded
+func HTML2Text(s string) string {
+	s = htmlHiddenRE.ReplaceAllString(s, "")
+	s = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s) // line breaks only come from the markup
+	s = htmlBlockRE.ReplaceAllString(s, "\n")
+	s = htmlCellRE.ReplaceAllString(s, "\x00")
+	s = htmlTagRE.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		cells := strings.Split(line, "\x00")
+		for j, cell := range cells {
+			cells[j] = strings.TrimSpace(htmlSpaceRE.ReplaceAllString(cell, " "))
+		}
+		lines[i] = strings.TrimRight(strings.Join(cells, "\t"), "\t")
+	}
+	s = strings.Join(lines, "\n")
+	return strings.TrimSpace(htmlNewlineRE.ReplaceAllString(s, "\n\n"))
+}
@@ -0,0 +1,25 @@
+package lib
+
+import "testing"
+
+func TestHTML2Text(t *testing.T) {
+	for _, c := range []struct {
+		html string
+		want string
+	}{
+		{"<p>Hello,</p>\r\n<p>see   the\nreport&nbsp;below.</p>", "Hello,\nsee the report below."},
+		{"<html><head><title>Invoice</title><style>p { color: red }</style></head>" +
+			"<body><script>alert(1)</script><!-- <p>hidden</p> -->Total: 5 &euro; &lt;net&gt;</body></html>",
+			"Total: 5 € <net>"},
+		{"<table><tr><th>Item</th><th>Qty</th></tr><tr><td>Apples</td><td>3</td></tr>" +
+			"<tr><td>Pears</td><td></td></tr></table>", "Item\tQty\nApples\t3\nPears"},
+		{"line<br>break<BR/>again<hr><div>block</div>", "line\nbreak\nagain\nblock"},
+		{"<p>one</p><br><br><br><br><p>two</p>", "one\n\ntwo"},
+		{"<ul><li>first</li><li>second &amp; last</li></ul>", "first\nsecond & last"},
+		{"", ""},
+	} {
+		if got := HTML2Text(c.html); got != c.want {
+			t.Errorf("%q: got %q, want %q", c.html, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package metagoffice
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for image.DecodeConfig
+	_ "image/jpeg" // register JPEG for image.DecodeConfig
+	_ "image/png"  // register PNG for image.DecodeConfig
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Image describes one picture of an OOXML package and where it is placed
+type Image struct {
+	Part        string `json:"part"`                  // name of the media part, e.g. word/media/image1.png
+	ContentType string `json:"contentType"`           // content type declared in [Content_Types].xml
+	Width       int    `json:"width,omitempty"`       // width in pixels (PNG, JPEG and GIF only)
+	Height      int    `json:"height,omitempty"`      // height in pixels (PNG, JPEG and GIF only)
+	Source      string `json:"source,omitempty"`      // part referencing the picture, e.g. word/header1.xml
+	Anchor      string `json:"anchor,omitempty"`      // "paragraph 3", "slide 2", "Sheet1!B4" or "Sheet1" (headers); empty for unreferenced media
+	Name        string `json:"name,omitempty"`        // name of the drawing object
+	Description string `json:"description,omitempty"` // alt text (descr attribute of wp:docPr, p:cNvPr or xdr:cNvPr)
+	Title       string `json:"altTitle,omitempty"`    // alt text title (title attribute)
+	Data        []byte `json:"data,omitempty"`        // content of the media part, if requested
+}
+
+var slideRE = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// GetImages lists the pictures of a DOCX, PPTX or XLSX file, one entry per placement of a media part
+// (media parts which are never referenced get one entry without anchor). The bytes of the pictures
+// are returned only if withData is set.
+func GetImages(document *os.File, withData bool) ([]Image, error) {
+	z, err := zip.OpenReader(document.Name())
+	if err != nil {
+		return nil, errors.New("failed to open the file as zip")
+	}
+	defer z.Close()
+	return getImages(&z.Reader, withData)
+}
+
+func getImages(z *zip.Reader, withData bool) ([]Image, error) {
+	parts := make(map[string]*zip.File)
+	for _, f := range z.File {
+		parts[f.Name] = f
+	}
+	types := readContentTypes(parts["[Content_Types].xml"])
+
+	// media parts and their properties
+	media := make(map[string]Image)
+	for _, f := range z.File {
+		if !strings.Contains(f.Name, "/media/") || f.FileInfo().IsDir() {
+			continue
+		}
+		img := Image{Part: f.Name, ContentType: types.of(f.Name)}
+		if rc, err := f.Open(); err == nil {
+			if cfg, _, err := image.DecodeConfig(rc); err == nil {
+				img.Width, img.Height = cfg.Width, cfg.Height
+			}
+			rc.Close()
+		}
+		if withData {
+			if rc, err := f.Open(); err == nil {
+				img.Data, _ = io.ReadAll(rc)
+				rc.Close()
+			}
+		}
+		media[f.Name] = img
+	}
+
+	// placements of the pictures in the documents, slides and drawings
+	var images []Image
+	used := make(map[string]bool)
+	rels := make(map[string]map[string]string) // relationships of the source parts, read once per part
+	add := func(source, anchor string, pic picture) {
+		targets, ok := rels[source]
+		if !ok {
+			targets = readRelationships(parts, source)
+			rels[source] = targets
+		}
+		target, ok := targets[pic.embed]
+		if !ok {
+			return
+		}
+		img, ok := media[target]
+		if !ok {
+			return
+		}
+		img.Source, img.Anchor = source, anchor
+		img.Name, img.Description, img.Title = pic.name, pic.descr, pic.title
+		images = append(images, img)
+		used[target] = true
+	}
+	sheets := drawingSheets(parts)
+	for _, f := range z.File {
+		switch {
+		case strings.HasPrefix(f.Name, "word/") && path.Dir(f.Name) == "word" && path.Ext(f.Name) == ".xml":
+			walkPictures(f, func(pic picture) {
+				add(f.Name, fmt.Sprintf("paragraph %d", pic.paragraph), pic)
+			})
+		case slideRE.MatchString(f.Name):
+			slide := slideRE.FindStringSubmatch(f.Name)[1]
+			walkPictures(f, func(pic picture) {
+				add(f.Name, "slide "+slide, pic)
+			})
+		case strings.HasPrefix(f.Name, "xl/drawings/") && (path.Ext(f.Name) == ".xml" || path.Ext(f.Name) == ".vml"):
+			walkPictures(f, func(pic picture) {
+				anchor := ""
+				if pic.cell {
+					anchor = cellName(pic.col, pic.row)
+				}
+				if sheet, ok := sheets[f.Name]; ok && anchor != "" {
+					anchor = sheet + "!" + anchor
+				} else if ok {
+					anchor = sheet // pictures of headers and footers
+				}
+				add(f.Name, anchor, pic)
+			})
+		}
+	}
+
+	var unused []string
+	for name := range media {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	for _, name := range unused {
+		images = append(images, media[name])
+	}
+	return images, nil
+}
+
+// picture is a reference to a media part found while walking an XML part
+type picture struct {
+	embed     string // relationship id of the blip
+	name      string
+	descr     string
+	title     string
+	paragraph int  // 1-based paragraph number (wordprocessing parts)
+	col, row  int  // 0-based cell of the top-left anchor (spreadsheet drawings)
+	cell      bool // col and row were read from the anchor
+}
+
+// walkPictures streams an XML part and calls fn for every a:blip, with the properties of the
+// enclosing drawing object (wp:docPr, p:cNvPr or xdr:cNvPr) and its position, and for every
+// legacy VML shape filled by a v:imagedata, with the properties of the v:shape
+func walkPictures(f *zip.File, fn func(pic picture)) {
+	rc, err := f.Open()
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+	var cur picture
+	var inFrom bool
+	var text *int               // element whose character data is being collected (xdr:col or xdr:row)
+	var anchor *strings.Builder // character data of x:Anchor
+	skip := 0                   // depth inside mc:Fallback, the VML copy of a DrawingML object
+	d := xml.NewDecoder(rc)
+	if path.Ext(f.Name) == ".vml" {
+		d.Strict = false // VML drawings of Excel are not always well-formed (<br> in comments)
+		d.AutoClose = xml.HTMLAutoClose
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 || (t.Name.Local == "Fallback" && t.Name.Space == nsMarkupCompatibility) {
+				skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "p":
+				if t.Name.Space == nsWordprocessing {
+					cur.paragraph++
+				}
+			case "docPr", "cNvPr": // wp:docPr comes first in DOCX and takes precedence over pic:cNvPr
+				cur.name = firstNonEmpty(cur.name, attr(t, "name"))
+				cur.descr = firstNonEmpty(cur.descr, attr(t, "descr"))
+				cur.title = firstNonEmpty(cur.title, attr(t, "title"))
+			case "blip":
+				cur.embed = attrNS(t, nsRelationships, "embed")
+				if cur.embed != "" {
+					fn(cur)
+				}
+			case "shape", "rect":
+				if t.Name.Space == nsVML {
+					cur.name, cur.descr, cur.title = attr(t, "id"), attr(t, "alt"), ""
+					cur.embed, cur.cell = "", false
+				}
+			case "imagedata":
+				if t.Name.Space == nsVML {
+					cur.embed = firstNonEmpty(attrNS(t, nsRelationships, "id"), attrNS(t, nsOfficeVML, "relid"))
+					cur.title = firstNonEmpty(cur.title, attrNS(t, nsOfficeVML, "title"))
+				}
+			case "Anchor":
+				if t.Name.Space == nsExcelVML {
+					anchor = &strings.Builder{}
+				}
+			case "from":
+				inFrom, cur.cell = true, true
+			case "col":
+				if inFrom {
+					text = &cur.col
+				}
+			case "row":
+				if inFrom {
+					text = &cur.row
+				}
+			case "twoCellAnchor", "oneCellAnchor", "absoluteAnchor":
+				cur.col, cur.row, cur.cell = 0, 0, false
+				cur.name, cur.descr, cur.title = "", "", ""
+			case "inline", "anchor":
+				cur.name, cur.descr, cur.title = "", "", ""
+			case "pic":
+				if t.Name.Space != nsPicture {
+					cur.name, cur.descr, cur.title = "", "", ""
+				}
+			}
+		case xml.CharData:
+			if text != nil {
+				*text, _ = strconv.Atoi(strings.TrimSpace(string(t)))
+			}
+			if anchor != nil {
+				anchor.Write(t)
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			text = nil
+			switch t.Name.Local {
+			case "from":
+				inFrom = false
+			case "Anchor":
+				if anchor == nil {
+					break
+				}
+				// LeftColumn, LeftOffset, TopRow, TopOffset, RightColumn, RightOffset, BottomRow, BottomOffset
+				if fields := strings.Split(anchor.String(), ","); len(fields) >= 3 {
+					cur.col, _ = strconv.Atoi(strings.TrimSpace(fields[0]))
+					cur.row, _ = strconv.Atoi(strings.TrimSpace(fields[2]))
+					cur.cell = true
+				}
+				anchor = nil
+			case "shape", "rect":
+				if t.Name.Space == nsVML && cur.embed != "" {
+					fn(cur)
+					cur.embed = ""
+				}
+			}
+		}
+	}
+}
+
+const (
+	nsWordprocessing = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	nsPicture        = "http://schemas.openxmlformats.org/drawingml/2006/picture"
+	nsRelationships  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	relTypeDrawing   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing"
+	relTypeVML       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing"
+
+	nsMarkupCompatibility = "http://schemas.openxmlformats.org/markup-compatibility/2006"
+	nsVML                 = "urn:schemas-microsoft-com:vml"
+	nsOfficeVML           = "urn:schemas-microsoft-com:office:office"
+	nsExcelVML            = "urn:schemas-microsoft-com:office:excel"
+)
+
+func firstNonEmpty(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
+
+func attr(t xml.StartElement, local string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == local && a.Name.Space == "" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func attrNS(t xml.StartElement, space, local string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == local && a.Name.Space == space {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// cellName converts 0-based column and row numbers to an A1 reference
+func cellName(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row+1)
+}
+
+// contentTypes holds the defaults (by extension) and overrides (by part) of [Content_Types].xml
+type contentTypes struct {
+	Defaults []struct {
+		Extension   string `xml:"Extension,attr"`
+		ContentType string `xml:"ContentType,attr"`
+	} `xml:"Default"`
+	Overrides []struct {
+		PartName    string `xml:"PartName,attr"`
+		ContentType string `xml:"ContentType,attr"`
+	} `xml:"Override"`
+}
+
+func readContentTypes(f *zip.File) contentTypes {
+	var ct contentTypes
+	if f == nil {
+		return ct
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return ct
+	}
+	defer rc.Close()
+	xml.NewDecoder(rc).Decode(&ct)
+	return ct
+}
+
+func (ct contentTypes) of(part string) string {
+	for _, o := range ct.Overrides {
+		if strings.TrimPrefix(o.PartName, "/") == part {
+			return o.ContentType
+		}
+	}
+	ext := strings.TrimPrefix(path.Ext(part), ".")
+	for _, d := range ct.Defaults {
+		if strings.EqualFold(d.Extension, ext) {
+			return d.ContentType
+		}
+	}
+	return ""
+}
+
+type relationships struct {
+	Relationship []struct {
+		ID         string `xml:"Id,attr"`
+		Type       string `xml:"Type,attr"`
+		Target     string `xml:"Target,attr"`
+		TargetMode string `xml:"TargetMode,attr"`
+	} `xml:"Relationship"`
+}
+
+// readRelationships returns the internal relationships of a part, mapping their id to the resolved part name
+func readRelationships(parts map[string]*zip.File, source string) map[string]string {
+	targets := make(map[string]string)
+	for _, rel := range readRels(parts, source).Relationship {
+		if rel.TargetMode == "External" {
+			continue
+		}
+		targets[rel.ID] = resolveTarget(source, rel.Target)
+	}
+	return targets
+}
+
+func readRels(parts map[string]*zip.File, source string) relationships {
+	var rels relationships
+	f, ok := parts[path.Join(path.Dir(source), "_rels", path.Base(source)+".rels")]
+	if !ok {
+		return rels
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return rels
+	}
+	defer rc.Close()
+	xml.NewDecoder(rc).Decode(&rels)
+	return rels
+}
+
+// resolveTarget resolves the target of a relationship relative to its source part
+func resolveTarget(source, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(path.Clean(target), "/")
+	}
+	return strings.TrimPrefix(path.Join(path.Dir(source), target), "/")
+}
+
+// drawingSheets maps the drawing parts of a workbook to the name of the sheet displaying them
+func drawingSheets(parts map[string]*zip.File) map[string]string {
+	result := make(map[string]string)
+	f, ok := parts["xl/workbook.xml"]
+	if !ok {
+		return result
+	}
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return result
+	}
+	xml.NewDecoder(rc).Decode(&wb)
+	rc.Close()
+	sheetParts := readRelationships(parts, "xl/workbook.xml")
+	for _, sheet := range wb.Sheets {
+		sheetPart, ok := sheetParts[sheet.ID]
+		if !ok {
+			continue
+		}
+		for _, rel := range readRels(parts, sheetPart).Relationship {
+			if rel.Type == relTypeDrawing || rel.Type == relTypeVML {
+				result[resolveTarget(sheetPart, rel.Target)] = sheet.Name
+			}
+		}
+	}
+	return result
+}
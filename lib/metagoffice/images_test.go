@@ -0,0 +1,160 @@
+package metagoffice
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// pngImage returns a PNG picture of the given size
+func pngImage(t *testing.T, width, height int) string {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+const (
+	testContentTypes = `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="png" ContentType="image/png"/><Default Extension="emf" ContentType="image/x-emf"/></Types>`
+	testRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`
+)
+
+// checkImages compares the placements found in a package to the wanted ones, given as part, anchor, name,
+// description and title
+func checkImages(t *testing.T, members map[string]string, want [][5]string) []Image {
+	t.Helper()
+	images, err := GetImages(writeZip(t, members), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != len(want) {
+		t.Fatalf("got %d images %+v, want %d", len(images), images, len(want))
+	}
+	for i, img := range images {
+		got := [5]string{img.Part, img.Anchor, img.Name, img.Description, img.Title}
+		if got != want[i] {
+			t.Errorf("image %d: got %q, want %q", i, got, want[i])
+		}
+	}
+	return images
+}
+
+func TestGetImagesWord(t *testing.T) {
+	images := checkImages(t, map[string]string{
+		"[Content_Types].xml":   testContentTypes,
+		"word/media/image1.png": pngImage(t, 3, 2),
+		"word/media/image2.emf": "EMF",
+		"word/media/unused.png": pngImage(t, 1, 1),
+		"word/_rels/document.xml.rels": testRels + `<Relationship Id="rId1" Target="media/image1.png"/>` +
+			`<Relationship Id="rId2" Target="/word/media/image2.emf"/>` +
+			`<Relationship Id="rId3" Target="http://example.com/a.png" TargetMode="External"/></Relationships>`,
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"` +
+			` xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"` +
+			` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"` +
+			` xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture"` +
+			` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"` +
+			` xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006"` +
+			` xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office"><w:body>` +
+			`<w:p><w:r>text</w:r></w:p>` +
+			`<w:p><w:r><w:drawing><wp:inline><wp:docPr id="1" name="Picture 1" descr="A chart" title="Sales"/>` +
+			`<a:graphic><a:graphicData><pic:pic><pic:nvPicPr><pic:cNvPr id="0" name="chart.png"/></pic:nvPicPr>` +
+			`<pic:blipFill><a:blip r:embed="rId1"/></pic:blipFill></pic:pic></a:graphicData></a:graphic>` +
+			`</wp:inline></w:drawing></w:r></w:p>` +
+			`<w:p><w:r><w:pict><v:shape id="_x0000_i1025" alt="Old logo" style="width:10pt">` +
+			`<v:imagedata r:id="rId2" o:title="logo"/></v:shape></w:pict></w:r></w:p>` +
+			`<w:p><w:r><mc:AlternateContent><mc:Choice Requires="wps"><w:drawing><wp:anchor>` +
+			`<wp:docPr id="2" name="Picture 2"/><a:graphic><a:graphicData><pic:pic><pic:blipFill><a:blip r:embed="rId1"/>` +
+			`</pic:blipFill></pic:pic></a:graphicData></a:graphic></wp:anchor></w:drawing></mc:Choice>` +
+			`<mc:Fallback><w:pict><v:shape id="_x0000_s1026"><v:imagedata r:id="rId1"/></v:shape></w:pict></mc:Fallback>` +
+			`</mc:AlternateContent></w:r></w:p>` +
+			`<w:p><w:r><w:pict><v:shape id="_x0000_s1027"><v:imagedata r:id="rId3"/></v:shape></w:pict></w:r></w:p>` +
+			`</w:body></w:document>`,
+	}, [][5]string{
+		{"word/media/image1.png", "paragraph 2", "Picture 1", "A chart", "Sales"},
+		{"word/media/image2.emf", "paragraph 3", "_x0000_i1025", "Old logo", "logo"},
+		{"word/media/image1.png", "paragraph 4", "Picture 2", "", ""},
+		{"word/media/unused.png", "", "", "", ""},
+	})
+	if img := images[0]; img.ContentType != "image/png" || img.Width != 3 || img.Height != 2 || img.Source != "word/document.xml" {
+		t.Errorf("image1.png: %+v", img)
+	}
+	if img := images[1]; img.ContentType != "image/x-emf" || img.Width != 0 {
+		t.Errorf("image2.emf: %+v", img)
+	}
+}
+
+func TestGetImagesSpreadsheet(t *testing.T) {
+	checkImages(t, map[string]string{
+		"[Content_Types].xml": testContentTypes,
+		"xl/media/image1.png": pngImage(t, 1, 1),
+		"xl/media/image2.png": pngImage(t, 1, 1),
+		"xl/media/image3.png": pngImage(t, 1, 1),
+		"xl/_rels/workbook.xml.rels": testRels + `<Relationship Id="rId1" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`,
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"` +
+			` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/worksheets/_rels/sheet1.xml.rels": testRels +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing"` +
+			` Target="../drawings/drawing1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing"` +
+			` Target="../drawings/vmlDrawing1.vml"/>` +
+			`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing"` +
+			` Target="../drawings/vmlDrawingHF1.vml"/></Relationships>`,
+		"xl/drawings/_rels/drawing1.xml.rels":      testRels + `<Relationship Id="rId1" Target="../media/image1.png"/></Relationships>`,
+		"xl/drawings/_rels/vmlDrawing1.vml.rels":   testRels + `<Relationship Id="rId1" Target="../media/image2.png"/></Relationships>`,
+		"xl/drawings/_rels/vmlDrawingHF1.vml.rels": testRels + `<Relationship Id="rId1" Target="../media/image3.png"/></Relationships>`,
+		"xl/drawings/drawing1.xml": `<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing"` +
+			` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"` +
+			` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<xdr:twoCellAnchor><xdr:from><xdr:col>1</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>3</xdr:row></xdr:from>` +
+			`<xdr:pic><xdr:nvPicPr><xdr:cNvPr id="2" name="Picture 1" descr="Logo"/></xdr:nvPicPr>` +
+			`<xdr:blipFill><a:blip r:embed="rId1"/></xdr:blipFill></xdr:pic></xdr:twoCellAnchor></xdr:wsDr>`,
+		// comments and header pictures are VML, where Excel writes <br> without end tag
+		"xl/drawings/vmlDrawing1.vml": `<xml xmlns:v="urn:schemas-microsoft-com:vml"` +
+			` xmlns:o="urn:schemas-microsoft-com:office:office" xmlns:x="urn:schemas-microsoft-com:office:excel">` +
+			`<v:shape id="_x0000_s1025" type="#_x0000_t202"><v:textbox><div>note<br>text</div></v:textbox>` +
+			`<x:ClientData ObjectType="Note"><x:Anchor>3, 15, 0, 2, 5, 15, 4, 16</x:Anchor></x:ClientData></v:shape>` +
+			`<v:shape id="_x0000_s1026" alt="Stamp"><v:imagedata o:relid="rId1" o:title="stamp"/>` +
+			`<x:ClientData ObjectType="Pict"><x:Anchor>` + "\n 27, 0, 9, 5, 29, 0, 12, 0</x:Anchor></x:ClientData></v:shape></xml>",
+		"xl/drawings/vmlDrawingHF1.vml": `<xml xmlns:v="urn:schemas-microsoft-com:vml"` +
+			` xmlns:o="urn:schemas-microsoft-com:office:office">` +
+			`<v:shape id="LH" o:spid="_x0000_s1025"><v:imagedata o:relid="rId1" o:title="header"/></v:shape></xml>`,
+	}, [][5]string{
+		{"xl/media/image1.png", "Data!B4", "Picture 1", "Logo", ""},
+		{"xl/media/image2.png", "Data!AB10", "_x0000_s1026", "Stamp", "stamp"},
+		{"xl/media/image3.png", "Data", "LH", "", "header"},
+	})
+}
+
+func TestGetImagesPresentation(t *testing.T) {
+	images, err := GetImages(writeZip(t, map[string]string{
+		"[Content_Types].xml":              testContentTypes,
+		"ppt/media/image1.png":             pngImage(t, 2, 2),
+		"ppt/slides/_rels/slide2.xml.rels": testRels + `<Relationship Id="rId2" Target="../media/image1.png"/></Relationships>`,
+		"ppt/slides/slide2.xml": `<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"` +
+			` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"` +
+			` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:cSld><p:spTree>` +
+			`<p:pic><p:nvPicPr><p:cNvPr id="4" name="Picture 3" descr="Team"/></p:nvPicPr>` +
+			`<p:blipFill><a:blip r:embed="rId2"/></p:blipFill></p:pic>` +
+			`<p:pic><p:nvPicPr><p:cNvPr id="5" name="Picture 4"/></p:nvPicPr>` +
+			`<p:blipFill><a:blip r:embed="rId2"/></p:blipFill></p:pic></p:spTree></p:cSld></p:sld>`,
+	}), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("got %d images", len(images))
+	}
+	for i, name := range []string{"Picture 3", "Picture 4"} {
+		if img := images[i]; img.Anchor != "slide 2" || img.Name != name || len(img.Data) == 0 {
+			t.Errorf("image %d: %+v", i, img)
+		}
+	}
+	if images[0].Description != "Team" || images[1].Description != "" {
+		t.Errorf("descriptions %q, %q", images[0].Description, images[1].Description)
+	}
+}
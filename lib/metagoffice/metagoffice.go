@@ -6,6 +6,7 @@ import (
 	"encoding/xml"
 	"errors"
 	"os"
+	"strings"
 )
 
 // XMLContent contains the fields of te file core.xml
@@ -58,3 +59,53 @@ func GetContent(document *os.File) (fields XMLContent, err error) {
 
 	return fields, nil
 }
+
+// odfMeta contains the fields of the file meta.xml of OpenDocument packages
+type odfMeta struct {
+	Title          string   `xml:"meta>title"`
+	Subject        string   `xml:"meta>subject"`
+	InitialCreator string   `xml:"meta>initial-creator"`
+	Creator        string   `xml:"meta>creator"`
+	Keywords       []string `xml:"meta>keyword"`
+	Description    string   `xml:"meta>description"`
+	EditingCycles  string   `xml:"meta>editing-cycles"`
+	Created        string   `xml:"meta>creation-date"`
+	Modified       string   `xml:"meta>date"`
+}
+
+// GetODFContent reads meta.xml of an OpenDocument file (ODT, ODS, ODP) into the fields used for core.xml:
+// the initial creator is the creator, the last author is the last modifier and the editing cycles are the revision
+func GetODFContent(document *os.File) (fields XMLContent, err error) {
+	z, err := zip.OpenReader(document.Name())
+	if err != nil {
+		return fields, errors.New("failed to open the file as zip")
+	}
+	defer z.Close()
+
+	for _, file := range z.File {
+		if file.Name != "meta.xml" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return fields, errors.New("failed to open meta.xml")
+		}
+		defer rc.Close()
+
+		var meta odfMeta
+		if err := xml.NewDecoder(rc).Decode(&meta); err != nil {
+			return fields, errors.New("failed to Unmarshal")
+		}
+		fields.Title = meta.Title
+		fields.Subject = meta.Subject
+		fields.Creator = meta.InitialCreator
+		fields.Keywords = strings.Join(meta.Keywords, ", ")
+		fields.Description = meta.Description
+		fields.LastModifiedBy = meta.Creator
+		fields.Revision = meta.EditingCycles
+		fields.Created = meta.Created
+		fields.Modified = meta.Modified
+		return fields, nil
+	}
+	return fields, errors.New("failed to find meta.xml")
+}
@@ -0,0 +1,78 @@
+package metagoffice
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// writeZip writes a zip file of the given members, sorted by name, and returns it opened
+func writeZip(t *testing.T, members map[string]string) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "package.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	z := zip.NewWriter(f)
+	for _, name := range names {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(members[name]))
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestGetODFContent(t *testing.T) {
+	f := writeZip(t, map[string]string{"meta.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-meta xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+ xmlns:meta="urn:oasis:names:tc:opendocument:xmlns:meta:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<office:meta>
+<meta:initial-creator>Jane Roe</meta:initial-creator>
+<dc:creator>John Doe</dc:creator>
+<dc:title>Annual report</dc:title>
+<dc:subject>Finance</dc:subject>
+<dc:description>First draft</dc:description>
+<meta:keyword>money</meta:keyword>
+<meta:keyword>report</meta:keyword>
+<meta:editing-cycles>7</meta:editing-cycles>
+<meta:creation-date>2021-03-14T09:26:00</meta:creation-date>
+<dc:date>2022-01-02T10:00:00.123</dc:date>
+<meta:generator>LibreOffice</meta:generator>
+</office:meta>
+</office:document-meta>`})
+	fields, err := GetODFContent(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := XMLContent{
+		Title:          "Annual report",
+		Subject:        "Finance",
+		Creator:        "Jane Roe",
+		Keywords:       "money, report",
+		Description:    "First draft",
+		LastModifiedBy: "John Doe",
+		Revision:       "7",
+		Created:        "2021-03-14T09:26:00",
+		Modified:       "2022-01-02T10:00:00.123",
+	}
+	if fields != want {
+		t.Errorf("got %+v, want %+v", fields, want)
+	}
+
+	if _, err := GetODFContent(writeZip(t, map[string]string{"content.xml": "<office:document-content/>"})); err == nil {
+		t.Error("no meta.xml: no error")
+	}
+}
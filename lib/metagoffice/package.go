@@ -0,0 +1,68 @@
+package metagoffice
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path"
+	"strings"
+)
+
+// Package describes what an OOXML package holds, according to its [Content_Types].xml
+type Package struct {
+	Kind          string // "docx", "xlsx" or "pptx" for every variant (macro-enabled, template, slideshow); empty if unknown
+	ContentType   string // content type of the main part, e.g. application/vnd.ms-word.document.macroEnabled.main+xml
+	HasVBAProject bool   // the package contains a vbaProject.bin part
+}
+
+// content types of the main part of the Word, Excel and PowerPoint packages
+var mainContentTypes = map[string]string{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml":   "docx",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml":   "docx",
+	"application/vnd.ms-word.document.macroEnabled.main+xml":                             "docx",
+	"application/vnd.ms-word.template.macroEnabledTemplate.main+xml":                     "docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml":         "xlsx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.template.main+xml":      "xlsx",
+	"application/vnd.ms-excel.sheet.macroEnabled.main+xml":                               "xlsx",
+	"application/vnd.ms-excel.template.macroEnabled.main+xml":                            "xlsx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml": "pptx",
+	"application/vnd.openxmlformats-officedocument.presentationml.slideshow.main+xml":    "pptx",
+	"application/vnd.openxmlformats-officedocument.presentationml.template.main+xml":     "pptx",
+	"application/vnd.ms-powerpoint.presentation.macroEnabled.main+xml":                   "pptx",
+	"application/vnd.ms-powerpoint.slideshow.macroEnabled.main+xml":                      "pptx",
+	"application/vnd.ms-powerpoint.template.macroEnabled.main+xml":                       "pptx",
+}
+
+const vbaProjectContentType = "application/vnd.ms-office.vbaProject"
+
+// GetPackage reads [Content_Types].xml of an OOXML file to find which kind of document it is and whether it has macros
+func GetPackage(document *os.File) (Package, error) {
+	var pkg Package
+	z, err := zip.OpenReader(document.Name())
+	if err != nil {
+		return pkg, errors.New("failed to open the file as zip")
+	}
+	defer z.Close()
+
+	parts := make(map[string]*zip.File)
+	for _, f := range z.File {
+		parts[f.Name] = f
+	}
+	ctFile, ok := parts["[Content_Types].xml"]
+	if !ok {
+		return pkg, errors.New("failed to find [Content_Types].xml")
+	}
+	types := readContentTypes(ctFile)
+	for _, o := range types.Overrides {
+		if kind, ok := mainContentTypes[o.ContentType]; ok {
+			pkg.Kind, pkg.ContentType = kind, o.ContentType
+			break
+		}
+	}
+	for name := range parts {
+		if types.of(name) == vbaProjectContentType || strings.EqualFold(path.Base(name), "vbaProject.bin") {
+			pkg.HasVBAProject = true
+		}
+	}
+	return pkg, nil
+}
@@ -0,0 +1,56 @@
+package metagoffice
+
+import "testing"
+
+// contentTypesOf returns the [Content_Types].xml of a package whose main part has the given content type,
+// with the overrides and defaults given as raw XML
+func contentTypesOf(main, more string) string {
+	return `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+		`<Override PartName="/main.xml" ContentType="` + main + `"/>` + more + `</Types>`
+}
+
+func TestGetPackage(t *testing.T) {
+	const vbaDefault = `<Default Extension="bin" ContentType="application/vnd.ms-office.vbaProject"/>`
+	for _, c := range []struct {
+		name    string
+		members map[string]string
+		want    Package
+	}{
+		{".docm", map[string]string{
+			"[Content_Types].xml": contentTypesOf("application/vnd.ms-word.document.macroEnabled.main+xml", vbaDefault),
+			"word/vbaProject.bin": "VBA",
+		}, Package{"docx", "application/vnd.ms-word.document.macroEnabled.main+xml", true}},
+		{".dotx", map[string]string{
+			"[Content_Types].xml": contentTypesOf("application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml", ""),
+		}, Package{"docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml", false}},
+		// the project is found by its content type, whatever its name
+		{".xltm", map[string]string{
+			"[Content_Types].xml": contentTypesOf("application/vnd.ms-excel.template.macroEnabled.main+xml",
+				`<Override PartName="/xl/project.dat" ContentType="application/vnd.ms-office.vbaProject"/>`),
+			"xl/project.dat": "VBA",
+		}, Package{"xlsx", "application/vnd.ms-excel.template.macroEnabled.main+xml", true}},
+		{".ppsx", map[string]string{
+			"[Content_Types].xml": contentTypesOf("application/vnd.openxmlformats-officedocument.presentationml.slideshow.main+xml", vbaDefault),
+		}, Package{"pptx", "application/vnd.openxmlformats-officedocument.presentationml.slideshow.main+xml", false}},
+		// or by its name, whatever its content type
+		{".potm", map[string]string{
+			"[Content_Types].xml": contentTypesOf("application/vnd.ms-powerpoint.template.macroEnabled.main+xml", ""),
+			"ppt/VBAProject.bin":  "VBA",
+		}, Package{"pptx", "application/vnd.ms-powerpoint.template.macroEnabled.main+xml", true}},
+		{"unknown", map[string]string{
+			"[Content_Types].xml": contentTypesOf("application/vnd.ms-visio.drawing.main+xml", ""),
+		}, Package{}},
+	} {
+		pkg, err := GetPackage(writeZip(t, c.members))
+		if err != nil || pkg != c.want {
+			t.Errorf("%s: got %+v, %v, want %+v", c.name, pkg, err, c.want)
+		}
+	}
+
+	if _, err := GetPackage(writeZip(t, map[string]string{"word/document.xml": "<w:document/>"})); err == nil {
+		t.Error("no [Content_Types].xml: no error")
+	}
+}
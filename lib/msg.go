@@ -0,0 +1,378 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// ---- file msg.go ----
+// Outlook messages (.msg) are compound files holding MAPI properties ([MS-OXMSG]): variable length
+// properties in "__substg1.0_TTTTPPPP" streams, fixed length ones in the "__properties_version1.0" stream,
+// recipients and attachments in "__recip_version1.0_#N" and "__attach_version1.0_#N" storages.
+
+var errMSGInvalid = errors.New("not an Outlook message")
+
+// Message is an email read from an Outlook .msg file or a MIME .eml file
+type Message struct {
+	Subject     string
+	From        string   // "Name <address>"
+	To          []string // "Name <address>" of every recipient
+	Cc          []string
+	Bcc         []string
+	Date        time.Time // time the message was sent
+	Received    time.Time // time the message was delivered
+	Body        string    // plain text body
+	HTMLBody    string
+	RTFBody     string // RTF body, decompressed
+	Attachments []EmbeddedObject
+}
+
+// MAPI property identifiers and types
+const (
+	prSubject            = 0x0037
+	prClientSubmitTime   = 0x0039
+	prSenderName         = 0x0C1A
+	prSenderEmail        = 0x0C1F
+	prSenderSMTP         = 0x5D01
+	prRecipientType      = 0x0C15
+	prDeliveryTime       = 0x0E06
+	prBody               = 0x1000
+	prRTFCompressed      = 0x1009
+	prHTML               = 0x1013
+	prDisplayName        = 0x3001
+	prEmailAddress       = 0x3003
+	prCreationTime       = 0x3007
+	prAttachData         = 0x3701
+	prAttachFilename     = 0x3704
+	prAttachMethod       = 0x3705
+	prAttachLongFilename = 0x3707
+	prSMTPAddress        = 0x39FE
+	prInternetCodepage   = 0x3FDE
+	prMessageCodepage    = 0x3FFD
+
+	ptString8 = 0x001E
+	ptUnicode = 0x001F
+	ptBinary  = 0x0102
+	ptObject  = 0x000D
+
+	attachEmbeddedMessage = 5
+)
+
+// msgStorage is one storage of a .msg file: the message itself, a recipient or an attachment
+type msgStorage struct {
+	streams  map[string][]byte // streams of the storage by name
+	fixed    map[uint16]uint64 // fixed length properties by identifier
+	codepage int               // codepage of the 8-bit strings
+}
+
+// ReadMSG parses the Outlook message represented by r of the given size
+func ReadMSG(r io.ReaderAt, size int64) (*Message, error) {
+	data := make([]byte, size)
+	if _, err := r.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	dir, err := readCFBDirectory(data)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	storages := make(map[string]*msgStorage)
+	storageOf := func(location string) *msgStorage {
+		s, ok := storages[location]
+		if !ok {
+			s = &msgStorage{streams: make(map[string][]byte), fixed: make(map[uint16]uint64), codepage: 1252}
+			storages[location] = s
+		}
+		return s
+	}
+	d, err := mscfb.New(bytes.NewReader(data))
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	for _, f := range d.File {
+		if f.FileInfo().IsDir() || len(f.Path) > 1 { // deeper streams belong to embedded messages and objects
+			continue
+		}
+		if f.Size > int64(len(data)) { // the size in the directory is not to be trusted
+			continue
+		}
+		stream := make([]byte, f.Size)
+		if _, err := io.ReadFull(f, stream); err != nil {
+			continue
+		}
+		storageOf(strings.Join(f.Path, "/")).streams[f.Name] = stream
+	}
+	root, ok := storages[""]
+	if !ok {
+		return nil, wrapError(errMSGInvalid)
+	}
+	// the header of the property stream is 32 bytes for a message file and 24 bytes for an embedded message
+	header := 32
+	if n := len(root.streams["__properties_version1.0"]); n >= 24 && (n-24)%16 == 0 {
+		header = 24
+	}
+	for location, s := range storages {
+		if location == "" {
+			s.readFixed(header)
+		} else {
+			s.readFixed(8)
+		}
+	}
+	if cp, ok := root.fixed[prInternetCodepage]; ok && cp != 0 {
+		root.codepage = int(uint32(cp))
+	} else if cp, ok := root.fixed[prMessageCodepage]; ok && cp != 0 {
+		root.codepage = int(uint32(cp))
+	}
+
+	m := &Message{
+		Subject:  root.text(prSubject),
+		Body:     root.text(prBody),
+		Date:     root.time(prClientSubmitTime),
+		Received: root.time(prDeliveryTime),
+	}
+	if m.Date.IsZero() {
+		m.Date = root.time(prCreationTime)
+	}
+	address := root.text(prSenderSMTP)
+	if address == "" {
+		address = root.text(prSenderEmail)
+	}
+	m.From = mailbox(root.text(prSenderName), address)
+	if html, ok := root.streams[substg(prHTML, ptBinary)]; ok {
+		m.HTMLBody = decodeCodepage(html, root.codepage)
+	} else {
+		m.HTMLBody = root.text(prHTML)
+	}
+	if rtf, ok := root.streams[substg(prRTFCompressed, ptBinary)]; ok {
+		if raw, err := decompressRTF(rtf); err == nil {
+			m.RTFBody = string(raw)
+		}
+	}
+
+	var locations []string
+	for location := range storages {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+	for _, location := range locations {
+		s := storages[location]
+		s.codepage = root.codepage
+		switch {
+		case strings.HasPrefix(location, "__recip_version1.0_"):
+			address := s.text(prSMTPAddress)
+			if address == "" {
+				address = s.text(prEmailAddress)
+			}
+			recipient := mailbox(s.text(prDisplayName), address)
+			switch s.fixed[prRecipientType] {
+			case 2:
+				m.Cc = append(m.Cc, recipient)
+			case 3:
+				m.Bcc = append(m.Bcc, recipient)
+			default:
+				m.To = append(m.To, recipient)
+			}
+		case strings.HasPrefix(location, "__attach_version1.0_"):
+			m.Attachments = append(m.Attachments, s.attachment(location, data, dir)...)
+		}
+	}
+	return m, nil
+}
+
+// ExtractMSGAttachments returns the attachments of an Outlook message, embedded messages become .msg files
+func ExtractMSGAttachments(r io.ReaderAt, size int64) ([]EmbeddedObject, error) {
+	m, err := ReadMSG(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return m.Attachments, nil
+}
+
+// Text returns the body of the message as plain text, converted from RTF or HTML when there is no plain text body
+func (m *Message) Text() string {
+	if strings.TrimSpace(m.Body) != "" {
+		return m.Body
+	}
+	if m.RTFBody != "" {
+		if text, _, err := RTF2Text(strings.NewReader(m.RTFBody)); err == nil && text != "" {
+			return text
+		}
+	}
+	return HTML2Text(m.HTMLBody)
+}
+
+// Header returns the From, To, Cc, Date and Subject lines of the message
+func (m *Message) Header() string {
+	var b strings.Builder
+	line := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	line("From", m.From)
+	line("To", strings.Join(m.To, ", "))
+	line("Cc", strings.Join(m.Cc, ", "))
+	line("Bcc", strings.Join(m.Bcc, ", "))
+	if !m.Date.IsZero() {
+		line("Date", m.Date.Format(time.RFC1123Z))
+	}
+	line("Subject", m.Subject)
+	return b.String()
+}
+
+func mailbox(name, address string) string {
+	switch {
+	case address == "" || address == name:
+		return name
+	case name == "":
+		return address
+	}
+	return fmt.Sprintf("%s <%s>", name, address)
+}
+
+func substg(id, typ uint16) string {
+	return fmt.Sprintf("__substg1.0_%04X%04X", id, typ)
+}
+
+// readFixed reads the 16 bytes entries of the property stream which follow a header of the given size
+func (s *msgStorage) readFixed(header int) {
+	stream := s.streams["__properties_version1.0"]
+	for off := header; off+16 <= len(stream); off += 16 {
+		tag := binary.LittleEndian.Uint32(stream[off:])
+		s.fixed[uint16(tag>>16)] = binary.LittleEndian.Uint64(stream[off+8:])
+	}
+}
+
+// text returns a string property, stored either as UTF-16 or as 8-bit characters of the message codepage
+func (s *msgStorage) text(id uint16) string {
+	if b, ok := s.streams[substg(id, ptUnicode)]; ok {
+		return strings.TrimRight(decodeUTF16(b), "\x00")
+	}
+	if b, ok := s.streams[substg(id, ptString8)]; ok {
+		return strings.TrimRight(decodeCodepage(b, s.codepage), "\x00")
+	}
+	return ""
+}
+
+// time returns a PT_SYSTIME property (100-nanosecond intervals since January 1, 1601)
+func (s *msgStorage) time(id uint16) time.Time {
+	ft, ok := s.fixed[id]
+	if !ok || ft == 0 {
+		return time.Time{}
+	}
+	return filetimeToTime(ft)
+}
+
+func filetimeToTime(ft uint64) time.Time {
+	const epochDelta = 116444736000000000 // 1601-01-01 to 1970-01-01 in 100-nanosecond intervals
+	if ft < epochDelta {
+		return time.Time{}
+	}
+	ns := (ft - epochDelta) * 100
+	return time.Unix(int64(ns/1e9), int64(ns%1e9)).UTC()
+}
+
+// attachment returns the file of an attachment storage: binary data, an embedded message or an OLE object
+func (s *msgStorage) attachment(location string, data []byte, dir []cfbDirEntry) []EmbeddedObject {
+	name := s.text(prAttachLongFilename)
+	if name == "" {
+		name = s.text(prAttachFilename)
+	}
+	if name == "" {
+		name = s.text(prDisplayName)
+	}
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if b, ok := s.streams[substg(prAttachData, ptBinary)]; ok {
+		if name == "" || name == "." || name == "/" {
+			name = "attachment" + sniffExtension(b)
+		}
+		return []EmbeddedObject{{Name: name, Data: b}}
+	}
+	for i, e := range dir {
+		if e.typ != cfbStorage || e.name != substg(prAttachData, ptObject) || strings.Join(e.path, "/") != location {
+			continue
+		}
+		if s.fixed[prAttachMethod] == attachEmbeddedMessage {
+			if name == "" || name == "." || name == "/" {
+				name = "message"
+			}
+			if !strings.EqualFold(path.Ext(name), ".msg") {
+				name += ".msg"
+			}
+			return []EmbeddedObject{{Name: name, Data: rerootCFB(data, dir, i)}}
+		}
+		return unwrapOLE(location, rerootCFB(data, dir, i))
+	}
+	return nil
+}
+
+// ---- compressed RTF ----
+// [MS-OXRTFCP] 2.2.2 Compressed RTF: LZ77 with a 4096 bytes dictionary initialized with common RTF words
+
+const rtfPrebuf = "{\\rtf1\\ansi\\mac\\deff0\\deftab720{\\fonttbl;}{\\f0\\fnil \\froman \\fswiss \\fmodern \\fscript " +
+	"\\fdecor MS Sans SerifSymbolArialTimes New RomanCourier{\\colortbl\\red0\\green0\\blue0\r\n\\par " +
+	"\\pard\\plain\\f0\\fs20\\b\\i\\u\\tab\\tx"
+
+var errRTFCompressed = errors.New("invalid compressed RTF")
+
+// decompressRTF decompresses the PR_RTF_COMPRESSED property of a message
+func decompressRTF(b []byte) ([]byte, error) {
+	if len(b) < 16 {
+		return nil, errRTFCompressed
+	}
+	compSize := int(binary.LittleEndian.Uint32(b))
+	rawSize := int(binary.LittleEndian.Uint32(b[4:]))
+	compType := string(b[8:12])
+	end := min(len(b), compSize+4)
+	if end < 16 {
+		return nil, errRTFCompressed
+	}
+	if compType == "MELA" { // uncompressed
+		return b[16:min(end, 16+rawSize)], nil
+	}
+	if compType != "LZFu" {
+		return nil, errRTFCompressed
+	}
+	var dict [4096]byte
+	copy(dict[:], rtfPrebuf)
+	write := len(rtfPrebuf)
+	// the header is not to be trusted with the size of the buffer: every 17 bytes give 136 bytes at most
+	out := bytes.NewBuffer(make([]byte, 0, min(rawSize, 8*end)))
+	for in := 16; in < end; {
+		control := b[in]
+		in++
+		for bit := 0; bit < 8 && in < end; bit++ {
+			if control&(1<<bit) == 0 {
+				dict[write%4096] = b[in]
+				out.WriteByte(b[in])
+				write++
+				in++
+				continue
+			}
+			if in+1 >= end {
+				return out.Bytes(), nil
+			}
+			ref := int(binary.BigEndian.Uint16(b[in:]))
+			in += 2
+			offset, length := ref>>4, ref&0xF+2
+			if offset == write%4096 {
+				return out.Bytes(), nil // end of the stream
+			}
+			for i := 0; i < length; i++ {
+				c := dict[(offset+i)%4096]
+				dict[write%4096] = c
+				out.WriteByte(c)
+				write++
+			}
+		}
+	}
+	return out.Bytes(), nil
+}
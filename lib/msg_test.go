@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"runtime"
+	"testing"
+	"unicode/utf16"
+)
+
+// example of [MS-OXRTFCP] 3.1.1 Compressing Plain Text
+func TestDecompressRTF(t *testing.T) {
+	compressed, _ := hex.DecodeString("2d0000002b0000004c5a4675f1c5c7a703000a00726370673132354232" +
+		"0af32068656c090020627705b06c647d0a800fa0")
+	raw, err := decompressRTF(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\\rtf1\\ansi\\ansicpg1252\\pard hello world}\r\n"; string(raw) != want {
+		t.Fatalf("got %q, want %q", raw, want)
+	}
+}
+
+func TestDecompressRTFMalformed(t *testing.T) {
+	header := func(compSize, rawSize uint32, compType string) []byte {
+		b := binary.LittleEndian.AppendUint32(nil, compSize)
+		b = binary.LittleEndian.AppendUint32(b, rawSize)
+		return append(append(b, compType...), 0, 0, 0, 0)
+	}
+	for _, c := range []struct {
+		name string
+		data []byte
+	}{
+		{"truncated", header(12, 0, "LZFu")[:15]},
+		{"uncompressed, size below the header", header(4, 16, "MELA")},
+		{"compressed, size below the header", header(8, 16, "LZFu")},
+		{"unknown type", header(12, 0, "ABCD")},
+	} {
+		if _, err := decompressRTF(c.data); err == nil {
+			t.Errorf("%s: no error", c.name)
+		}
+	}
+
+	// a raw size of 4 GB in the header of a few bytes
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	data := append(header(12+9, 0xFFFFFFFF, "LZFu"), 0, 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h')
+	raw, err := decompressRTF(data)
+	runtime.ReadMemStats(&after)
+	if err != nil || string(raw) != "abcdefgh" {
+		t.Errorf("got %q, %v", raw, err)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("%d bytes allocated", n)
+	}
+}
+
+// msgText returns the stream of a Unicode string property
+func msgText(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+func TestReadMSGStreamSize(t *testing.T) {
+	data := buildCFB(t, map[string][]byte{
+		"__properties_version1.0": make([]byte, 32),
+		"__substg1.0_0037001F":    msgText("Quarterly report"),
+		"__substg1.0_1000001F":    msgText("See the figures attached."),
+	})
+	dir, err := readCFBDirectory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range dir {
+		if e.name == "__substg1.0_1000001F" {
+			// the body claims to be 1 TB long
+			binary.LittleEndian.PutUint64(data[e.offset+0x78:], 1<<40)
+		}
+	}
+	m, err := ReadMSG(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Subject != "Quarterly report" || m.Body != "" {
+		t.Errorf("got subject %q, body %q", m.Subject, m.Body)
+	}
+}
@@ -0,0 +1,214 @@
+package lib
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ---- file odfr2textr.go ----
+// Text extraction of OpenDocument files (ODT, ODS and ODP) from their content.xml part.
+
+var errODFContent = errors.New("content.xml not found")
+
+// maximum number of copies written for a repeated table row or cell, a sheet can declare a million of them
+const odfMaxRepeat = 1000
+
+// ODF2Text reads the content.xml part of an OpenDocument package and returns its text: one line per paragraph,
+// heading or list item, tab separated cells for table rows, and sheets introduced by their name
+func ODF2Text(r io.ReaderAt, size int64) (string, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", wrapError(err)
+	}
+	for _, f := range z.File {
+		if f.Name != "content.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", wrapError(err)
+		}
+		defer rc.Close()
+		w := &odfWriter{}
+		if err := w.read(xml.NewDecoder(rc)); err != nil {
+			return "", wrapError(err)
+		}
+		return strings.TrimRight(w.out.String(), "\n"), nil
+	}
+	return "", wrapError(errODFContent)
+}
+
+type odfCell struct {
+	text   strings.Builder
+	repeat int
+}
+
+type odfRow struct {
+	cells  []string
+	repeat int
+}
+
+// odfWriter turns the stream of content.xml elements into lines of text
+type odfWriter struct {
+	out   strings.Builder
+	paras []*strings.Builder // open paragraphs and headings (frames and notes may nest them)
+	rows  []*odfRow          // open table rows
+	cells []*odfCell         // open table cells
+	lists int                // nesting level of lists
+	item  bool               // the next paragraph starts a list item
+}
+
+// elements whose text is not part of the document body
+var odfSkipped = map[string]bool{
+	"tracked-changes":   true, // text:tracked-changes holds deleted text
+	"annotation":        true, // office:annotation
+	"sequence-decls":    true,
+	"forms":             true,
+	"page-thumbnail":    true,
+	"named-expressions": true,
+}
+
+func (w *odfWriter) read(d *xml.Decoder) error {
+	skip := 0
+	spreadsheet := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 || odfSkipped[t.Name.Local] {
+				skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "spreadsheet":
+				spreadsheet = true
+			case "table":
+				if spreadsheet && len(w.cells) == 0 {
+					w.line(fmt.Sprintf("Sheet \"%s\":", attrValue(t, "name")))
+				}
+			case "p", "h":
+				para := &strings.Builder{}
+				if w.item {
+					para.WriteString(strings.Repeat("  ", w.lists-1) + "- ")
+					w.item = false
+				}
+				w.paras = append(w.paras, para)
+			case "list":
+				w.lists++
+			case "list-item":
+				w.item = true
+			case "s":
+				n, err := strconv.Atoi(attrValue(t, "c"))
+				if err != nil || n < 1 {
+					n = 1
+				}
+				w.text(strings.Repeat(" ", min(n, odfMaxRepeat)))
+			case "tab":
+				w.text("\t")
+			case "line-break":
+				w.text("\n")
+			case "table-row":
+				w.rows = append(w.rows, &odfRow{repeat: odfRepeat(t, "number-rows-repeated")})
+			case "table-cell", "covered-table-cell":
+				w.cells = append(w.cells, &odfCell{repeat: odfRepeat(t, "number-columns-repeated")})
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			switch t.Name.Local {
+			case "p", "h":
+				if n := len(w.paras); n > 0 {
+					para := w.paras[n-1]
+					w.paras = w.paras[:n-1]
+					w.line(para.String())
+				}
+			case "list":
+				w.lists--
+			case "list-item":
+				w.item = false
+			case "table-cell", "covered-table-cell":
+				if n := len(w.cells); n > 0 && len(w.rows) > 0 {
+					cell := w.cells[n-1]
+					w.cells = w.cells[:n-1]
+					row := w.rows[len(w.rows)-1]
+					for i := 0; i < cell.repeat; i++ {
+						row.cells = append(row.cells, cell.text.String())
+					}
+				}
+			case "table-row":
+				if n := len(w.rows); n > 0 {
+					row := w.rows[n-1]
+					w.rows = w.rows[:n-1]
+					for len(row.cells) > 0 && row.cells[len(row.cells)-1] == "" {
+						row.cells = row.cells[:len(row.cells)-1]
+					}
+					if len(row.cells) > 0 {
+						for i := 0; i < row.repeat; i++ {
+							w.line(strings.Join(row.cells, "\t"))
+						}
+					}
+				}
+			}
+		case xml.CharData:
+			if skip == 0 {
+				w.text(string(t))
+			}
+		}
+	}
+}
+
+// text appends character data to the innermost open paragraph (text outside paragraphs is layout only)
+func (w *odfWriter) text(s string) {
+	if n := len(w.paras); n > 0 {
+		w.paras[n-1].WriteString(s)
+	}
+}
+
+// line writes a finished paragraph or row, into the open table cell if there is one
+func (w *odfWriter) line(s string) {
+	if strings.TrimSpace(s) == "" {
+		return
+	}
+	if n := len(w.cells); n > 0 {
+		cell := &w.cells[n-1].text
+		if cell.Len() > 0 {
+			cell.WriteString(" ")
+		}
+		cell.WriteString(strings.NewReplacer("\t", " ", "\n", " ").Replace(s))
+		return
+	}
+	w.out.WriteString(s)
+	w.out.WriteString("\n")
+}
+
+// attrValue returns the value of the attribute of an element with the given local name, in any namespace
+func attrValue(t xml.StartElement, local string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// odfRepeat reads a repetition count attribute, bounded by odfMaxRepeat
+func odfRepeat(t xml.StartElement, local string) int {
+	n, err := strconv.Atoi(attrValue(t, local))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return min(n, odfMaxRepeat)
+}
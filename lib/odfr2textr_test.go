@@ -0,0 +1,71 @@
+package lib
+
+import (
+	"bytes"
+	"testing"
+)
+
+// odfContent returns the content.xml part of an OpenDocument package with the given body
+func odfContent(body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"` +
+		` xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"` +
+		` xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"` +
+		` xmlns:draw="urn:oasis:names:tc:opendocument:xmlns:drawing:1.0">` +
+		`<office:body>` + body + `</office:body></office:document-content>`
+}
+
+func TestODF2Text(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		body string
+		want string
+	}{
+		{"paragraphs", `<office:text><text:h>Title</text:h><text:p>Hello <text:span>world</text:span></text:p>` +
+			`<text:p/><text:p>a<text:s text:c="3"/>b<text:tab/>c<text:line-break/>d</text:p></office:text>`,
+			"Title\nHello world\na   b\tc\nd"},
+		{"lists", `<office:text><text:list><text:list-item><text:p>one</text:p></text:list-item>` +
+			`<text:list-item><text:p>two</text:p><text:list><text:list-item><text:p>nested</text:p>` +
+			`</text:list-item></text:list></text:list-item></text:list></office:text>`,
+			"- one\n- two\n  - nested"},
+		{"skipped elements", `<office:text><text:sequence-decls><text:sequence-decl text:name="x"/></text:sequence-decls>` +
+			`<text:tracked-changes><text:changed-region><text:p>deleted</text:p></text:changed-region></text:tracked-changes>` +
+			`<text:p>kept<office:annotation><text:p>comment</text:p></office:annotation></text:p></office:text>`,
+			"kept"},
+		{"text table", `<office:text><table:table table:name="T"><table:table-row><table:table-cell><text:p>a</text:p>` +
+			`<text:p>b</text:p></table:table-cell><table:table-cell><text:p>c</text:p></table:table-cell>` +
+			`</table:table-row></table:table></office:text>`,
+			"a b\tc"},
+		{"spreadsheet", `<office:spreadsheet><table:table table:name="Sheet1">` +
+			`<table:table-row table:number-rows-repeated="2"><table:table-cell table:number-columns-repeated="2">` +
+			`<text:p>x</text:p></table:table-cell><table:table-cell/><table:table-cell><text:p>y</text:p>` +
+			`</table:table-cell><table:table-cell table:number-columns-repeated="16384"/></table:table-row>` +
+			`<table:table-row table:number-rows-repeated="1048576"><table:table-cell table:number-columns-repeated="1024"/>` +
+			`</table:table-row></table:table><table:table table:name="Empty"/></office:spreadsheet>`,
+			"Sheet \"Sheet1\":\nx\tx\t\ty\nx\tx\t\ty\nSheet \"Empty\":"},
+		{"presentation", `<office:presentation><draw:page draw:name="page1"><draw:frame><draw:text-box>` +
+			`<text:p>Slide title</text:p></draw:text-box></draw:frame></draw:page></office:presentation>`,
+			"Slide title"},
+	} {
+		data := buildZip(t, map[string]string{"mimetype": "application/vnd.oasis.opendocument.text", "content.xml": odfContent(c.body)})
+		text, err := ODF2Text(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if text != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, text, c.want)
+		}
+	}
+}
+
+func TestODF2TextInvalid(t *testing.T) {
+	data := buildZip(t, map[string]string{"mimetype": "application/vnd.oasis.opendocument.text"})
+	if _, err := ODF2Text(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("no content.xml: no error")
+	}
+	data = buildZip(t, map[string]string{"content.xml": odfContent(`<office:text><text:p>open`)})
+	if _, err := ODF2Text(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("truncated content.xml: no error")
+	}
+}
@@ -0,0 +1,379 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// ---- file ooxmlcrypt.go ----
+// Password protected OOXML packages ([MS-OFFCRYPTO] 2.3.4): a compound file whose EncryptionInfo stream
+// describes the key derivation and whose EncryptedPackage stream holds the zip package encrypted with AES.
+
+// DefaultPassword is used by Excel to encrypt workbooks which are only protected against modification
+const DefaultPassword = "VelvetSweatshop"
+
+var (
+	ErrPasswordRequired = errors.New("document is encrypted, a password is required")
+	ErrInvalidPassword  = errors.New("invalid password")
+	errEncryptionInfo   = errors.New("unsupported or invalid EncryptionInfo")
+)
+
+// block keys of the agile encryption ([MS-OFFCRYPTO] 2.3.4.11, 2.3.4.13)
+var (
+	agileVerifierInputKey = []byte{0xfe, 0xa7, 0xd2, 0x76, 0x3b, 0x4b, 0x9e, 0x79}
+	agileVerifierValueKey = []byte{0xd7, 0xaa, 0x0f, 0x6d, 0x30, 0x61, 0x34, 0x4e}
+	agileKeyValueKey      = []byte{0x14, 0x6e, 0x0b, 0xe7, 0xab, 0xac, 0xd0, 0xd6}
+)
+
+const (
+	// size of the segments of the EncryptedPackage stream with agile encryption
+	agileSegment = 4096
+	// largest spin count of the agile encryption ([MS-OFFCRYPTO] 2.3.4.10)
+	maxSpinCount = 10000000
+)
+
+// IsEncryptedOOXML tells whether r is a compound file holding an encrypted OOXML package
+func IsEncryptedOOXML(r io.ReaderAt) bool {
+	head := make([]byte, len(cfbSignature))
+	if _, err := r.ReadAt(head, 0); err != nil || !bytes.Equal(head, cfbSignature) {
+		return false
+	}
+	d, err := mscfb.New(r)
+	if err != nil {
+		return false
+	}
+	found := 0
+	for _, f := range d.File {
+		if len(f.Path) == 0 && (f.Name == "EncryptionInfo" || f.Name == "EncryptedPackage") {
+			found++
+		}
+	}
+	return found == 2
+}
+
+// DecryptOOXML decrypts the package of an encrypted OOXML file in memory and returns it with the encryption
+// mechanism (EncryptionAgile or EncryptionStandard). The default password of Excel is tried first, then the
+// passwords of the callback: ErrPasswordRequired is returned if it has none (or is nil), ErrInvalidPassword
+// if none fits.
+func DecryptOOXML(r io.ReaderAt, passwords PasswordCallback) ([]byte, string, error) {
+	d, err := mscfb.New(r)
+	if err != nil {
+		return nil, "", err
+	}
+	var info, pkg []byte
+	for _, f := range d.File {
+		if len(f.Path) != 0 || (f.Name != "EncryptionInfo" && f.Name != "EncryptedPackage") {
+			continue
+		}
+		stream, err := io.ReadAll(f)
+		if err != nil {
+			return nil, "", wrapError(err)
+		}
+		if f.Name == "EncryptionInfo" {
+			info = stream
+		} else {
+			pkg = stream
+		}
+	}
+	if len(info) < 8 || len(pkg) < 8 {
+		return nil, "", errEncryptionInfo
+	}
+	mechanism := EncryptionStandard
+	if binary.LittleEndian.Uint32(info) == 0x00040004 {
+		mechanism = EncryptionAgile
+	}
+	var data []byte
+	var decryptErr error
+	_, err = findPassword(passwords, ErrPasswordRequired, func(password string) bool {
+		data, decryptErr = decryptPackage(info, pkg, password)
+		return decryptErr != ErrInvalidPassword
+	}, DefaultPassword)
+	if err != nil {
+		return nil, mechanism, err
+	}
+	return data, mechanism, decryptErr
+}
+
+func decryptPackage(info, pkg []byte, password string) ([]byte, error) {
+	if len(info) < 8 {
+		return nil, errEncryptionInfo
+	}
+	major := binary.LittleEndian.Uint16(info)
+	minor := binary.LittleEndian.Uint16(info[2:])
+	switch {
+	case major == 4 && minor == 4:
+		return decryptAgile(info[8:], pkg, password)
+	case (major == 2 || major == 3 || major == 4) && minor == 2:
+		return decryptStandard(info[8:], pkg, password)
+	}
+	return nil, fmt.Errorf("%w: version %d.%d", errEncryptionInfo, major, minor)
+}
+
+// agileEncryption is the XML descriptor of the agile encryption ([MS-OFFCRYPTO] 2.3.4.10)
+type agileEncryption struct {
+	KeyData agileParams `xml:"keyData"`
+	Keys    []struct {
+		agileParams
+		SpinCount                  int    `xml:"spinCount,attr"`
+		EncryptedVerifierHashInput string `xml:"encryptedVerifierHashInput,attr"`
+		EncryptedVerifierHashValue string `xml:"encryptedVerifierHashValue,attr"`
+		EncryptedKeyValue          string `xml:"encryptedKeyValue,attr"`
+		URI                        string `xml:"uri,attr"`
+	} `xml:"keyEncryptors>keyEncryptor>encryptedKey"`
+}
+
+type agileParams struct {
+	SaltValue       string `xml:"saltValue,attr"`
+	HashAlgorithm   string `xml:"hashAlgorithm,attr"`
+	CipherAlgorithm string `xml:"cipherAlgorithm,attr"`
+	CipherChaining  string `xml:"cipherChaining,attr"`
+	KeyBits         int    `xml:"keyBits,attr"`
+	BlockSize       int    `xml:"blockSize,attr"`
+}
+
+// valid tells whether the key and block sizes are those of AES
+func (p agileParams) valid() bool {
+	return validKeySize(p.KeyBits/8) && p.KeyBits%8 == 0 && p.BlockSize == aes.BlockSize
+}
+
+// decryptAgile derives the key encryption key from the password (hash iterated spinCount times), checks
+// the verifier, decrypts the package key and then the package, segment by segment
+func decryptAgile(descriptor, pkg []byte, password string) ([]byte, error) {
+	var enc agileEncryption
+	if err := xml.Unmarshal(descriptor, &enc); err != nil {
+		return nil, fmt.Errorf("%w: %v", errEncryptionInfo, err)
+	}
+	if enc.KeyData.CipherAlgorithm != "AES" || enc.KeyData.CipherChaining != "ChainingModeCBC" {
+		return nil, fmt.Errorf("%w: cipher %s %s", errEncryptionInfo, enc.KeyData.CipherAlgorithm, enc.KeyData.CipherChaining)
+	}
+	if !enc.KeyData.valid() {
+		return nil, fmt.Errorf("%w: %d bit key, %d byte blocks", errEncryptionInfo, enc.KeyData.KeyBits, enc.KeyData.BlockSize)
+	}
+	for _, key := range enc.Keys {
+		if key.URI != "" && key.URI != "http://schemas.microsoft.com/office/2006/keyEncryptor/password" {
+			continue // certificate key encryptor
+		}
+		newHash := hashFunc(key.HashAlgorithm)
+		salt, err1 := base64.StdEncoding.DecodeString(key.SaltValue)
+		input, err2 := base64.StdEncoding.DecodeString(key.EncryptedVerifierHashInput)
+		value, err3 := base64.StdEncoding.DecodeString(key.EncryptedVerifierHashValue)
+		keyValue, err4 := base64.StdEncoding.DecodeString(key.EncryptedKeyValue)
+		if newHash == nil || errors.Join(err1, err2, err3, err4) != nil || !key.valid() {
+			return nil, errEncryptionInfo
+		}
+		if key.SpinCount < 0 || key.SpinCount > maxSpinCount {
+			return nil, fmt.Errorf("%w: spin count %d", errEncryptionInfo, key.SpinCount)
+		}
+		h := newHash()
+		h.Write(salt)
+		h.Write(utf16LE(password))
+		digest := h.Sum(nil)
+		for i := 0; i < key.SpinCount; i++ {
+			h.Reset()
+			binary.Write(h, binary.LittleEndian, uint32(i))
+			h.Write(digest)
+			digest = h.Sum(digest[:0])
+		}
+		derive := func(blockKey []byte) []byte {
+			h.Reset()
+			h.Write(digest)
+			h.Write(blockKey)
+			return fitLength(h.Sum(nil), key.KeyBits/8, 0x36)
+		}
+		verifierInput, err1 := decryptCBC(derive(agileVerifierInputKey), salt, input)
+		verifierValue, err2 := decryptCBC(derive(agileVerifierValueKey), salt, value)
+		if errors.Join(err1, err2) != nil || len(verifierInput) < len(salt) {
+			return nil, errEncryptionInfo
+		}
+		h.Reset()
+		h.Write(verifierInput[:len(salt)])
+		if sum := h.Sum(nil); len(verifierValue) < len(sum) || subtle.ConstantTimeCompare(sum, verifierValue[:len(sum)]) != 1 {
+			return nil, ErrInvalidPassword
+		}
+		packageKey, err := decryptCBC(derive(agileKeyValueKey), salt, keyValue)
+		if err != nil || len(packageKey) < enc.KeyData.KeyBits/8 {
+			return nil, errEncryptionInfo
+		}
+		return decryptAgileSegments(enc.KeyData, packageKey[:enc.KeyData.KeyBits/8], pkg)
+	}
+	return nil, fmt.Errorf("%w: no password key encryptor", errEncryptionInfo)
+}
+
+// decryptAgileSegments decrypts the segments of EncryptedPackage, the IV of each segment being the hash of
+// the key data salt and the segment number
+func decryptAgileSegments(params agileParams, key, pkg []byte) ([]byte, error) {
+	newHash := hashFunc(params.HashAlgorithm)
+	salt, err := base64.StdEncoding.DecodeString(params.SaltValue)
+	if newHash == nil || err != nil {
+		return nil, errEncryptionInfo
+	}
+	size := binary.LittleEndian.Uint64(pkg)
+	encrypted := pkg[8:]
+	if size > uint64(len(encrypted)) {
+		return nil, fmt.Errorf("%w: truncated package", errEncryptionInfo)
+	}
+	out := make([]byte, 0, len(encrypted))
+	h := newHash()
+	for i := 0; len(encrypted) > 0; i++ {
+		n := min(agileSegment, len(encrypted))
+		h.Reset()
+		h.Write(salt)
+		binary.Write(h, binary.LittleEndian, uint32(i))
+		iv := fitLength(h.Sum(nil), params.BlockSize, 0x36)
+		segment, err := decryptCBC(key, iv, encrypted[:n])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, segment...)
+		encrypted = encrypted[n:]
+	}
+	return out[:size], nil
+}
+
+// decryptStandard derives the key with SHA-1 iterated 50000 times ([MS-OFFCRYPTO] 2.3.4.7), checks the
+// verifier and decrypts the package with AES-ECB
+func decryptStandard(info, pkg []byte, password string) ([]byte, error) {
+	if len(info) < 4 {
+		return nil, errEncryptionInfo
+	}
+	headerSize := int(binary.LittleEndian.Uint32(info))
+	if headerSize < 32 || 4+headerSize+4+16+16+4+32 > len(info) {
+		return nil, errEncryptionInfo
+	}
+	header := info[4 : 4+headerSize]
+	algID := binary.LittleEndian.Uint32(header[8:])
+	keySize := int(binary.LittleEndian.Uint32(header[16:])) / 8
+	if algID != 0x660E && algID != 0x660F && algID != 0x6610 { // AES-128, AES-192, AES-256
+		return nil, fmt.Errorf("%w: algorithm 0x%04X", errEncryptionInfo, algID)
+	}
+	if !validKeySize(keySize) {
+		return nil, fmt.Errorf("%w: %d byte key", errEncryptionInfo, keySize)
+	}
+	verifier := info[4+headerSize:]
+	saltSize := int(binary.LittleEndian.Uint32(verifier))
+	if saltSize != 16 {
+		return nil, errEncryptionInfo
+	}
+	salt := verifier[4:20]
+	encryptedVerifier := verifier[20:36]
+	hashSize := int(binary.LittleEndian.Uint32(verifier[36:]))
+	encryptedHash := verifier[40:72]
+
+	key := standardKey(salt, password, keySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errEncryptionInfo
+	}
+	plainVerifier := decryptECB(block, encryptedVerifier)
+	plainHash := decryptECB(block, encryptedHash)
+	sum := sha1.Sum(plainVerifier)
+	if hashSize > len(plainHash) || subtle.ConstantTimeCompare(sum[:], plainHash[:min(hashSize, sha1.Size)]) != 1 {
+		return nil, ErrInvalidPassword
+	}
+	size := binary.LittleEndian.Uint64(pkg)
+	encrypted := pkg[8:]
+	encrypted = encrypted[:len(encrypted)/aes.BlockSize*aes.BlockSize]
+	if size > uint64(len(encrypted)) {
+		return nil, fmt.Errorf("%w: truncated package", errEncryptionInfo)
+	}
+	return decryptECB(block, encrypted)[:size], nil
+}
+
+// standardKey derives the encryption key of the standard encryption from the password
+func standardKey(salt []byte, password string, keySize int) []byte {
+	h := sha1.New()
+	h.Write(salt)
+	h.Write(utf16LE(password))
+	digest := h.Sum(nil)
+	for i := 0; i < 50000; i++ {
+		h.Reset()
+		binary.Write(h, binary.LittleEndian, uint32(i))
+		h.Write(digest)
+		digest = h.Sum(digest[:0])
+	}
+	h.Reset()
+	h.Write(digest)
+	binary.Write(h, binary.LittleEndian, uint32(0)) // block 0
+	final := h.Sum(nil)
+	derived := func(fill byte) []byte {
+		buf := bytes.Repeat([]byte{fill}, 64)
+		for i, b := range final {
+			buf[i] ^= b
+		}
+		sum := sha1.Sum(buf)
+		return sum[:]
+	}
+	return append(derived(0x36), derived(0x5C)...)[:keySize]
+}
+
+// validKeySize tells whether n is the key size of AES-128, AES-192 or AES-256 in bytes
+func validKeySize(n int) bool {
+	return n == 16 || n == 24 || n == 32
+}
+
+func hashFunc(name string) func() hash.Hash {
+	switch name {
+	case "SHA1", "SHA-1":
+		return sha1.New
+	case "SHA256", "SHA-256":
+		return sha256.New
+	case "SHA384", "SHA-384":
+		return sha512.New384
+	case "SHA512", "SHA-512":
+		return sha512.New
+	}
+	return nil
+}
+
+// fitLength truncates b to n bytes or pads it with the given byte
+func fitLength(b []byte, n int, pad byte) []byte {
+	if len(b) >= n {
+		return b[:n]
+	}
+	return append(b, bytes.Repeat([]byte{pad}, n-len(b))...)
+}
+
+func decryptCBC(key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) < block.BlockSize() || len(data)%block.BlockSize() != 0 {
+		return nil, errEncryptionInfo
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv[:block.BlockSize()]).CryptBlocks(out, data)
+	return out, nil
+}
+
+func decryptECB(block cipher.Block, data []byte) []byte {
+	out := make([]byte, len(data))
+	for i := 0; i+block.BlockSize() <= len(data); i += block.BlockSize() {
+		block.Decrypt(out[i:], data[i:])
+	}
+	return out
+}
+
+func utf16LE(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
@@ -0,0 +1,156 @@
+package lib
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// checkPackage tells whether data is a zip package whose members all read back with their checksums
+func checkPackage(t *testing.T, name string, data []byte, members int) {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	if len(z.File) != members {
+		t.Errorf("%s: %d members, want %d", name, len(z.File), members)
+	}
+	for _, f := range z.File {
+		rc, err := f.Open()
+		if err == nil {
+			_, err = io.Copy(io.Discard, rc)
+			rc.Close()
+		}
+		if err != nil {
+			t.Errorf("%s: %s: %v", name, f.Name, err)
+		}
+	}
+}
+
+// encryptAES.xlsx and encryptSHA1.xlsx are test files of github.com/xuri/excelize (BSD 3-Clause License,
+// see testdata/LICENSE-excelize), encrypted by Excel with the password "password"
+func TestDecryptOOXML(t *testing.T) {
+	for _, c := range []struct {
+		file      string
+		mechanism string
+		members   int
+	}{
+		{"testdata/encryptAES.xlsx", EncryptionStandard, 9},
+		{"testdata/encryptSHA1.xlsx", EncryptionAgile, 10}, // two segments
+	} {
+		file, err := os.ReadFile(c.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncryptedOOXML(bytes.NewReader(file)) {
+			t.Errorf("%s: not encrypted", c.file)
+		}
+		data, mechanism, err := DecryptOOXML(bytes.NewReader(file), Password("password"))
+		if err != nil {
+			t.Errorf("%s: %v", c.file, err)
+			continue
+		}
+		if mechanism != c.mechanism {
+			t.Errorf("%s: mechanism %q, want %q", c.file, mechanism, c.mechanism)
+		}
+		checkPackage(t, c.file, data, c.members)
+		if _, _, err := DecryptOOXML(bytes.NewReader(file), nil); err != ErrPasswordRequired {
+			t.Errorf("%s without password: %v", c.file, err)
+		}
+		if _, _, err := DecryptOOXML(bytes.NewReader(file), Passwords([]string{"wrong", "Password"})); err != ErrInvalidPassword {
+			t.Errorf("%s with wrong passwords: %v", c.file, err)
+		}
+	}
+}
+
+// encryptStandard returns the EncryptionInfo and EncryptedPackage streams of a package encrypted with
+// AES-128 standard encryption ([MS-OFFCRYPTO] 2.3.4.5)
+func encryptStandard(t *testing.T, pkg []byte, password string) (info, encrypted []byte) {
+	salt := []byte("0123456789abcdef")
+	block, err := aes.NewCipher(standardKey(salt, password, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptECB := func(b []byte) []byte {
+		b = append(b, make([]byte, (aes.BlockSize-len(b)%aes.BlockSize)%aes.BlockSize)...)
+		out := make([]byte, len(b))
+		for i := 0; i < len(b); i += aes.BlockSize {
+			block.Encrypt(out[i:], b[i:])
+		}
+		return out
+	}
+	verifier := []byte("fedcba9876543210")
+	hash := sha1.Sum(verifier)
+
+	info = []byte{4, 0, 2, 0, 0x24, 0, 0, 0} // version 4.2, fCryptoAPI and fAES
+	info = binary.LittleEndian.AppendUint32(info, 32)
+	for _, v := range []uint32{0x24, 0, 0x660E, 0x8004, 128, 0x18, 0, 0} {
+		info = binary.LittleEndian.AppendUint32(info, v)
+	}
+	info = binary.LittleEndian.AppendUint32(info, 16)
+	info = append(append(info, salt...), encryptECB(verifier)...)
+	info = binary.LittleEndian.AppendUint32(info, sha1.Size)
+	info = append(info, encryptECB(hash[:])...)
+
+	encrypted = binary.LittleEndian.AppendUint64(nil, uint64(len(pkg)))
+	return info, append(encrypted, encryptECB(append([]byte{}, pkg...))...)
+}
+
+func TestDecryptOOXMLDefaultPassword(t *testing.T) {
+	pkg := buildZip(t, map[string]string{
+		"[Content_Types].xml": `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`,
+		"xl/workbook.xml":     `<workbook/>`,
+	})
+	info, encrypted := encryptStandard(t, pkg, DefaultPassword)
+	file := buildCFB(t, map[string][]byte{"EncryptionInfo": info, "EncryptedPackage": encrypted})
+	data, mechanism, err := DecryptOOXML(bytes.NewReader(file), nil)
+	if err != nil || mechanism != EncryptionStandard || !bytes.Equal(data, pkg) {
+		t.Errorf("got %d bytes, %q, %v", len(data), mechanism, err)
+	}
+}
+
+// invalid sizes and spin counts are rejected before any key is derived
+func TestDecryptOOXMLInvalidInfo(t *testing.T) {
+	pkg := buildZip(t, map[string]string{"[Content_Types].xml": `<Types/>`})
+	info, encrypted := encryptStandard(t, pkg, "password")
+	for _, bits := range []uint32{0, 40, 1024, 0xFFFFFFF8} {
+		bad := append([]byte{}, info...)
+		binary.LittleEndian.PutUint32(bad[28:], bits) // KeySize of the header
+		if _, err := decryptPackage(append([]byte{3, 0, 2, 0}, bad...), encrypted, "password"); !errors.Is(err, errEncryptionInfo) {
+			t.Errorf("standard, %d bit key: %v", bits, err)
+		}
+	}
+
+	agile := func(keyBits, blockSize, spinCount int) []byte {
+		params := fmt.Sprintf(`saltValue="MDEyMzQ1Njc4OWFiY2RlZg==" hashAlgorithm="SHA512" cipherAlgorithm="AES"`+
+			` cipherChaining="ChainingModeCBC" keyBits="%d" blockSize="%d"`, keyBits, blockSize)
+		descriptor := `<encryption xmlns="http://schemas.microsoft.com/office/2006/encryption"` +
+			` xmlns:p="http://schemas.microsoft.com/office/2006/keyEncryptor/password"><keyData ` + params + `/>` +
+			`<keyEncryptors><keyEncryptor uri="http://schemas.microsoft.com/office/2006/keyEncryptor/password">` +
+			fmt.Sprintf(`<p:encryptedKey %s spinCount="%d" encryptedVerifierHashInput="" encryptedVerifierHashValue=""`, params, spinCount) +
+			` encryptedKeyValue=""/></keyEncryptor></keyEncryptors></encryption>`
+		zeros := strings.Repeat("A", 64) // 48 zero bytes
+		descriptor = strings.ReplaceAll(descriptor, `=""`, `="`+zeros+`"`)
+		return append([]byte{4, 0, 4, 0, 0x40, 0, 0, 0}, descriptor...)
+	}
+	for _, c := range [][3]int{{0, 16, 100000}, {-128, 16, 100000}, {1 << 20, 16, 100000}, {256, 0, 100000},
+		{256, 1 << 30, 100000}, {256, 16, -1}, {256, 16, maxSpinCount + 1}} {
+		if _, err := decryptPackage(agile(c[0], c[1], c[2]), encrypted, "password"); !errors.Is(err, errEncryptionInfo) {
+			t.Errorf("agile, %d bit key, %d byte blocks, spin count %d: %v", c[0], c[1], c[2], err)
+		}
+	}
+	// the descriptor is otherwise read up to the verifier
+	if _, err := decryptPackage(agile(256, 16, 10), encrypted, "password"); err != ErrInvalidPassword {
+		t.Errorf("agile: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package lib
+
+import (
+	"io"
+
+	"github.com/WhityGhost/gh0ffice/lib/pdf"
+)
+
+// ---- file passwords.go ----
+// Passwords of the encrypted formats (OOXML packages, legacy Office binaries, PDF) and the mechanisms
+// which protect them.
+
+// encryption mechanisms reported by DecryptOOXML, DecryptCFB and OpenPDF
+const (
+	EncryptionAgile     = "ooxml-agile"    // OOXML agile encryption (AES, iterated SHA-512)
+	EncryptionStandard  = "ooxml-standard" // OOXML standard encryption (AES-128, SHA-1)
+	EncryptionRC4       = "rc4"            // Word, Excel or PowerPoint binary, RC4 with a 40-bit MD5 key
+	EncryptionCryptoAPI = "rc4-cryptoapi"  // Word, Excel or PowerPoint binary, RC4 CryptoAPI
+	EncryptionXOR       = "xor"            // Word or Excel binary, XOR obfuscation
+	EncryptionPDFRC4    = "pdf-rc4"        // PDF standard security handler, RC4 (V=1, 2 or 4)
+	EncryptionPDFAES128 = "pdf-aes-128"    // PDF standard security handler, AESV2 (V=4)
+	EncryptionPDFAES256 = "pdf-aes-256"    // PDF standard security handler, AESV3 (V=5)
+)
+
+// PasswordCallback is asked for the passwords to try on an encrypted document, attempt counting from 0,
+// until it returns false
+type PasswordCallback func(attempt int) (password string, ok bool)
+
+// Password returns a PasswordCallback which gives a single password, or none if it is empty
+func Password(password string) PasswordCallback {
+	return func(attempt int) (string, bool) {
+		return password, attempt == 0 && password != ""
+	}
+}
+
+// Passwords returns a PasswordCallback which gives the passwords of a list, empty ones excepted
+func Passwords(passwords []string) PasswordCallback {
+	var list []string
+	for _, password := range passwords {
+		if password != "" {
+			list = append(list, password)
+		}
+	}
+	return func(attempt int) (string, bool) {
+		if attempt >= len(list) {
+			return "", false
+		}
+		return list[attempt], true
+	}
+}
+
+// OpenPDF opens a PDF file and returns its encryption mechanism, if any. Files encrypted with a user
+// password other than the empty one ask the callback for passwords: ErrPasswordRequired is returned if it
+// has none (or is nil), ErrInvalidPassword if none fits.
+func OpenPDF(r io.ReaderAt, size int64, passwords PasswordCallback) (*pdf.Reader, string, error) {
+	attempt, tried := 0, 0
+	reader, err := pdf.NewReaderEncrypted(r, size, func() string {
+		if passwords == nil {
+			return ""
+		}
+		for {
+			password, ok := passwords(attempt)
+			if !ok {
+				return ""
+			}
+			attempt++
+			if password != "" { // an empty answer would stop NewReaderEncrypted, which tried it first
+				tried++
+				return password
+			}
+		}
+	})
+	if err == pdf.ErrInvalidPassword {
+		if tried == 0 {
+			return nil, "", ErrPasswordRequired
+		}
+		return nil, "", ErrInvalidPassword
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	switch reader.Encryption() {
+	case "RC4":
+		return reader, EncryptionPDFRC4, nil
+	case "AESV2":
+		return reader, EncryptionPDFAES128, nil
+	case "AESV3":
+		return reader, EncryptionPDFAES256, nil
+	}
+	return reader, "", nil
+}
+
+// findPassword returns the first password accepted by fits: the given defaults, then those of the callback.
+// required is returned when the callback has no password to try.
+func findPassword(passwords PasswordCallback, required error, fits func(string) bool, defaults ...string) (string, error) {
+	for _, password := range defaults {
+		if fits(password) {
+			return password, nil
+		}
+	}
+	if passwords == nil {
+		return "", required
+	}
+	for attempt := 0; ; attempt++ {
+		password, ok := passwords(attempt)
+		if !ok {
+			if attempt == 0 {
+				return "", required
+			}
+			return "", ErrInvalidPassword
+		}
+		if fits(password) {
+			return password, nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package lib
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// answers lists the passwords given by a callback
+func answers(passwords PasswordCallback) []string {
+	var list []string
+	for attempt := 0; attempt < 10; attempt++ {
+		password, ok := passwords(attempt)
+		if !ok {
+			break
+		}
+		list = append(list, password)
+	}
+	return list
+}
+
+func TestPasswordCallbacks(t *testing.T) {
+	if got := answers(Password("secret")); len(got) != 1 || got[0] != "secret" {
+		t.Errorf("Password: %q", got)
+	}
+	if got := answers(Password("")); len(got) != 0 {
+		t.Errorf("empty Password: %q", got)
+	}
+	if got := answers(Passwords([]string{"", "a", "", "b"})); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("Passwords: %q", got)
+	}
+	if got := answers(Passwords(nil)); len(got) != 0 {
+		t.Errorf("no Passwords: %q", got)
+	}
+}
+
+// the file is encrypted with the user password "user" and the owner password "owner"
+func TestOpenPDF(t *testing.T) {
+	data, err := os.ReadFile("pdf/testdata/aes-128.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func(passwords PasswordCallback) (string, error) {
+		_, mechanism, err := OpenPDF(bytes.NewReader(data), int64(len(data)), passwords)
+		return mechanism, err
+	}
+	if _, err := open(nil); err != ErrPasswordRequired {
+		t.Errorf("nil callback: %v", err)
+	}
+	if _, err := open(Passwords(nil)); err != ErrPasswordRequired {
+		t.Errorf("no passwords: %v", err)
+	}
+	if _, err := open(Passwords([]string{"wrong", "USER"})); err != ErrInvalidPassword {
+		t.Errorf("wrong passwords: %v", err)
+	}
+	if mechanism, err := open(Passwords([]string{"wrong", "owner"})); err != nil || mechanism != EncryptionPDFAES128 {
+		t.Errorf("owner password: %q, %v", mechanism, err)
+	}
+
+	// empty answers of a callback are skipped, not taken for the end of the list
+	calls := 0
+	mechanism, err := open(func(attempt int) (string, bool) {
+		calls++
+		return []string{"", "wrong", "", "user"}[attempt], attempt < 4
+	})
+	if err != nil || mechanism != EncryptionPDFAES128 || calls != 4 {
+		t.Errorf("callback with empty answers: %q, %v after %d calls", mechanism, err, calls)
+	}
+	if _, err := open(func(attempt int) (string, bool) { return "", attempt < 3 }); err != ErrPasswordRequired {
+		t.Errorf("callback with empty answers only: %v", err)
+	}
+
+	// unencrypted files have no mechanism
+	plain := buildPDF("<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [] /Count 0 >>")
+	if _, mechanism, err := OpenPDF(bytes.NewReader(plain), int64(len(plain)), Password("user")); err != nil || mechanism != "" {
+		t.Errorf("unencrypted: %q, %v", mechanism, err)
+	}
+}
@@ -0,0 +1,178 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ActiveContent is an action, a script or an attachment found in an object of the file
+type ActiveContent struct {
+	Key    string // OpenAction, AA, JavaScript, Launch, EmbeddedFile or Encrypt
+	Object string // object holding it, e.g. "12 0 obj", or "trailer"
+	Detail string // script excerpt, launched file, attachment name, triggers...
+}
+
+// maximum length of the scripts quoted in ActiveContent.Detail
+const activeExcerpt = 200
+
+// ActiveContent lists the objects of the file which run actions (document open action, additional actions,
+// JavaScript, Launch) or hold attachments, and the encryption of the file if any. Objects which cannot be
+// read are skipped.
+func (r *Reader) ActiveContent() []ActiveContent {
+	var found []ActiveContent
+	if enc := r.trailer["Encrypt"]; enc != nil {
+		v := r.safeResolve(objptr{}, enc)
+		found = append(found, ActiveContent{Key: "Encrypt", Object: "trailer",
+			Detail: fmt.Sprintf("%s V=%d R=%d", v.safeKey("Filter").Name(), v.safeKey("V").Int64(), v.safeKey("R").Int64())})
+	}
+	for id, x := range r.xref {
+		if x.ptr.id != uint32(id) || (!x.inStream && x.offset == 0) {
+			continue
+		}
+		v := r.safeResolve(objptr{}, x.ptr)
+		location := fmt.Sprintf("%d %d obj", x.ptr.id, x.ptr.gen)
+		found = append(found, activeIn(v, v.data, location, 0)...)
+	}
+	return found
+}
+
+// safeResolve resolves x, returning a null Value instead of panicking on malformed objects
+func (r *Reader) safeResolve(parent objptr, x interface{}) (v Value) {
+	defer func() {
+		if recover() != nil {
+			v = Value{}
+		}
+	}()
+	return r.resolve(parent, x)
+}
+
+// activeIn inspects a dictionary and the direct (not referenced) dictionaries and arrays it contains
+func activeIn(v Value, x object, location string, depth int) []ActiveContent {
+	if depth > 8 {
+		return nil
+	}
+	var found []ActiveContent
+	switch x := x.(type) {
+	case stream:
+		return activeIn(v, x.hdr, location, depth)
+	case array:
+		for _, e := range x {
+			found = append(found, activeIn(v, e, location, depth+1)...)
+		}
+		return found
+	case dict:
+		d := Value{v.r, v.ptr, x}
+		if _, ok := x["OpenAction"]; ok {
+			found = append(found, ActiveContent{Key: "OpenAction", Object: location, Detail: describeAction(d.safeKey("OpenAction"))})
+		}
+		if _, ok := x["AA"]; ok {
+			found = append(found, ActiveContent{Key: "AA", Object: location, Detail: strings.Join(d.safeKey("AA").Keys(), " ")})
+		}
+		switch x["S"] {
+		case name("JavaScript"):
+			found = append(found, ActiveContent{Key: "JavaScript", Object: location, Detail: excerpt(scriptText(d.safeKey("JS")))})
+		case name("Launch"):
+			found = append(found, ActiveContent{Key: "Launch", Object: location, Detail: describeAction(d)})
+		}
+		if _, ok := x["EF"]; ok {
+			found = append(found, ActiveContent{Key: "EmbeddedFile", Object: location, Detail: fileSpecName(d)})
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			if k != "Parent" && k != "P" {
+				keys = append(keys, string(k))
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			found = append(found, activeIn(v, x[name(k)], location, depth+1)...)
+		}
+	}
+	return found
+}
+
+// safeKey is Key without panics on malformed objects
+func (v Value) safeKey(key string) (k Value) {
+	defer func() {
+		if recover() != nil {
+			k = Value{}
+		}
+	}()
+	return v.Key(key)
+}
+
+// describeAction returns the type and target of an action, or the destination of an open action
+func describeAction(a Value) string {
+	switch a.Kind() {
+	case Array:
+		return "GoTo"
+	case Dict:
+		s := a.safeKey("S").Name()
+		switch s {
+		case "JavaScript":
+			return s + ": " + excerpt(scriptText(a.safeKey("JS")))
+		case "Launch":
+			target := fileSpecName(a)
+			if target == "" {
+				target = fileSpecName(a.safeKey("Win"))
+			}
+			if params := a.safeKey("Win").safeKey("P").Text(); params != "" {
+				target += " " + params
+			}
+			return s + ": " + target
+		case "URI":
+			return s + ": " + a.safeKey("URI").RawString()
+		case "GoToR", "GoToE", "ImportData", "SubmitForm":
+			return s + ": " + fileSpecName(a)
+		}
+		return s
+	}
+	return ""
+}
+
+// fileSpecName returns the file named by the F or UF entry of a file specification or an action
+func fileSpecName(v Value) string {
+	f := v.safeKey("F")
+	if f.Kind() == Dict {
+		if uf := f.safeKey("UF").Text(); uf != "" {
+			return uf
+		}
+		return f.safeKey("F").Text()
+	}
+	if uf := v.safeKey("UF").Text(); uf != "" {
+		return uf
+	}
+	return f.Text()
+}
+
+// scriptText returns a JavaScript given as a text string or a stream
+func scriptText(js Value) (text string) {
+	defer func() {
+		if recover() != nil {
+			text = ""
+		}
+	}()
+	if js.Kind() == Stream {
+		rd := js.Reader()
+		defer rd.Close()
+		b, _ := io.ReadAll(io.LimitReader(rd, 4*activeExcerpt)) // enough for excerpt, once white space is collapsed
+		return string(b)
+	}
+	return js.Text()
+}
+
+// excerpt collapses the white space of a script and cuts it at activeExcerpt bytes, on a rune boundary
+func excerpt(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > activeExcerpt {
+		n := activeExcerpt
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		s = s[:n] + "..."
+	}
+	return s
+}
@@ -0,0 +1,17 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestExcerpt(t *testing.T) {
+	s := excerpt(strings.Repeat("a", activeExcerpt-1) + "éé")
+	if !utf8.ValidString(s) || s != strings.Repeat("a", activeExcerpt-1)+"..." {
+		t.Errorf("got %q", s)
+	}
+	if s := excerpt("app.alert(1);\n\n  run()"); s != "app.alert(1); run()" {
+		t.Errorf("got %q", s)
+	}
+}
@@ -0,0 +1,155 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// An Annotation is a note, a text markup, a drawing or a link put on a page
+type Annotation struct {
+	Subtype  string // Text (sticky note), FreeText, Highlight, Underline, StrikeOut, Squiggly, Ink, Link...
+	Page     int    // number of the page of the annotation
+	Rect     Rect
+	Author   string // T entry, the name of the reviewer of markup annotations
+	Contents string
+	Modified time.Time
+	Text     string // text of the page under a text markup annotation (its QuadPoints) or a link
+	URI      string // target of a link to a URI, or file opened by a link to another document
+	Dest     int    // number of the page a link leads to in the document, 0 if none
+}
+
+// Annotations returns the annotations of the page, but its popups (which show the contents of
+// others) and the widgets of the form fields, see Reader.Fields
+func (p Page) Annotations() ([]Annotation, error) {
+	num := 0
+	if r := p.V.r; r != nil {
+		num = r.pageNumber(p.V)
+	}
+	return p.annotations(num)
+}
+
+// annotations returns the annotations of the page of that number
+func (p Page) annotations(num int) (annots []Annotation, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			annots = nil
+			err = errors.New(fmt.Sprint(r))
+		}
+	}()
+	var (
+		content []Text
+		read    bool
+	)
+	r := p.V.r
+	list := p.V.Key("Annots")
+	for i := 0; i < list.Len(); i++ {
+		v := list.Index(i)
+		subtype := v.safeKey("Subtype").Name()
+		if v.Kind() != Dict || subtype == "Popup" || subtype == "Widget" {
+			continue
+		}
+		a := Annotation{
+			Subtype:  subtype,
+			Page:     num,
+			Rect:     rectOf(v.safeKey("Rect")),
+			Author:   v.safeKey("T").Text(),
+			Contents: v.safeKey("Contents").Text(),
+			Modified: parseDate(v.safeKey("M").Text()),
+		}
+		quads := quadRects(v.safeKey("QuadPoints"))
+		switch subtype {
+		case "Highlight", "Underline", "StrikeOut", "Squiggly":
+		case "Link":
+			if len(quads) == 0 {
+				quads = []Rect{a.Rect}
+			}
+			if dest := v.safeKey("Dest"); dest.Kind() != Null {
+				a.Dest = r.destPage(dest)
+			} else {
+				a.Dest, a.URI = r.actionTarget(v.safeKey("A"))
+			}
+		default:
+			quads = nil
+		}
+		if len(quads) > 0 {
+			if !read {
+				content, read = pageText(p), true
+			}
+			a.Text = coveredText(content, quads)
+		}
+		annots = append(annots, a)
+	}
+	return annots, nil
+}
+
+// Annotations returns the annotations of all the pages, see Page.Annotations. The pages which cannot be
+// read are skipped.
+func (r *Reader) Annotations() []Annotation {
+	var annots []Annotation
+	for i := 1; i <= r.NumPage(); i++ {
+		v := r.safePage(i)
+		if v.Kind() != Dict {
+			continue
+		}
+		a, _ := Page{v}.annotations(i)
+		annots = append(annots, a...)
+	}
+	return annots
+}
+
+// pageText is the text of p.Content, or none for the pages whose content cannot be read
+func pageText(p Page) (text []Text) {
+	defer func() {
+		recover()
+	}()
+	return p.Content().Text
+}
+
+// rectOf returns the rectangle of an array [x1 y1 x2 y2] of two opposite corners
+func rectOf(v Value) Rect {
+	x1, y1, x2, y2 := v.Index(0).Float64(), v.Index(1).Float64(), v.Index(2).Float64(), v.Index(3).Float64()
+	return Rect{Point{min(x1, x2), min(y1, y2)}, Point{max(x1, x2), max(y1, y2)}}
+}
+
+// quadRects returns the rectangles bounding the quadrilaterals of a QuadPoints array, which has the eight
+// coordinates of the corners of each
+func quadRects(v Value) []Rect {
+	var rects []Rect
+	for i := 0; i+8 <= v.Len(); i += 8 {
+		r := Rect{Point{math.Inf(1), math.Inf(1)}, Point{math.Inf(-1), math.Inf(-1)}}
+		for j := i; j < i+8; j += 2 {
+			x, y := v.Index(j).Float64(), v.Index(j+1).Float64()
+			r = union(r, Rect{Point{x, y}, Point{x, y}})
+		}
+		rects = append(rects, r)
+	}
+	return rects
+}
+
+// coveredText returns the text of the glyphs whose middle is in one of the rectangles, in reading order
+func coveredText(text []Text, rects []Rect) string {
+	var in []Text
+	for _, t := range text {
+		if t.S == "\n" && t.W == 0 || math.Abs(t.FontSize) < 0.01 {
+			continue
+		}
+		b := newBox(t).rect()
+		x, y := (b.Min.X+b.Max.X)/2, (b.Min.Y+b.Max.Y)/2
+		for _, r := range rects {
+			if x >= r.Min.X && x <= r.Max.X && y >= r.Min.Y && y <= r.Max.Y {
+				in = append(in, t)
+				break
+			}
+		}
+	}
+	var lines []string
+	for _, b := range layout(in) {
+		for _, l := range b.Lines {
+			lines = append(lines, l.String())
+		}
+	}
+	return strings.Join(lines, " ")
+}
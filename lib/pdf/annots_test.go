@@ -0,0 +1,81 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCoveredText(t *testing.T) {
+	var text []Text
+	text = show(text, 100, 700, 10, "The quick brown fox")
+	text = show(text, 100, 688, 10, "jumps over the dog")
+	// a highlight from "brown" to "jumps", written in the order of the corners of the QuadPoints
+	quads := quadRects(Value{data: array{
+		154.0, 708.0, 206.0, 708.0, 154.0, 698.0, 206.0, 698.0,
+		100.0, 696.0, 130.0, 696.0, 100.0, 686.0, 130.0, 686.0,
+	}})
+	if got := coveredText(text, quads); got != "brown fox jumps" {
+		t.Errorf("got %q", got)
+	}
+}
+
+// a page tree whose nodes have no Type, as some writers make them
+func TestAnnotationPages(t *testing.T) {
+	data := writePDF(0,
+		"<< /Type /Catalog /Pages 2 0 R /Names << /Dests << /Names [(second) [5 0 R /Fit]] >> >> >>",
+		"<< /Kids [3 0 R 6 0 R] /Count 3 >>",
+		"<< /Parent 2 0 R /Kids [4 0 R 5 0 R] /Count 2 >>",
+		"<< /Parent 3 0 R /MediaBox [0 0 612 792] >>",
+		"<< /Parent 3 0 R /MediaBox [0 0 612 792] /Annots [<< /Subtype /Text /Rect [0 0 10 10] /Contents (note) >>] >>",
+		"<< /Parent 2 0 R /MediaBox [0 0 612 792] /Annots [7 0 R 8 0 R] >>",
+		"<< /Type /Annot /Subtype /Link /Rect [0 0 10 10] /Dest [4 0 R /Fit] >>",
+		"<< /Type /Annot /Subtype /Link /Rect [0 0 10 10] /A << /S /GoTo /D (second) >> >>",
+	)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := r.NumPage(); n != 3 {
+		t.Fatalf("%d pages", n)
+	}
+	for i := 1; i <= 3; i++ {
+		if n := r.pageNumber(r.Page(i).V); n != i {
+			t.Errorf("page %d numbered %d", i, n)
+		}
+	}
+	annots := r.Annotations()
+	var got []string
+	for _, a := range annots {
+		got = append(got, fmt.Sprintf("%s on %d to %d", a.Subtype, a.Page, a.Dest))
+	}
+	if want := "Text on 2 to 0|Link on 3 to 1|Link on 3 to 2"; strings.Join(got, "|") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, "|"), want)
+	}
+	if a, err := r.Page(2).Annotations(); err != nil || len(a) != 1 || a[0].Page != 2 || a[0].Contents != "note" {
+		t.Errorf("page 2: %+v, %v", a, err)
+	}
+	if a, err := r.Page(4).Annotations(); err != nil || len(a) != 0 {
+		t.Errorf("page 4: %+v, %v", a, err)
+	}
+}
+
+func TestPageNumbers(t *testing.T) {
+	// a direct Pages node, and the root listed as its own kid
+	data := writePDF(0,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [<< /Type /Pages /Kids [3 0 R] /Count 1 >> 4 0 R 2 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+	)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 2; i++ {
+		if n := r.pageNumber(r.Page(i).V); n != i {
+			t.Errorf("page %d numbered %d", i, n)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package pdf
+
+import (
+	"archive/zip"
+	"bytes"
+	_ "embed"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Composite fonts and CJK text: the CMaps predefined by PDF 32000-1:2008, §9.7.5.2, which map the codes
+// of legacy Chinese, Japanese and Korean encodings to CIDs, and the CID-to-Unicode tables of the Adobe
+// character collections (Adobe-GB1, Adobe-CNS1, Adobe-Japan1, Adobe-Korea1 and Adobe-KR).
+
+// cmaps.zip holds the predefined CMaps of https://github.com/adobe-type-tools/cmap-resources (BSD
+// license, see LICENSE.txt in the archive) but those of the Unicode encodings (Uni*), which are
+// built from the CID-to-Unicode tables instead
+//
+//go:embed cmaps.zip
+var cmapsZip []byte
+
+var predefined struct {
+	sync.Mutex
+	files   map[string]*zip.File
+	cmaps   map[string]*cmap
+	inverse map[string]map[string]int // text to CID of the character collections
+}
+
+// predefinedCMap returns a predefined CMap, or the CID-to-Unicode CMap of a character collection
+// ("Adobe-Japan1-UCS2"), or nil if it is unknown
+func predefinedCMap(name string) *cmap {
+	predefined.Lock()
+	if predefined.cmaps == nil {
+		predefined.cmaps = map[string]*cmap{}
+		predefined.files = map[string]*zip.File{}
+		if z, err := zip.NewReader(bytes.NewReader(cmapsZip), int64(len(cmapsZip))); err == nil {
+			for _, f := range z.File {
+				predefined.files[f.Name] = f
+			}
+		}
+	}
+	m, ok := predefined.cmaps[name]
+	f := predefined.files[name]
+	predefined.Unlock()
+	if ok {
+		return m
+	}
+
+	// parsed outside of the lock, for the usecmap operator loads the CMap extended
+	switch {
+	case name == "Identity-H" || name == "Identity-V":
+		m = &cmap{space: [4][]byteRange{1: {{"\x00\x00", "\xff\xff"}}}, cidrange: []cidrange{{"\x00\x00", "\xff\xff", 0}}}
+		if name == "Identity-V" {
+			m.wmode = 1
+		}
+	case strings.HasPrefix(name, "Uni"):
+		m = unicodeCMap(name)
+	case f != nil:
+		if rc, err := f.Open(); err == nil {
+			m = parseCmap(rc)
+			rc.Close()
+		}
+	}
+	predefined.Lock()
+	predefined.cmaps[name] = m
+	predefined.Unlock()
+	return m
+}
+
+// collections of the Unicode CMaps, by prefix of their name
+var unicodeCollections = []struct{ prefix, ordering string }{
+	{"UniGB-", "Adobe-GB1"},
+	{"UniCNS-", "Adobe-CNS1"},
+	{"UniJIS", "Adobe-Japan1"}, // UniJIS-, UniJIS2004-, UniJISPro-, UniJISX0213-...
+	{"UniKS-", "Adobe-Korea1"},
+	{"UniAKR-", "Adobe-KR"},
+}
+
+// codespace ranges of the Unicode encoding forms
+var formSpaces = map[string][4][]byteRange{
+	"UCS2":  {1: {{"\x00\x00", "\xff\xff"}}},
+	"UTF16": {1: {{"\x00\x00", "\xd7\xff"}, {"\xe0\x00", "\xff\xff"}}, 3: {{"\xd8\x00\xdc\x00", "\xdb\xff\xdf\xff"}}},
+	"UTF8":  {0: {{"\x00", "\x7f"}}, 1: {{"\xc2\x80", "\xdf\xbf"}}, 2: {{"\xe0\x80\x80", "\xef\xbf\xbf"}}, 3: {{"\xf0\x80\x80\x80", "\xf4\xbf\xbf\xbf"}}},
+	"UTF32": {3: {{"\x00\x00\x00\x00", "\x00\x10\xff\xff"}}},
+}
+
+// unicodeCMap builds a CMap of a Unicode encoding, such as UniJIS-UTF16-H: its codes are the text, and
+// their CIDs are those the CID-to-Unicode table of the collection gives for it (vertical variants of
+// glyphs left aside)
+func unicodeCMap(name string) *cmap {
+	parts := strings.Split(name, "-")
+	if len(parts) < 3 || parts[len(parts)-1] != "H" && parts[len(parts)-1] != "V" {
+		return nil
+	}
+	m := &cmap{form: parts[1], space: formSpaces[parts[1]]}
+	if parts[len(parts)-1] == "V" {
+		m.wmode = 1
+	}
+	for _, c := range unicodeCollections {
+		if strings.HasPrefix(name, c.prefix) {
+			m.ordering = c.ordering
+		}
+	}
+	if m.ordering == "" || !m.hasSpace() {
+		return nil
+	}
+	return m
+}
+
+// decodeForm decodes a code of a Unicode encoding form
+func decodeForm(form, code string) (string, bool) {
+	switch form {
+	case "UCS2", "UTF16":
+		if len(code)%2 == 0 {
+			return utf16Decode(code), true
+		}
+	case "UTF8":
+		if utf8.ValidString(code) {
+			return code, true
+		}
+	case "UTF32":
+		if r := rune(codeValue(code)); len(code) == 4 && utf8.ValidRune(r) {
+			return string(r), true
+		}
+	}
+	return "", false
+}
+
+// collectionCMap returns the CID-to-Unicode CMap of a character collection, as "Adobe-Japan1", whose
+// codes are the CIDs on two bytes
+func collectionCMap(ordering string) *cmap {
+	if ordering == "" || strings.HasSuffix(ordering, "-Identity") {
+		return nil
+	}
+	return predefinedCMap(ordering + "-UCS2")
+}
+
+// collectionName returns the character collection given by a CIDSystemInfo dictionary
+func collectionName(info Value) string {
+	registry, ordering := info.Key("Registry").Text(), info.Key("Ordering").Text()
+	if registry == "" || ordering == "" {
+		return ""
+	}
+	return registry + "-" + ordering
+}
+
+// cidCode returns the code of a CID in a CID-to-Unicode CMap
+func cidCode(cid int) string {
+	return string([]byte{byte(cid >> 8), byte(cid)})
+}
+
+// main legacy encodings of the character collections, whose CIDs are those of the Unicode CMaps
+var collectionEncodings = map[string]string{
+	"Adobe-GB1":    "GBK-EUC-H",
+	"Adobe-CNS1":   "ETen-B5-H",
+	"Adobe-Japan1": "90ms-RKSJ-H",
+	"Adobe-Korea1": "KSCms-UHC-H",
+}
+
+// collectionCID returns the CID of a text in a character collection: the one of the main legacy
+// encoding of the collection, else the first one of the CID-to-Unicode table
+func collectionCID(ordering, text string) (int, bool) {
+	m := collectionCMap(ordering)
+	if m == nil {
+		return 0, false
+	}
+	predefined.Lock()
+	inverse, ok := predefined.inverse[ordering]
+	predefined.Unlock()
+	if !ok {
+		inverse = map[string]int{}
+		if enc := predefinedCMap(collectionEncodings[ordering]); enc != nil {
+			for _, r := range enc.cidrange {
+				lo, hi := codeValue(r.lo), codeValue(r.hi)
+				for cid := r.cid; cid <= r.cid+hi-lo; cid++ {
+					if s, ok := m.unicode(cidCode(cid)); ok && s != string(noRune) {
+						if _, dup := inverse[s]; !dup {
+							inverse[s] = cid
+						}
+					}
+				}
+			}
+		}
+		fallback := map[string]int{}
+		last := 0
+		for code := range m.bfchar {
+			last = max(last, codeValue(code))
+		}
+		for _, r := range m.bfrange {
+			last = max(last, codeValue(r.hi))
+		}
+		for cid := last; cid > 0; cid-- {
+			if s, ok := m.unicode(cidCode(cid)); ok && s != string(noRune) {
+				fallback[s] = cid
+			}
+		}
+		for s, cid := range fallback {
+			if _, ok := inverse[s]; !ok {
+				inverse[s] = cid
+			}
+		}
+		predefined.Lock()
+		if predefined.inverse == nil {
+			predefined.inverse = map[string]map[string]int{}
+		}
+		predefined.inverse[ordering] = inverse
+		predefined.Unlock()
+	}
+	cid, ok := inverse[text]
+	return cid, ok
+}
+
+// cidEncoder decodes the strings shown with a composite (Type0) font: the encoding CMap splits them into
+// codes and gives their CIDs, the text of which comes from the ToUnicode CMap of the font, else from
+// the code itself for Unicode encodings, else from the CID-to-Unicode table of the character collection,
+// else from the glyphs of the embedded font program
+type cidEncoder struct {
+	encoding   *cmap
+	toUnicode  *cmap
+	collection *cmap
+	descendant Value
+	program    *fontProgram
+	cidToGID   []byte    // CIDToGIDMap stream of a TrueType CIDFont
+	read       sync.Once // reads the font program and the CIDToGIDMap
+}
+
+func (f Font) cidEncoding() TextEncoding {
+	e := &cidEncoder{descendant: f.V.Key("DescendantFonts").Index(0)}
+	switch enc := f.V.Key("Encoding"); enc.Kind() {
+	case Name:
+		e.encoding = predefinedCMap(enc.Name())
+	case Stream:
+		e.encoding = readCmap(enc)
+	}
+	if e.encoding == nil {
+		if DebugOn {
+			println("unknown encoding", f.V.Key("Encoding").String())
+		}
+		e.encoding = predefinedCMap("Identity-H")
+	}
+	if toUnicode := f.V.Key("ToUnicode"); toUnicode.Kind() == Stream {
+		e.toUnicode = readCmap(toUnicode)
+	}
+	e.collection = collectionCMap(collectionName(e.descendant.Key("CIDSystemInfo")))
+	if e.collection == nil {
+		e.collection = collectionCMap(e.encoding.ordering)
+	}
+	return e
+}
+
+func (e *cidEncoder) Decode(raw string) (text string) {
+	var r []rune
+	for len(raw) > 0 {
+		code, _ := e.encoding.nextCode(raw)
+		raw = raw[len(code):]
+		r = append(r, []rune(e.text(code))...)
+	}
+	return string(r)
+}
+
+func (e *cidEncoder) text(code string) string {
+	if e.toUnicode != nil {
+		if s, ok := e.toUnicode.unicode(code); ok {
+			return s
+		}
+	}
+	if s, ok := e.encoding.unicode(code); ok {
+		return s
+	}
+	if cid, ok := e.encoding.cid(code); ok {
+		if e.collection != nil {
+			if s, ok := e.collection.unicode(cidCode(cid)); ok {
+				return s
+			}
+		}
+		if s := e.glyphText(cid); s != "" {
+			return s
+		}
+	}
+	return string(noRune)
+}
+
+// glyphText returns the text of the glyph of a CID in the embedded font program
+func (e *cidEncoder) glyphText(cid int) string {
+	e.read.Do(func() {
+		if e.descendant.r != nil {
+			e.program = e.descendant.r.fontProgram(e.descendant.Key("FontDescriptor"))
+		}
+		if m := e.descendant.Key("CIDToGIDMap"); m.Kind() == Stream {
+			e.cidToGID, _ = io.ReadAll(m.Reader())
+		}
+	})
+	if e.program == nil {
+		return ""
+	}
+	gid := e.program.cidGlyph(cid)
+	if e.cidToGID != nil {
+		gid = int(u16(e.cidToGID, 2*cid))
+	}
+	return e.program.glyphText(gid)
+}
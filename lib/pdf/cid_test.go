@@ -0,0 +1,36 @@
+package pdf
+
+import "testing"
+
+func TestPredefinedCMaps(t *testing.T) {
+	for _, c := range []struct {
+		cmap, raw, want string
+	}{
+		{"90ms-RKSJ-H", "A\x82\xa0\x93\xfa", "Aあ日"},
+		{"UniJIS-UCS2-H", "\x30\x42\x65\xe5", "あ日"},
+		{"GBK-EUC-H", "\xd6\xd0\xce\xc4", "中文"},
+		{"B5pc-H", "\xa4\xa4\xa4\xe5", "中文"},
+		{"KSCms-UHC-H", "\xb0\xa1\xc7\xd1", "가한"},
+		{"UniKS-UTF16-H", "\xac\x00", "가"},
+	} {
+		m := predefinedCMap(c.cmap)
+		if m == nil {
+			t.Errorf("%s: not found", c.cmap)
+			continue
+		}
+		e := &cidEncoder{encoding: m, collection: collectionCMap(m.ordering)}
+		if got := e.Decode(c.raw); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.cmap, got, c.want)
+		}
+	}
+	if m := predefinedCMap("90ms-RKSJ-V"); m == nil || m.wmode != 1 || m.usecmap == nil {
+		t.Errorf("90ms-RKSJ-V: vertical CMap using 90ms-RKSJ-H expected")
+	}
+}
+
+func TestCodespaceRange(t *testing.T) {
+	r := byteRange{"\x81\x40", "\x9f\xfc"}
+	if !r.contains("\x82\xa0") || r.contains("\x82\xfd") || r.contains("\xa0\x40") {
+		t.Errorf("codespace ranges are rectangles")
+	}
+}
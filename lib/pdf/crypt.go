@@ -0,0 +1,206 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Passwords which open an encrypted file, see Reader.Password
+const (
+	PasswordUser  = "user"
+	PasswordOwner = "owner"
+)
+
+// Encryption returns the cipher of an encrypted file: "RC4", "AESV2" (AES-128) or "AESV3" (AES-256),
+// or "" if the file is not encrypted
+func (r *Reader) Encryption() string {
+	switch {
+	case r.key == nil:
+		return ""
+	case len(r.key) == 32:
+		return "AESV3"
+	case r.useAES:
+		return "AESV2"
+	}
+	return "RC4"
+}
+
+// initEncryptAES256 checks the password against the owner then the user hash of a V=5 security handler
+// (revision 5 of the Adobe extension level 3, revision 6 of PDF 2.0) and decrypts the file key.
+// See ISO 32000-2:2020, §7.6.4.3.3 and §7.6.4.4.
+func (r *Reader) initEncryptAES256(encrypt dict, password string) error {
+	R, _ := encrypt["R"].(int64)
+	if R != 5 && R != 6 {
+		return fmt.Errorf("unsupported PDF: encryption revision R=%d", R)
+	}
+	if !okayCryptFilters(encrypt, "AESV3", 32) {
+		return fmt.Errorf("unsupported PDF: encryption version V=5; %v", objfmt(encrypt))
+	}
+	O, _ := encrypt["O"].(string)
+	U, _ := encrypt["U"].(string)
+	OE, _ := encrypt["OE"].(string)
+	UE, _ := encrypt["UE"].(string)
+	if len(O) < 48 || len(U) < 48 || len(OE) != 32 || len(UE) != 32 {
+		return fmt.Errorf("malformed PDF: missing O=, U=, OE= or UE= encryption parameters")
+	}
+	pw := saslPrep(password)
+	if len(pw) > 127 {
+		pw = pw[:127]
+	}
+	hash := func(salt, udata []byte) []byte {
+		return hashR6(pw, salt, udata, R == 6)
+	}
+
+	var key []byte
+	var err error
+	switch {
+	case subtle.ConstantTimeCompare(hash([]byte(O[32:40]), []byte(U[:48])), []byte(O[:32])) == 1:
+		key, err = decryptFileKey(hash([]byte(O[40:48]), []byte(U[:48])), OE)
+		r.password = PasswordOwner
+	case subtle.ConstantTimeCompare(hash([]byte(U[32:40]), nil), []byte(U[:32])) == 1:
+		key, err = decryptFileKey(hash([]byte(U[40:48]), nil), UE)
+		r.password = PasswordUser
+	default:
+		return ErrInvalidPassword
+	}
+	if err != nil {
+		r.password = ""
+		return err
+	}
+
+	// Perms holds P encrypted with the file key, which tells whether the permissions were tampered with
+	perms, _ := encrypt["Perms"].(string)
+	p, _ := encrypt["P"].(int64)
+	if R == 6 || perms != "" {
+		block, _ := aes.NewCipher(key)
+		if len(perms) < 16 {
+			r.password = ""
+			return fmt.Errorf("malformed PDF: missing Perms= encryption parameter")
+		}
+		dec := make([]byte, 16)
+		block.Decrypt(dec, []byte(perms[:16]))
+		if string(dec[9:12]) != "adb" || binary.LittleEndian.Uint32(dec) != uint32(p) {
+			r.password = ""
+			return fmt.Errorf("malformed PDF: P= does not match the Perms= encryption parameter")
+		}
+	}
+
+	r.key = key
+	r.useAES = true
+	return nil
+}
+
+// decryptFileKey decrypts OE or UE with the intermediate key derived from the password (AES-256, no IV)
+func decryptFileKey(intermediate []byte, encrypted string) ([]byte, error) {
+	block, err := aes.NewCipher(intermediate)
+	if err != nil {
+		return nil, fmt.Errorf("malformed PDF: invalid AES key: %v", err)
+	}
+	key := []byte(encrypted)
+	cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(key, key)
+	return key, nil
+}
+
+// hashR6 is the SHA-256 hash of revision 5, or the iterated hash of revision 6 (ISO 32000-2:2020,
+// algorithm 2.B) in which each round encrypts the password and previous hash with AES-128 and picks
+// SHA-256, SHA-384 or SHA-512 from the result
+func hashR6(pw, salt, udata []byte, r6 bool) []byte {
+	h := sha256.New()
+	h.Write(pw)
+	h.Write(salt)
+	h.Write(udata)
+	k := h.Sum(nil)
+	if !r6 {
+		return k
+	}
+	var e []byte
+	for round := 0; round < 64 || int(e[len(e)-1]) > round-32; round++ {
+		seq := make([]byte, 0, len(pw)+len(k)+len(udata))
+		seq = append(append(append(seq, pw...), k...), udata...)
+		k1 := bytes.Repeat(seq, 64)
+		block, _ := aes.NewCipher(k[:16])
+		e = make([]byte, len(k1))
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+		// the first 16 bytes of e taken as a big-endian number modulo 3 equal the sum of the bytes modulo 3
+		sum := 0
+		for _, b := range e[:16] {
+			sum += int(b)
+		}
+		switch sum % 3 {
+		case 0:
+			s := sha256.Sum256(e)
+			k = s[:]
+		case 1:
+			s := sha512.Sum384(e)
+			k = s[:]
+		case 2:
+			s := sha512.Sum512(e)
+			k = s[:]
+		}
+	}
+	return k[:32]
+}
+
+// saslPrep prepares a password with the SASLprep profile of stringprep (RFC 4013): non-ASCII spaces become
+// spaces, characters commonly mapped to nothing are removed and the result is normalized with NFKC.
+// Passwords holding prohibited characters are used as they are.
+func saslPrep(password string) []byte {
+	mapped := strings.Map(func(c rune) rune {
+		switch {
+		case c == 0x00AD || c == 0x034F || c == 0x1806 || c == 0x180B || c == 0x180C || c == 0x180D ||
+			c == 0x200B || c == 0x200C || c == 0x200D || c == 0x2060 || c == 0xFEFF || c >= 0xFE00 && c <= 0xFE0F:
+			return -1
+		case c != ' ' && c > 0x7F && unicode.Is(unicode.Zs, c):
+			return ' '
+		}
+		return c
+	}, password)
+	prepared := norm.NFKC.String(mapped)
+	for _, c := range prepared {
+		if unicode.IsControl(c) || unicode.Is(unicode.Co, c) || unicode.Is(unicode.Cs, c) ||
+			c >= 0xFDD0 && c <= 0xFDEF || c&0xFFFE == 0xFFFE || c >= 0x2FF0 && c <= 0x2FFB ||
+			c >= 0x206A && c <= 0x206F || c == 0x200E || c == 0x200F || c == 0x202A || c == 0x202B ||
+			c == 0x202C || c == 0x202D || c == 0x202E || c >= 0xE0001 && c <= 0xE007F || c == 0xFFFD {
+			return []byte(password)
+		}
+	}
+	return []byte(prepared)
+}
+
+// okayCryptFilters checks that the streams and strings use the same crypt filter, with the given method
+// and key length in bytes (some writers give it in bits)
+func okayCryptFilters(encrypt dict, method string, length int64) bool {
+	cf, ok := encrypt["CF"].(dict)
+	if !ok {
+		return false
+	}
+	stmf, ok := encrypt["StmF"].(name)
+	if !ok {
+		return false
+	}
+	strf, ok := encrypt["StrF"].(name)
+	if !ok {
+		return false
+	}
+	if stmf != strf {
+		return false
+	}
+	cfparam, _ := cf[stmf].(dict)
+	if cfparam["AuthEvent"] != nil && cfparam["AuthEvent"] != name("DocOpen") {
+		return false
+	}
+	if cfparam["Length"] != nil && cfparam["Length"] != length && cfparam["Length"] != 8*length {
+		return false
+	}
+	return cfparam["CFM"] == name(method)
+}
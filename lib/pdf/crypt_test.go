@@ -0,0 +1,75 @@
+package pdf
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// examples of RFC 4013 section 3
+func TestSASLPrep(t *testing.T) {
+	for _, c := range []struct{ in, want string }{
+		{"I\u00adX", "IX"},
+		{"user", "user"},
+		{"USER", "USER"},
+		{"\u00aa", "a"},
+		{"\u2168", "IX"},
+		{"a\u00a0b", "a b"}, // non-ASCII space
+	} {
+		if got := string(saslPrep(c.in)); got != c.want {
+			t.Errorf("saslPrep(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+// The files of testdata are encrypted with the user password "user" and the owner password "owner", by
+// pdfcpu but aes-256-r6.pdf, written by a script with the hash of ISO 32000-2:2020, algorithm 2.B.
+func TestOpenEncrypted(t *testing.T) {
+	for _, c := range []struct {
+		file       string
+		encryption string
+	}{
+		{"rc4-40.pdf", "RC4"},  // R=2, a 5 byte file key extended to 10 bytes per object
+		{"rc4-128.pdf", "RC4"}, // R=4
+		{"aes-128.pdf", "AESV2"},
+		{"aes-256-r5.pdf", "AESV3"},
+		{"aes-256-r6.pdf", "AESV3"},
+	} {
+		data, err := os.ReadFile("testdata/" + c.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pw := range []string{"user", "owner"} {
+			tried := 0
+			r, err := NewReaderEncrypted(bytes.NewReader(data), int64(len(data)), func() string {
+				tried++
+				if tried > 1 {
+					return ""
+				}
+				return pw
+			})
+			if err != nil {
+				t.Errorf("%s with %s password: %v", c.file, pw, err)
+				continue
+			}
+			if r.Password() != pw || r.Encryption() != c.encryption {
+				t.Errorf("%s with %s password: opened with %q, %q", c.file, pw, r.Password(), r.Encryption())
+			}
+			if title := r.Trailer().Key("Info").Key("Title").Text(); title != "Secret title" {
+				t.Errorf("%s: title %q", c.file, title)
+			}
+			var text strings.Builder
+			for _, s := range r.Page(1).Content().Text {
+				text.WriteString(s.S)
+			}
+			if text.String() != "Hello, encrypted world" {
+				t.Errorf("%s: text %q", c.file, text.String())
+			}
+		}
+		_, err = NewReaderEncrypted(bytes.NewReader(data), int64(len(data)), func() string { return "" })
+		if err != ErrInvalidPassword {
+			t.Errorf("%s without password: %v", c.file, err)
+		}
+	}
+}
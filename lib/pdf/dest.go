@@ -0,0 +1,102 @@
+package pdf
+
+// pageNumber returns the number of a page of the document, from its dictionary, or 0 if it is not one
+func (r *Reader) pageNumber(page Value) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pageNums == nil {
+		r.pageNums = map[objptr]int{}
+		func() {
+			defer func() {
+				recover()
+			}()
+			// the pages are numbered as Page finds them: those of a Pages node follow the Count of its
+			// previous siblings
+			seen := map[objptr]bool{}
+			var walk func(node Value, ref bool, first, depth int)
+			walk = func(node Value, ref bool, first, depth int) {
+				if depth > 64 || ref && seen[node.ptr] {
+					return
+				}
+				if ref { // direct nodes cannot make a cycle
+					seen[node.ptr] = true
+				}
+				kids := node.Key("Kids")
+				n := first
+				for i := 0; i < kids.Len(); i++ {
+					kid := kids.Index(i)
+					switch pageNodeType(kid) {
+					case "Pages":
+						walk(kid, kids.isRef(i), n, depth+1)
+						n += int(kid.Key("Count").Int64())
+					case "Page":
+						n++
+						// direct pages share the object of their parent, and cannot be told apart
+						if _, ok := r.pageNums[kid.ptr]; !ok && kids.isRef(i) {
+							r.pageNums[kid.ptr] = n
+						}
+					}
+				}
+			}
+			if root := r.Trailer().Key("Root").Key("Pages"); pageNodeType(root) == "Pages" {
+				walk(root, true, 0, 0)
+			}
+		}()
+	}
+	if page.Kind() != Dict {
+		return 0
+	}
+	return r.pageNums[page.ptr]
+}
+
+// destPage returns the number of the page a destination (PDF 32000-1:2008, §12.3.2) leads to: an array
+// whose first element is the page, or the name of one in the Dests name tree of the catalog or in its
+// Dests dictionary (PDF 1.1), whose values may also be dictionaries with the array as D entry. It is 0
+// for the destinations out of the document.
+func (r *Reader) destPage(dest Value) int {
+	switch dest.Kind() {
+	case Name:
+		dest = r.namedDest(dest.Name())
+	case String:
+		dest = r.namedDest(dest.Text())
+	}
+	if dest.Kind() == Dict {
+		dest = dest.safeKey("D")
+	}
+	if dest.Kind() != Array {
+		return 0
+	}
+	return r.pageNumber(dest.Index(0))
+}
+
+// namedDest looks up a named destination
+func (r *Reader) namedDest(name string) Value {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.dests == nil {
+		r.dests = map[string]Value{}
+		root := r.Trailer().safeKey("Root")
+		dests := root.safeKey("Dests")
+		for _, key := range dests.Keys() {
+			r.dests[key] = dests.safeKey(key)
+		}
+		walkNameTree(root.safeKey("Names").safeKey("Dests"), func(key string, v Value) {
+			r.dests[key] = v
+		})
+	}
+	return r.dests[name]
+}
+
+// actionTarget returns where an action leads: the page of a GoTo action, or the URI of a URI action
+// and the file of the actions which open another one (GoToR, GoToE and Launch)
+func (r *Reader) actionTarget(a Value) (page int, uri string) {
+	switch a.safeKey("S").Name() {
+	case "GoTo":
+		return r.destPage(a.safeKey("D")), ""
+	case "URI":
+		return 0, a.safeKey("URI").Text()
+	case "GoToR", "GoToE", "Launch":
+		return 0, fileSpecName(a)
+	}
+	return 0, ""
+}
@@ -0,0 +1,94 @@
+package pdf
+
+// An EmbeddedFile is a file stored in the PDF file: an associated file (AF) of the document or of a page,
+// as PDF 2.0 uses them for the source data of a document (ZUGFeRD or Factur-X invoices for instance), or
+// an attachment of the EmbeddedFiles name tree
+type EmbeddedFile struct {
+	Name         string // UF or F entry of the file specification
+	Description  string
+	Subtype      string // MIME type of the file, if given
+	Relationship string // AFRelationship of an associated file: Source, Data, Alternative, Supplement...
+	Page         int    // number of the page the file is associated with, 0 for the document
+	V            Value  // the embedded file stream, see Value.Reader
+}
+
+// EmbeddedFiles returns the associated files of the document and of its pages and the files of the
+// EmbeddedFiles name tree, each file once. Malformed objects end the search.
+func (r *Reader) EmbeddedFiles() (files []EmbeddedFile) {
+	defer func() {
+		recover()
+	}()
+	seen := map[objptr]bool{}
+	add := func(spec Value, page int) {
+		ef := spec.safeKey("EF")
+		strm := ef.safeKey("UF")
+		if strm.Kind() != Stream {
+			strm = ef.safeKey("F")
+		}
+		if strm.Kind() != Stream {
+			return
+		}
+		if ptr := strm.data.(stream).ptr; ptr != (objptr{}) {
+			if seen[ptr] {
+				return
+			}
+			seen[ptr] = true
+		}
+		files = append(files, EmbeddedFile{
+			Name:         fileSpecName(spec),
+			Description:  spec.safeKey("Desc").Text(),
+			Subtype:      strm.safeKey("Subtype").Name(),
+			Relationship: spec.safeKey("AFRelationship").Name(),
+			Page:         page,
+			V:            strm,
+		})
+	}
+	root := r.Trailer().safeKey("Root")
+	af := root.safeKey("AF")
+	for i := 0; i < af.Len(); i++ {
+		add(af.Index(i), 0)
+	}
+	for i := 1; i <= r.NumPage(); i++ {
+		af := r.safePage(i).safeKey("AF")
+		for j := 0; j < af.Len(); j++ {
+			add(af.Index(j), i)
+		}
+	}
+	walkNameTree(root.safeKey("Names").safeKey("EmbeddedFiles"), func(_ string, spec Value) {
+		add(spec, 0)
+	})
+	return files
+}
+
+// safePage is Page without panics on malformed page trees
+func (r *Reader) safePage(num int) (v Value) {
+	defer func() {
+		if recover() != nil {
+			v = Value{}
+		}
+	}()
+	return r.Page(num).V
+}
+
+// walkNameTree calls fn with the keys and values of a name tree (PDF 32000-1:2008, §7.9.6) in order
+func walkNameTree(node Value, fn func(key string, v Value)) {
+	seen := map[objptr]bool{}
+	var walk func(node Value, ref bool, depth int)
+	walk = func(node Value, ref bool, depth int) {
+		if node.Kind() != Dict || depth > 32 || ref && seen[node.ptr] {
+			return
+		}
+		if ref { // direct nodes cannot make a cycle
+			seen[node.ptr] = true
+		}
+		names := node.safeKey("Names")
+		for i := 0; i+1 < names.Len(); i += 2 {
+			fn(names.Index(i).Text(), names.Index(i+1))
+		}
+		kids := node.safeKey("Kids")
+		for i := 0; i < kids.Len(); i++ {
+			walk(kids.Index(i), kids.isRef(i), depth+1)
+		}
+	}
+	walk(node, true, 0)
+}
@@ -0,0 +1,29 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNameTreeCycles(t *testing.T) {
+	// name tree nodes listing themselves as their kids, walked once
+	ef := "<< /Length 5 >>\nstream\nhello\nendstream"
+	data := writePDF(0,
+		"<< /Type /Catalog /Pages 2 0 R /Names << /Dests 4 0 R /EmbeddedFiles 5 0 R >> >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+		"<< /Kids [4 0 R 4 0 R 4 0 R 4 0 R << /Names [(first) [3 0 R /Fit]] >>] >>",
+		"<< /Kids [5 0 R 5 0 R 5 0 R 5 0 R << /Names [(a.txt) << /Type /Filespec /F (a.txt) /EF << /F 6 0 R >> >>] >>] >>",
+		ef,
+	)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := r.destPage(Value{data: "first"}); n != 1 {
+		t.Errorf("destination to page %d", n)
+	}
+	if files := r.EmbeddedFiles(); len(files) != 1 || files[0].Name != "a.txt" {
+		t.Errorf("files %+v", files)
+	}
+}
@@ -0,0 +1,321 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Stream filters other than FlateDecode and ASCII85Decode, and the predictors of FlateDecode and
+// LZWDecode. See PDF 32000-1:2008, §7.4.
+
+// decodedReader decodes its whole input at the first Read, for the filters which are simpler to undo
+// on a buffer than on a stream
+type decodedReader struct {
+	rd     io.Reader
+	decode func([]byte) ([]byte, error)
+	out    io.Reader
+}
+
+func (d *decodedReader) Read(b []byte) (int, error) {
+	if d.out == nil {
+		in, err := io.ReadAll(d.rd)
+		if err != nil {
+			d.out = &errorReadCloser{err}
+		} else if out, err := d.decode(in); err != nil {
+			d.out = io.MultiReader(bytes.NewReader(out), &errorReadCloser{err})
+		} else {
+			d.out = bytes.NewReader(out)
+		}
+	}
+	return d.out.Read(b)
+}
+
+// decodeLZW undoes LZWDecode: variable length codes of 9 to 12 bits, high-order bit first, whose length
+// grows one code early when earlyChange is 1
+func decodeLZW(data []byte, earlyChange int) ([]byte, error) {
+	const clearTable, eod = 256, 257
+	table := make([][]byte, 258, 4096)
+	for i := 0; i < 256; i++ {
+		table[i] = []byte{byte(i)}
+	}
+	width := 9
+	var out, prev []byte
+	var bits uint32
+	nbits := 0
+	for pos := 0; ; {
+		for nbits < width {
+			if pos >= len(data) {
+				return out, nil
+			}
+			bits = bits<<8 | uint32(data[pos])
+			pos++
+			nbits += 8
+		}
+		code := int(bits>>(nbits-width)) & (1<<width - 1)
+		nbits -= width
+		switch code {
+		case clearTable:
+			table = table[:258]
+			width = 9
+			prev = nil
+			continue
+		case eod:
+			return out, nil
+		}
+		var entry []byte
+		switch {
+		case code < len(table) && table[code] != nil:
+			entry = table[code]
+		case code == len(table) && prev != nil:
+			entry = append(prev[:len(prev):len(prev)], prev[0])
+		default:
+			return out, fmt.Errorf("malformed LZW code %d", code)
+		}
+		out = append(out, entry...)
+		if prev != nil && len(table) < 4096 {
+			table = append(table, append(prev[:len(prev):len(prev)], entry[0]))
+		}
+		prev = entry
+		if len(table)+earlyChange >= 1<<width && width < 12 {
+			width++
+		}
+	}
+}
+
+// decodeASCIIHex undoes ASCIIHexDecode: pairs of hexadecimal digits ended by '>', white space being
+// ignored and a final odd digit followed by 0
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data)/2)
+	var hi byte
+	odd := false
+	for _, c := range data {
+		var v byte
+		switch {
+		case isSpace(c):
+			continue
+		case c == '>':
+			if odd {
+				out = append(out, hi<<4)
+			}
+			return out, nil
+		case '0' <= c && c <= '9':
+			v = c - '0'
+		case 'a' <= c && c <= 'f':
+			v = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			return out, fmt.Errorf("malformed ASCIIHex character %#q", rune(c))
+		}
+		if odd {
+			out = append(out, hi<<4|v)
+		} else {
+			hi = v
+		}
+		odd = !odd
+	}
+	if odd {
+		out = append(out, hi<<4)
+	}
+	return out, nil
+}
+
+// decodeRunLength undoes RunLengthDecode: a length byte n followed by n+1 bytes to copy (n < 128), or by
+// one byte to repeat 257-n times (n > 128), 128 marking the end of data
+func decodeRunLength(data []byte) ([]byte, error) {
+	var out []byte
+	for i := 0; i < len(data); {
+		n := int(data[i])
+		i++
+		switch {
+		case n == 128:
+			return out, nil
+		case n < 128:
+			if i+n+1 > len(data) {
+				return append(out, data[i:]...), errors.New("truncated RunLength run")
+			}
+			out = append(out, data[i:i+n+1]...)
+			i += n + 1
+		default:
+			if i >= len(data) {
+				return out, errors.New("truncated RunLength run")
+			}
+			out = append(out, bytes.Repeat(data[i:i+1], 257-n)...)
+			i++
+		}
+	}
+	return out, nil
+}
+
+// applyPredictor undoes the predictor given in the decode parameters of FlateDecode or LZWDecode
+func applyPredictor(rd io.Reader, param Value) io.Reader {
+	pred := param.Key("Predictor").Int64()
+	if pred <= 1 {
+		return rd
+	}
+	colors, bpc, columns := param.Key("Colors").Int64(), param.Key("BitsPerComponent").Int64(), param.Key("Columns").Int64()
+	if colors <= 0 {
+		colors = 1
+	}
+	if bpc <= 0 {
+		bpc = 8
+	}
+	if columns <= 0 {
+		columns = 1
+	}
+	if pred != 2 && (pred < 10 || pred > 15) || bpc > 16 || colors*bpc*columns > 1<<24 {
+		return &errorReadCloser{fmt.Errorf("unsupported predictor %d (colors %d, bits %d, columns %d)", pred, colors, bpc, columns)}
+	}
+	row := int((colors*bpc*columns + 7) / 8)
+	p := &predictorReader{
+		r:       rd,
+		png:     pred >= 10,
+		colors:  int(colors),
+		bpc:     int(bpc),
+		samples: int(colors * columns),
+		bpp:     int((colors*bpc + 7) / 8),
+		prev:    make([]byte, row),
+		cur:     make([]byte, row),
+	}
+	if p.png {
+		p.tag = make([]byte, 1)
+	}
+	return p
+}
+
+// predictorReader undoes the PNG prediction (predictors 10 to 15, the algorithm being given by the tag
+// byte of each row) or the TIFF horizontal differencing (predictor 2) of the rows of an image
+type predictorReader struct {
+	r       io.Reader
+	png     bool
+	colors  int
+	bpc     int
+	samples int // samples per row
+	bpp     int // bytes per pixel, at least 1
+	tag     []byte
+	prev    []byte
+	cur     []byte
+	pend    []byte
+}
+
+func (p *predictorReader) Read(b []byte) (int, error) {
+	for len(p.pend) == 0 {
+		if p.png {
+			if _, err := io.ReadFull(p.r, p.tag); err != nil {
+				if err == io.ErrUnexpectedEOF {
+					err = io.EOF
+				}
+				return 0, err
+			}
+		}
+		n, err := io.ReadFull(p.r, p.cur)
+		if err != nil && (err != io.ErrUnexpectedEOF || n == 0) {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		if p.png {
+			if err := p.unpredictPNG(p.tag[0]); err != nil {
+				return 0, err
+			}
+		} else {
+			p.unpredictTIFF()
+		}
+		p.pend = p.cur[:n]
+		p.prev, p.cur = p.cur, p.prev
+	}
+	n := copy(b, p.pend)
+	p.pend = p.pend[n:]
+	return n, nil
+}
+
+func (p *predictorReader) unpredictPNG(tag byte) error {
+	cur, prev, bpp := p.cur, p.prev, p.bpp
+	switch tag {
+	case 0: // None
+	case 1: // Sub
+		for i := bpp; i < len(cur); i++ {
+			cur[i] += cur[i-bpp]
+		}
+	case 2: // Up
+		for i := range cur {
+			cur[i] += prev[i]
+		}
+	case 3: // Average
+		for i := range cur {
+			left := 0
+			if i >= bpp {
+				left = int(cur[i-bpp])
+			}
+			cur[i] += byte((left + int(prev[i])) / 2)
+		}
+	case 4: // Paeth
+		for i := range cur {
+			var a, c int
+			if i >= bpp {
+				a, c = int(cur[i-bpp]), int(prev[i-bpp])
+			}
+			cur[i] += byte(paeth(a, int(prev[i]), c))
+		}
+	default:
+		return fmt.Errorf("malformed PNG prediction: row tag %d", tag)
+	}
+	return nil
+}
+
+func paeth(a, b, c int) int {
+	p := a + b - c
+	pa, pb, pc := abs(p-a), abs(p-b), abs(p-c)
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// unpredictTIFF adds to each sample the sample of the same component in the previous pixel
+func (p *predictorReader) unpredictTIFF() {
+	row, bpc := p.cur, p.bpc
+	if bpc == 8 {
+		for i := p.colors; i < len(row); i++ {
+			row[i] += row[i-p.colors]
+		}
+		return
+	}
+	mask := uint32(1)<<bpc - 1
+	for i := p.colors; i < p.samples; i++ {
+		setSample(row, i, bpc, (sample(row, i, bpc)+sample(row, i-p.colors, bpc))&mask)
+	}
+}
+
+// sample returns the i-th sample of bpc bits of a row, high-order bit first
+func sample(row []byte, i, bpc int) uint32 {
+	var v uint32
+	for bit := i * bpc; bit < (i+1)*bpc; bit++ {
+		v = v<<1 | uint32(row[bit/8]>>(7-bit%8)&1)
+	}
+	return v
+}
+
+func setSample(row []byte, i, bpc int, v uint32) {
+	for bit := (i+1)*bpc - 1; bit >= i*bpc; bit-- {
+		if v&1 != 0 {
+			row[bit/8] |= 1 << (7 - bit%8)
+		} else {
+			row[bit/8] &^= 1 << (7 - bit%8)
+		}
+		v >>= 1
+	}
+}
@@ -0,0 +1,46 @@
+package pdf
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// example of PDF 32000-1:2008, §7.4.4.2
+func TestDecodeLZW(t *testing.T) {
+	out, err := decodeLZW([]byte{0x80, 0x0B, 0x60, 0x50, 0x22, 0x0C, 0x0C, 0x85, 0x01}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "-----A---B"; string(out) != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+}
+
+func TestDecodeASCIIHexRunLength(t *testing.T) {
+	out, err := decodeASCIIHex([]byte("48 65 6c6C\n6F 7>"))
+	if err != nil || string(out) != "Hello\x70" {
+		t.Fatalf("ASCIIHex: got %q, %v", out, err)
+	}
+	out, err = decodeRunLength([]byte{2, 'a', 'b', 'c', 254, 'x', 128, 'z'})
+	if err != nil || string(out) != "abcxxx" {
+		t.Fatalf("RunLength: got %q, %v", out, err)
+	}
+}
+
+func TestPredictors(t *testing.T) {
+	// two rows of 3 RGB pixels: Sub then Up with PNG, horizontal differencing with TIFF
+	png := []byte{1, 1, 2, 3, 1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+	tiff := []byte{1, 2, 3, 1, 1, 1, 1, 1, 1, 2, 3, 4, 1, 1, 1, 1, 1, 1}
+	want := []byte{1, 2, 3, 2, 3, 4, 3, 4, 5, 2, 3, 4, 3, 4, 5, 4, 5, 6}
+	for _, c := range []struct {
+		predictor int64
+		data      []byte
+	}{{12, png}, {15, png}, {2, tiff}} {
+		param := Value{data: dict{"Predictor": c.predictor, "Colors": int64(3), "Columns": int64(3)}}
+		got, err := io.ReadAll(applyPredictor(bytes.NewReader(c.data), param))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("predictor %d: got %v, %v", c.predictor, got, err)
+		}
+	}
+}
@@ -0,0 +1,556 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Embedded font programs, read for the text of the fonts whose encoding and glyph names do not give it:
+// the cmap and post tables of TrueType and OpenType fonts, the charset and encoding of CFF fonts (Adobe
+// Technical Note #5176) and the encoding of Type 1 fonts.
+
+// A fontProgram holds what the text extraction uses of an embedded font program
+type fontProgram struct {
+	builtin [256]string    // text of the codes of the built-in encoding of the font
+	names   []string       // glyph names by glyph ID, from the post table or the CFF charset
+	text    map[int]string // text of glyph IDs, from the Unicode cmap subtables
+	gids    map[int]int    // glyph IDs of the CIDs of a CID-keyed CFF font
+	byName  map[string]int
+}
+
+// fontProgram returns the font program embedded in a font descriptor, read once per reader, or nil
+func (r *Reader) fontProgram(desc Value) (p *fontProgram) {
+	var strm Value
+	for _, key := range []string{"FontFile2", "FontFile3", "FontFile"} {
+		if strm = desc.safeKey(key); strm.Kind() == Stream {
+			break
+		}
+	}
+	if strm.Kind() != Stream {
+		return nil
+	}
+	ptr := strm.data.(stream).ptr
+	r.mu.Lock()
+	p, ok := r.fontPrograms[ptr]
+	r.mu.Unlock()
+	if ok {
+		return p
+	}
+	defer func() {
+		if recover() != nil {
+			p = nil
+		}
+		r.mu.Lock()
+		if r.fontPrograms == nil {
+			r.fontPrograms = map[objptr]*fontProgram{}
+		}
+		r.fontPrograms[ptr] = p
+		r.mu.Unlock()
+	}()
+	data, err := io.ReadAll(strm.Reader())
+	if err != nil && len(data) == 0 {
+		return nil
+	}
+	switch {
+	case strm.Key("Subtype").Name() == "Type1C" || strm.Key("Subtype").Name() == "CIDFontType0C":
+		p = parseCFF(data)
+	case len(data) >= 4 && (string(data[:4]) == "OTTO" || string(data[:4]) == "true" || string(data[:4]) == "ttcf" || binary.BigEndian.Uint32(data) == 0x00010000):
+		p = parseSFNT(data)
+	case strm.Key("Length1").Int64() > 0 || strings.HasPrefix(string(data), "%!"):
+		p = parseType1(data, int(strm.Key("Length1").Int64()))
+	}
+	if p != nil { // indexed now, the program is then shared by the fonts of every page
+		p.byName = map[string]int{}
+		for gid, n := range p.names {
+			if _, ok := p.byName[n]; !ok {
+				p.byName[n] = gid
+			}
+		}
+	}
+	return p
+}
+
+// glyphText returns the text of a glyph, from the Unicode cmap of the font, else from its name
+func (p *fontProgram) glyphText(gid int) string {
+	if s, ok := p.text[gid]; ok {
+		return s
+	}
+	if gid > 0 && gid < len(p.names) {
+		return glyphNameText(p.names[gid], false)
+	}
+	return ""
+}
+
+// nameText returns the text of a glyph given by a name which is not a glyph list one, from the glyph of
+// that name in the font, or from the glyph ID or CID the name is made of
+func (p *fontProgram) nameText(name string) string {
+	if gid, ok := p.byName[name]; ok {
+		return p.glyphText(gid)
+	}
+	n, cid, ok := glyphNumber(name)
+	if !ok {
+		return ""
+	}
+	if cid {
+		return p.glyphText(p.cidGlyph(n))
+	}
+	return p.glyphText(n)
+}
+
+// cidGlyph returns the glyph ID of a CID in a CFF font, the same number for the fonts which are not
+// CID-keyed
+func (p *fontProgram) cidGlyph(cid int) int {
+	if p.gids == nil {
+		return cid
+	}
+	return p.gids[cid]
+}
+
+// parseSFNT reads a TrueType or OpenType font (the first one of a collection)
+func parseSFNT(data []byte) *fontProgram {
+	off := 0
+	if string(data[:4]) == "ttcf" {
+		off = int(u32(data, 12))
+	}
+	tables := map[string][]byte{}
+	n := int(u16(data, off+4))
+	for i := 0; i < n; i++ {
+		rec := off + 12 + 16*i
+		start, length := int(u32(data, rec+8)), int(u32(data, rec+12))
+		if start >= 0 && length >= 0 && start <= len(data) && length <= len(data)-start {
+			tables[string(data[rec:rec+4])] = data[start : start+length]
+		}
+	}
+
+	p := &fontProgram{text: map[int]string{}}
+	if cff, ok := tables["CFF "]; ok {
+		if q := parseCFF(cff); q != nil {
+			p = q
+			p.text = map[int]string{}
+		}
+	}
+	if post := tables["post"]; p.names == nil && len(post) >= 34 {
+		switch u32(post, 0) {
+		case 0x00010000:
+			p.names = macGlyphNames[:]
+		case 0x00020000:
+			// glyph name indexes, then the Pascal strings of the names past the standard ones
+			numGlyphs := int(u16(post, 32))
+			var extra []string
+			for i := 34 + 2*numGlyphs; i < len(post); i += 1 + int(post[i]) {
+				extra = append(extra, string(post[i+1:min(len(post), i+1+int(post[i]))]))
+			}
+			p.names = make([]string, numGlyphs)
+			for gid := range p.names {
+				switch idx := int(u16(post, 34+2*gid)); {
+				case idx < len(macGlyphNames):
+					p.names[gid] = macGlyphNames[idx]
+				case idx-len(macGlyphNames) < len(extra):
+					p.names[gid] = extra[idx-len(macGlyphNames)]
+				}
+			}
+		}
+	}
+
+	// Unicode subtables give the text of the glyphs, symbolic ones (3,0) and (1,0) the built-in encoding
+	var symbol, mac map[int]int
+	cmap := tables["cmap"]
+	for i := 0; i < int(u16(cmap, 2)); i++ {
+		rec := 4 + 8*i
+		platform, encoding, start := u16(cmap, rec), u16(cmap, rec+2), int(u32(cmap, rec+4))
+		if start >= len(cmap) {
+			continue
+		}
+		switch m := cmapSubtable(cmap[start:]); {
+		case platform == 0 || platform == 3 && (encoding == 1 || encoding == 10):
+			for code, gid := range m {
+				if s, ok := p.text[gid]; !ok || []rune(s)[0] > rune(code) {
+					p.text[gid] = string(rune(code))
+				}
+			}
+		case platform == 3 && encoding == 0:
+			symbol = m
+		case platform == 1 && encoding == 0:
+			mac = m
+		}
+	}
+	for code := range p.builtin {
+		gid, ok := 0, false
+		if symbol != nil {
+			for _, base := range []int{0xf000, 0xf100, 0xf200, 0} {
+				if gid, ok = symbol[base+code]; ok {
+					break
+				}
+			}
+		}
+		if !ok && mac != nil {
+			gid, ok = mac[code]
+		}
+		if ok {
+			p.builtin[code] = p.glyphText(gid)
+		}
+	}
+	return p
+}
+
+// cmapSubtable reads the codes and glyph IDs of a cmap subtable of format 0, 4, 6 or 12. Crafted
+// subtables may repeat or overlap their ranges, so the codes read are counted rather than the codes found.
+func cmapSubtable(b []byte) map[int]int {
+	const maxCodes = 1 << 17
+	m := map[int]int{}
+	n := 0
+	switch u16(b, 0) {
+	case 0:
+		for code := 0; code < 256 && 6+code < len(b); code++ {
+			m[code] = int(b[6+code])
+		}
+	case 4:
+		segs := int(u16(b, 6)) / 2
+		if 16+8*segs > len(b) {
+			return m
+		}
+		ends, starts, deltas, offsets := 14, 16+2*segs, 16+4*segs, 16+6*segs
+		for i := 0; i < segs && n < maxCodes; i++ {
+			start, end := int(u16(b, starts+2*i)), int(u16(b, ends+2*i))
+			delta, offset := int(u16(b, deltas+2*i)), int(u16(b, offsets+2*i))
+			for code := start; code <= end && code != 0xffff && n < maxCodes; code++ {
+				n++
+				gid := (code + delta) & 0xffff
+				if offset != 0 {
+					if gid = int(u16(b, offsets+2*i+offset+2*(code-start))); gid != 0 {
+						gid = (gid + delta) & 0xffff
+					}
+				}
+				if gid != 0 {
+					m[code] = gid
+				}
+			}
+		}
+	case 6:
+		first, count := int(u16(b, 6)), int(u16(b, 8))
+		for i := 0; i < count; i++ {
+			if gid := int(u16(b, 10+2*i)); gid != 0 {
+				m[first+i] = gid
+			}
+		}
+	case 12:
+		for i := 0; i < int(u32(b, 12)) && 28+12*i <= len(b) && n < maxCodes; i++ {
+			start, end, gid := int(u32(b, 16+12*i)), int(u32(b, 20+12*i)), int(u32(b, 24+12*i))
+			for code := start; code <= end && code <= 0x10ffff && n < maxCodes; code++ {
+				n++
+				m[code] = gid + code - start
+			}
+		}
+	}
+	return m
+}
+
+// u16 and u32 read big-endian numbers, zero past the end of b
+func u16(b []byte, i int) uint16 {
+	if i < 0 || i+2 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b[i:])
+}
+
+func u32(b []byte, i int) uint32 {
+	if i < 0 || i+4 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b[i:])
+}
+
+// parseCFF reads the charset and encoding of the first font of a CFF font set
+func parseCFF(data []byte) *fontProgram {
+	if len(data) < 4 {
+		return nil
+	}
+	_, pos := cffIndex(data, int(data[2])) // Name INDEX
+	topDicts, pos := cffIndex(data, pos)
+	strs, _ := cffIndex(data, pos)
+	if len(topDicts) == 0 {
+		return nil
+	}
+	top := cffDict(topDicts[0])
+	sid := func(n int) string {
+		if n < len(cffStandardStrings) {
+			return cffStandardStrings[n]
+		}
+		if n-len(cffStandardStrings) < len(strs) {
+			return string(strs[n-len(cffStandardStrings)])
+		}
+		return ""
+	}
+	charStrings, _ := cffIndex(data, top[17])
+	numGlyphs := len(charStrings)
+	_, cidKeyed := top[12<<8|30] // ROS
+
+	// the charset gives the names, or the CIDs, of the glyphs past .notdef
+	p := &fontProgram{}
+	glyphs := make([]int, 1, numGlyphs)
+	switch charset := top[15]; {
+	case charset == 0 && !cidKeyed: // ISOAdobe
+		for gid := 1; gid < numGlyphs && gid <= 228; gid++ {
+			glyphs = append(glyphs, gid)
+		}
+	case charset > 2:
+		format, i := byte(0), charset+1
+		if charset < len(data) {
+			format = data[charset]
+		}
+		for len(glyphs) < numGlyphs && i < len(data) {
+			first, left := int(u16(data, i)), 0
+			switch format {
+			case 0:
+				i += 2
+			case 1:
+				left, i = int(data[min(i+2, len(data)-1)]), i+3
+			case 2:
+				left, i = int(u16(data, i+2)), i+4
+			default:
+				i = len(data)
+				continue
+			}
+			for n := first; n <= first+left && len(glyphs) < numGlyphs; n++ {
+				glyphs = append(glyphs, n)
+			}
+		}
+	}
+	if cidKeyed {
+		p.gids = map[int]int{}
+		for gid, cid := range glyphs {
+			p.gids[cid] = gid
+		}
+		return p
+	}
+	p.names = make([]string, len(glyphs))
+	for gid, n := range glyphs {
+		p.names[gid] = sid(n)
+	}
+
+	// the built-in encoding maps codes to glyph IDs, supplements to names
+	switch enc := top[16]; {
+	case enc == 0:
+		for code, r := range standardEncoding {
+			if r != noRune {
+				p.builtin[code] = string(r)
+			}
+		}
+	case enc > 1 && enc < len(data):
+		format, i := data[enc], enc+1
+		switch format & 0x7f {
+		case 0:
+			n := int(data[min(i, len(data)-1)])
+			for gid := 1; gid <= n && i+gid < len(data); gid++ {
+				p.builtin[data[i+gid]] = p.glyphText(gid)
+			}
+			i += 1 + n
+		case 1:
+			ranges, gid := int(data[min(i, len(data)-1)]), 1
+			for r := 0; r < ranges && i+2+2*r < len(data); r++ {
+				first, left := int(data[i+1+2*r]), int(data[i+2+2*r])
+				for code := first; code <= first+left && code < 256; code++ {
+					p.builtin[code] = p.glyphText(gid)
+					gid++
+				}
+			}
+			i += 1 + 2*ranges
+		}
+		if format&0x80 != 0 && i < len(data) {
+			for s, n := 0, int(data[i]); s < n && i+3+3*s < len(data); s++ {
+				p.builtin[data[i+1+3*s]] = glyphNameText(sid(int(u16(data, i+2+3*s))), false)
+			}
+		}
+	}
+	return p
+}
+
+// cffIndex reads the INDEX structure at pos, returning its items and the position following it
+func cffIndex(data []byte, pos int) ([][]byte, int) {
+	count := int(u16(data, pos))
+	if count == 0 || pos+3 > len(data) {
+		return nil, pos + 2
+	}
+	offSize := int(data[pos+2])
+	if offSize < 1 || offSize > 4 {
+		return nil, len(data)
+	}
+	offset := func(i int) int {
+		v, at := 0, pos+3+i*offSize
+		for j := 0; j < offSize && at+j < len(data); j++ {
+			v = v<<8 | int(data[at+j])
+		}
+		return pos + 3 + (count+1)*offSize - 1 + v
+	}
+	items := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		start, end := offset(i), offset(i+1)
+		if start < 0 || start > end || end > len(data) {
+			return items, len(data)
+		}
+		items = append(items, data[start:end])
+	}
+	return items, offset(count)
+}
+
+// cffDict reads the integer operands of the operators of a DICT, the two-byte operators 12 x being keyed
+// by 12<<8|x
+func cffDict(b []byte) map[int]int {
+	d := map[int]int{}
+	var operands []int
+	for i := 0; i < len(b); {
+		switch c := int(b[i]); {
+		case c <= 21:
+			op := c
+			if c == 12 && i+1 < len(b) {
+				op = 12<<8 | int(b[i+1])
+				i++
+			}
+			i++
+			if len(operands) > 0 {
+				d[op] = operands[len(operands)-1]
+			} else {
+				d[op] = 0
+			}
+			operands = operands[:0]
+		case c == 28:
+			operands = append(operands, int(int16(u16(b, i+1))))
+			i += 3
+		case c == 29:
+			operands = append(operands, int(int32(u32(b, i+1))))
+			i += 5
+		case c == 30: // real number, of nibbles up to 0xf
+			for i++; i < len(b) && b[i]&0x0f != 0x0f && b[i]&0xf0 != 0xf0; i++ {
+			}
+			i++
+			operands = append(operands, 0)
+		case c >= 32 && c <= 246:
+			operands = append(operands, c-139)
+			i++
+		case c >= 247 && c <= 250 && i+1 < len(b):
+			operands = append(operands, (c-247)*256+int(b[i+1])+108)
+			i += 2
+		case c >= 251 && c <= 254 && i+1 < len(b):
+			operands = append(operands, -(c-251)*256-int(b[i+1])-108)
+			i += 2
+		default:
+			i++
+		}
+	}
+	return d
+}
+
+// parseType1 reads the encoding of the clear text part of a Type 1 font program
+func parseType1(data []byte, length1 int) *fontProgram {
+	if length1 > 0 && length1 < len(data) {
+		data = data[:length1]
+	}
+	p := &fontProgram{}
+	fields := strings.Fields(string(data))
+	for i, f := range fields {
+		switch {
+		case f == "/Encoding" && i+1 < len(fields) && fields[i+1] == "StandardEncoding":
+			for code, r := range standardEncoding {
+				if r != noRune {
+					p.builtin[code] = string(r)
+				}
+			}
+		case f == "dup" && i+3 < len(fields) && fields[i+3] == "put" && strings.HasPrefix(fields[i+2], "/"):
+			if code, err := strconv.Atoi(fields[i+1]); err == nil && code >= 0 && code < 256 {
+				p.builtin[code] = glyphNameText(fields[i+2][1:], false)
+			}
+		}
+	}
+	return p
+}
+
+// standard Macintosh glyph names of the post table, by index
+var macGlyphNames = [258]string{
+	".notdef", ".null", "nonmarkingreturn", "space", "exclam", "quotedbl", "numbersign", "dollar",
+	"percent", "ampersand", "quotesingle", "parenleft", "parenright", "asterisk", "plus", "comma",
+	"hyphen", "period", "slash", "zero", "one", "two", "three", "four", "five", "six", "seven",
+	"eight", "nine", "colon", "semicolon", "less", "equal", "greater", "question", "at", "A", "B",
+	"C", "D", "E", "F", "G", "H", "I", "J", "K", "L", "M", "N", "O", "P", "Q", "R", "S", "T", "U",
+	"V", "W", "X", "Y", "Z", "bracketleft", "backslash", "bracketright", "asciicircum",
+	"underscore", "grave", "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n",
+	"o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "braceleft", "bar", "braceright",
+	"asciitilde", "Adieresis", "Aring", "Ccedilla", "Eacute", "Ntilde", "Odieresis", "Udieresis",
+	"aacute", "agrave", "acircumflex", "adieresis", "atilde", "aring", "ccedilla", "eacute",
+	"egrave", "ecircumflex", "edieresis", "iacute", "igrave", "icircumflex", "idieresis", "ntilde",
+	"oacute", "ograve", "ocircumflex", "odieresis", "otilde", "uacute", "ugrave", "ucircumflex",
+	"udieresis", "dagger", "degree", "cent", "sterling", "section", "bullet", "paragraph",
+	"germandbls", "registered", "copyright", "trademark", "acute", "dieresis", "notequal", "AE",
+	"Oslash", "infinity", "plusminus", "lessequal", "greaterequal", "yen", "mu", "partialdiff",
+	"summation", "product", "pi", "integral", "ordfeminine", "ordmasculine", "Omega", "ae",
+	"oslash", "questiondown", "exclamdown", "logicalnot", "radical", "florin", "approxequal",
+	"Delta", "guillemotleft", "guillemotright", "ellipsis", "nonbreakingspace", "Agrave", "Atilde",
+	"Otilde", "OE", "oe", "endash", "emdash", "quotedblleft", "quotedblright", "quoteleft",
+	"quoteright", "divide", "lozenge", "ydieresis", "Ydieresis", "fraction", "currency",
+	"guilsinglleft", "guilsinglright", "fi", "fl", "daggerdbl", "periodcentered", "quotesinglbase",
+	"quotedblbase", "perthousand", "Acircumflex", "Ecircumflex", "Aacute", "Edieresis", "Egrave",
+	"Iacute", "Icircumflex", "Idieresis", "Igrave", "Oacute", "Ocircumflex", "apple", "Ograve",
+	"Uacute", "Ucircumflex", "Ugrave", "dotlessi", "circumflex", "tilde", "macron", "breve",
+	"dotaccent", "ring", "cedilla", "hungarumlaut", "ogonek", "caron", "Lslash", "lslash",
+	"Scaron", "scaron", "Zcaron", "zcaron", "brokenbar", "Eth", "eth", "Yacute", "yacute", "Thorn",
+	"thorn", "minus", "multiply", "onesuperior", "twosuperior", "threesuperior", "onehalf",
+	"onequarter", "threequarters", "franc", "Gbreve", "gbreve", "Idotaccent", "Scedilla",
+	"scedilla", "Cacute", "cacute", "Ccaron", "ccaron", "dcroat",
+}
+
+// standard strings of CFF fonts, by SID (Adobe Technical Note #5176, Appendix A)
+var cffStandardStrings = [391]string{
+	".notdef", "space", "exclam", "quotedbl", "numbersign", "dollar", "percent", "ampersand",
+	"quoteright", "parenleft", "parenright", "asterisk", "plus", "comma", "hyphen", "period",
+	"slash", "zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+	"colon", "semicolon", "less", "equal", "greater", "question", "at", "A", "B", "C", "D", "E",
+	"F", "G", "H", "I", "J", "K", "L", "M", "N", "O", "P", "Q", "R", "S", "T", "U", "V", "W", "X",
+	"Y", "Z", "bracketleft", "backslash", "bracketright", "asciicircum", "underscore", "quoteleft",
+	"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s",
+	"t", "u", "v", "w", "x", "y", "z", "braceleft", "bar", "braceright", "asciitilde",
+	"exclamdown", "cent", "sterling", "fraction", "yen", "florin", "section", "currency",
+	"quotesingle", "quotedblleft", "guillemotleft", "guilsinglleft", "guilsinglright", "fi", "fl",
+	"endash", "dagger", "daggerdbl", "periodcentered", "paragraph", "bullet", "quotesinglbase",
+	"quotedblbase", "quotedblright", "guillemotright", "ellipsis", "perthousand", "questiondown",
+	"grave", "acute", "circumflex", "tilde", "macron", "breve", "dotaccent", "dieresis", "ring",
+	"cedilla", "hungarumlaut", "ogonek", "caron", "emdash", "AE", "ordfeminine", "Lslash",
+	"Oslash", "OE", "ordmasculine", "ae", "dotlessi", "lslash", "oslash", "oe", "germandbls",
+	"onesuperior", "logicalnot", "mu", "trademark", "Eth", "onehalf", "plusminus", "Thorn",
+	"onequarter", "divide", "brokenbar", "degree", "thorn", "threequarters", "twosuperior",
+	"registered", "minus", "eth", "multiply", "threesuperior", "copyright", "Aacute",
+	"Acircumflex", "Adieresis", "Agrave", "Aring", "Atilde", "Ccedilla", "Eacute", "Ecircumflex",
+	"Edieresis", "Egrave", "Iacute", "Icircumflex", "Idieresis", "Igrave", "Ntilde", "Oacute",
+	"Ocircumflex", "Odieresis", "Ograve", "Otilde", "Scaron", "Uacute", "Ucircumflex", "Udieresis",
+	"Ugrave", "Yacute", "Ydieresis", "Zcaron", "aacute", "acircumflex", "adieresis", "agrave",
+	"aring", "atilde", "ccedilla", "eacute", "ecircumflex", "edieresis", "egrave", "iacute",
+	"icircumflex", "idieresis", "igrave", "ntilde", "oacute", "ocircumflex", "odieresis", "ograve",
+	"otilde", "scaron", "uacute", "ucircumflex", "udieresis", "ugrave", "yacute", "ydieresis",
+	"zcaron", "exclamsmall", "Hungarumlautsmall", "dollaroldstyle", "dollarsuperior",
+	"ampersandsmall", "Acutesmall", "parenleftsuperior", "parenrightsuperior", "twodotenleader",
+	"onedotenleader", "zerooldstyle", "oneoldstyle", "twooldstyle", "threeoldstyle",
+	"fouroldstyle", "fiveoldstyle", "sixoldstyle", "sevenoldstyle", "eightoldstyle",
+	"nineoldstyle", "commasuperior", "threequartersemdash", "periodsuperior", "questionsmall",
+	"asuperior", "bsuperior", "centsuperior", "dsuperior", "esuperior", "isuperior", "lsuperior",
+	"msuperior", "nsuperior", "osuperior", "rsuperior", "ssuperior", "tsuperior", "ff", "ffi",
+	"ffl", "parenleftinferior", "parenrightinferior", "Circumflexsmall", "hyphensuperior",
+	"Gravesmall", "Asmall", "Bsmall", "Csmall", "Dsmall", "Esmall", "Fsmall", "Gsmall", "Hsmall",
+	"Ismall", "Jsmall", "Ksmall", "Lsmall", "Msmall", "Nsmall", "Osmall", "Psmall", "Qsmall",
+	"Rsmall", "Ssmall", "Tsmall", "Usmall", "Vsmall", "Wsmall", "Xsmall", "Ysmall", "Zsmall",
+	"colonmonetary", "onefitted", "rupiah", "Tildesmall", "exclamdownsmall", "centoldstyle",
+	"Lslashsmall", "Scaronsmall", "Zcaronsmall", "Dieresissmall", "Brevesmall", "Caronsmall",
+	"Dotaccentsmall", "Macronsmall", "figuredash", "hypheninferior", "Ogoneksmall", "Ringsmall",
+	"Cedillasmall", "questiondownsmall", "oneeighth", "threeeighths", "fiveeighths",
+	"seveneighths", "onethird", "twothirds", "zerosuperior", "foursuperior", "fivesuperior",
+	"sixsuperior", "sevensuperior", "eightsuperior", "ninesuperior", "zeroinferior", "oneinferior",
+	"twoinferior", "threeinferior", "fourinferior", "fiveinferior", "sixinferior", "seveninferior",
+	"eightinferior", "nineinferior", "centinferior", "dollarinferior", "periodinferior",
+	"commainferior", "Agravesmall", "Aacutesmall", "Acircumflexsmall", "Atildesmall",
+	"Adieresissmall", "Aringsmall", "AEsmall", "Ccedillasmall", "Egravesmall", "Eacutesmall",
+	"Ecircumflexsmall", "Edieresissmall", "Igravesmall", "Iacutesmall", "Icircumflexsmall",
+	"Idieresissmall", "Ethsmall", "Ntildesmall", "Ogravesmall", "Oacutesmall", "Ocircumflexsmall",
+	"Otildesmall", "Odieresissmall", "OEsmall", "Oslashsmall", "Ugravesmall", "Uacutesmall",
+	"Ucircumflexsmall", "Udieresissmall", "Yacutesmall", "Thornsmall", "Ydieresissmall", "001.000",
+	"001.001", "001.002", "001.003", "Black", "Bold", "Book", "Light", "Medium", "Regular",
+	"Roman", "Semibold",
+}
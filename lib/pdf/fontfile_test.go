@@ -0,0 +1,54 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// cmapFormat4 returns a cmap subtable of format 4 of the segments, each of a start and end code and a delta
+func cmapFormat4(segs ...[3]int) []byte {
+	b := make([]byte, 16+8*len(segs))
+	binary.BigEndian.PutUint16(b, 4)
+	binary.BigEndian.PutUint16(b[6:], uint16(2*len(segs)))
+	for i, seg := range segs {
+		binary.BigEndian.PutUint16(b[14+2*i:], uint16(seg[1]))
+		binary.BigEndian.PutUint16(b[16+2*len(segs)+2*i:], uint16(seg[0]))
+		binary.BigEndian.PutUint16(b[16+4*len(segs)+2*i:], uint16(seg[2]))
+	}
+	return b
+}
+
+func TestCmapSubtable(t *testing.T) {
+	m := cmapSubtable(cmapFormat4([3]int{0x41, 0x43, 3 - 0x41}, [3]int{0xffff, 0xffff, 1}))
+	if len(m) != 3 || m[0x41] != 3 || m[0x43] != 5 {
+		t.Errorf("format 4: got %v", m)
+	}
+
+	// segments which the table is too short to hold
+	b := cmapFormat4([3]int{0x41, 0x43, 1})
+	binary.BigEndian.PutUint16(b[6:], 0xfffe)
+	if m := cmapSubtable(b); len(m) != 0 {
+		t.Errorf("truncated format 4: got %d codes", len(m))
+	}
+
+	// 32767 segments of all the codes
+	segs := make([][3]int, 0x7fff)
+	for i := range segs {
+		segs[i] = [3]int{0, 0xfffe, 1}
+	}
+	if m := cmapSubtable(cmapFormat4(segs...)); len(m) != 0xffff {
+		t.Errorf("repeated format 4: got %d codes", len(m))
+	}
+
+	// format 12 groups of the same 100000 codes
+	b = make([]byte, 16+12*10000)
+	binary.BigEndian.PutUint16(b, 12)
+	binary.BigEndian.PutUint32(b[12:], 10000)
+	for i := 0; i < 10000; i++ {
+		binary.BigEndian.PutUint32(b[20+12*i:], 99999)
+		binary.BigEndian.PutUint32(b[24+12*i:], 1)
+	}
+	if m := cmapSubtable(b); len(m) != 100000 || m[99999] != 100000 {
+		t.Errorf("repeated format 12: got %d codes", len(m))
+	}
+}
@@ -0,0 +1,291 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A Field is a field of the interactive form of the document: a terminal field of the AcroForm field
+// tree, or a value of the data of an XFA form
+type Field struct {
+	Name     string   // fully qualified name, the partial names of the field and of its ancestors joined by dots
+	Type     string   // Tx (text), Btn (check box or radio button), Ch (choice) or Sig (signature), empty for XFA values
+	Value    string   // the selected options of a multiple choice field are joined by ", "
+	Default  string   // value the field takes when the form is reset
+	Options  []string // items of a choice field, as displayed
+	ReadOnly bool
+}
+
+// field flags (Ff) used by Fields, PDF 32000-1:2008, §12.7.3.1 and §12.7.4.2.1
+const (
+	fieldReadOnly   = 1 << 0
+	fieldPushButton = 1 << 16
+)
+
+// XML namespaces of the XFA data and of the rich text it may hold
+const (
+	nsXFAData = "http://www.xfa.org/schema/xfa-data/1.0/"
+	nsXHTML   = "http://www.w3.org/1999/xhtml"
+)
+
+// Fields returns the fields of the AcroForm of the document (push buttons, which hold no value, left out)
+// and then the values of the XFA datasets packet which are not also AcroForm fields, as forms made with
+// LiveCycle or AEM Designer only keep their data there. Malformed objects end the search.
+func (r *Reader) Fields() (fields []Field) {
+	defer func() {
+		recover()
+	}()
+	acro := r.Trailer().safeKey("Root").safeKey("AcroForm")
+	if acro.Kind() != Dict {
+		return nil
+	}
+	walkFields(acro.safeKey("Fields"), func(f Field) {
+		fields = append(fields, f)
+	})
+	known := map[string]bool{}
+	for _, f := range fields {
+		known[somKey(f.Name)] = true
+	}
+	for _, f := range parseXFA(xfaPackets(acro.safeKey("XFA"))) {
+		if !known[somKey(f.Name)] {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// inherited holds the attributes a field takes from its ancestors (PDF 32000-1:2008, Table 220)
+type inherited struct {
+	name  string
+	ft    string
+	ff    int64
+	v, dv Value
+	opt   Value
+}
+
+// walkFields calls fn with the terminal fields of the field tree whose roots are in the array fields
+func walkFields(fields Value, fn func(Field)) {
+	seen := map[objptr]bool{}
+	var walk func(node Value, ref bool, in inherited, depth int)
+	walk = func(node Value, ref bool, in inherited, depth int) {
+		if node.Kind() != Dict || depth > 32 || ref && seen[node.ptr] {
+			return
+		}
+		if ref { // direct fields cannot make a cycle
+			seen[node.ptr] = true
+		}
+		if t := node.safeKey("T").Text(); t != "" {
+			if in.name != "" {
+				in.name += "."
+			}
+			in.name += t
+		}
+		if ft := node.safeKey("FT").Name(); ft != "" {
+			in.ft = ft
+		}
+		if ff := node.safeKey("Ff"); ff.Kind() == Integer {
+			in.ff = ff.Int64()
+		}
+		for _, a := range []struct {
+			v   *Value
+			key string
+		}{{&in.v, "V"}, {&in.dv, "DV"}, {&in.opt, "Opt"}} {
+			if v := node.safeKey(a.key); v.Kind() != Null {
+				*a.v = v
+			}
+		}
+		terminal := true
+		kids := node.safeKey("Kids")
+		for i := 0; i < kids.Len(); i++ {
+			kid := kids.Index(i)
+			// the kids of a terminal field are its widget annotations, which have no partial name
+			if kid.safeKey("T").Kind() != Null || kid.safeKey("Kids").Kind() == Array {
+				terminal = false
+				walk(kid, kids.isRef(i), in, depth+1)
+			}
+		}
+		if !terminal || in.name == "" || in.ft == "Btn" && in.ff&fieldPushButton != 0 {
+			return
+		}
+		f := Field{
+			Name:     in.name,
+			Type:     in.ft,
+			Value:    fieldValue(in.v, in.ft, in.opt),
+			Default:  fieldValue(in.dv, in.ft, in.opt),
+			ReadOnly: in.ff&fieldReadOnly != 0,
+		}
+		if in.ft == "Ch" {
+			for i := 0; i < in.opt.Len(); i++ {
+				item := in.opt.Index(i)
+				if item.Kind() == Array { // [export value, displayed text]
+					item = item.Index(1)
+				}
+				f.Options = append(f.Options, item.Text())
+			}
+		}
+		fn(f)
+	}
+	for i := 0; i < fields.Len(); i++ {
+		walk(fields.Index(i), fields.isRef(i), inherited{}, 0)
+	}
+}
+
+// fieldValue returns the value V or DV of a field of type ft as text
+func fieldValue(v Value, ft string, opt Value) string {
+	switch v.Kind() {
+	case String:
+		return v.Text()
+	case Name:
+		// the appearance states of check boxes and radio buttons with an Opt array are indexes into it
+		if i, err := strconv.Atoi(v.Name()); err == nil && ft == "Btn" && i >= 0 && i < opt.Len() {
+			return opt.Index(i).Text()
+		}
+		return v.Name()
+	case Integer, Real:
+		return fmt.Sprint(v.data)
+	case Array:
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, fieldValue(v.Index(i), ft, opt))
+		}
+		return strings.Join(items, ", ")
+	case Stream: // long text may be written in a stream
+		data, err := io.ReadAll(v.Reader())
+		if err != nil && len(data) == 0 {
+			return ""
+		}
+		return Value{data: string(data)}.Text()
+	case Dict: // the signature dictionary of a signed field
+		return v.safeKey("Name").Text()
+	}
+	return ""
+}
+
+// xfaPackets returns the XML of an XFA form: the XFA entry is a stream of the whole XDP document or an
+// array of packet names and streams, whose contents make up the same document
+func xfaPackets(xfa Value) []byte {
+	var buf bytes.Buffer
+	read := func(strm Value) {
+		if strm.Kind() == Stream {
+			rd := strm.Reader()
+			io.Copy(&buf, rd)
+			rd.Close()
+		}
+	}
+	read(xfa)
+	for i := 1; i < xfa.Len(); i += 2 {
+		read(xfa.Index(i))
+	}
+	return buf.Bytes()
+}
+
+// xfaNode is an element of the XFA data
+type xfaNode struct {
+	name string
+	text strings.Builder
+	kids []*xfaNode
+}
+
+// parseXFA returns the values of the leaf elements of the data (xfa:data of the datasets packet) of an
+// XFA form, named by their path from the data root in the SOM notation, such as "form1.items.item[1].qty"
+func parseXFA(data []byte) []Field {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	var (
+		root  *xfaNode
+		stack []*xfaNode
+		rich  int // depth inside rich text (XHTML) elements, whose text goes to the enclosing value
+	)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == nsXHTML && len(stack) > 0 || rich > 0:
+				rich++
+			case t.Name.Space == nsXFAData && t.Name.Local == "data" && root == nil:
+				root = &xfaNode{}
+				stack = append(stack, root)
+			case len(stack) > 0:
+				n := &xfaNode{name: t.Name.Local}
+				top := stack[len(stack)-1]
+				top.kids = append(top.kids, n)
+				stack = append(stack, n)
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		case xml.EndElement:
+			switch {
+			case rich > 0:
+				rich--
+				if (t.Name.Local == "p" || t.Name.Local == "br") && len(stack) > 0 {
+					stack[len(stack)-1].text.WriteByte('\n')
+				}
+			case len(stack) > 0:
+				stack = stack[:len(stack)-1]
+			}
+		}
+		if root != nil && len(stack) == 0 {
+			break
+		}
+	}
+	if root == nil {
+		return nil
+	}
+	var fields []Field
+	var walk func(n *xfaNode, path string, depth int)
+	walk = func(n *xfaNode, path string, depth int) {
+		if depth > 32 {
+			return
+		}
+		if len(n.kids) == 0 {
+			fields = append(fields, Field{Name: path, Value: strings.TrimSpace(n.text.String())})
+			return
+		}
+		count := map[string]int{}
+		for _, k := range n.kids {
+			count[k.name]++
+		}
+		index := map[string]int{}
+		for _, k := range n.kids {
+			name := k.name
+			if count[name] > 1 {
+				name = fmt.Sprintf("%s[%d]", name, index[k.name])
+				index[k.name]++
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			walk(k, name, depth+1)
+		}
+	}
+	if len(root.kids) > 0 {
+		walk(root, "", 0)
+	}
+	return fields
+}
+
+// somIndex matches the indexes of a name in the XFA SOM notation
+var somIndex = regexp.MustCompile(`\[\d+\]`)
+
+// somKey returns the name of a field without the indexes and the unnamed subforms (#subform) which XFA
+// forms add to the names of the AcroForm fields they also write, such as "form1[0].#subform[0].name[0]"
+func somKey(name string) string {
+	var parts []string
+	for _, p := range strings.Split(somIndex.ReplaceAllString(name, ""), ".") {
+		if !strings.HasPrefix(p, "#") {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ".")
+}
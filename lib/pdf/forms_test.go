@@ -0,0 +1,76 @@
+package pdf
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWalkFields(t *testing.T) {
+	fields := Value{data: array{
+		dict{"T": "address", "FT": name("Tx"), "Ff": int64(fieldReadOnly), "Kids": array{
+			dict{"T": "city", "V": "Vienna"},
+			dict{"T": "country", "Ff": int64(0), "DV": "AT", "Kids": array{dict{"Subtype": name("Widget")}}},
+		}},
+		dict{"T": "size", "FT": name("Ch"), "V": array{"s", "m"}, "Opt": array{array{"s", "Small"}, "m"}},
+		dict{"T": "ok", "FT": name("Btn"), "V": name("1"), "Opt": array{"no", "yes"}},
+		dict{"T": "reset", "FT": name("Btn"), "Ff": int64(fieldPushButton)},
+	}}
+	var got []Field
+	walkFields(fields, func(f Field) {
+		got = append(got, f)
+	})
+	want := []Field{
+		{Name: "address.city", Type: "Tx", Value: "Vienna", ReadOnly: true},
+		{Name: "address.country", Type: "Tx", Default: "AT"},
+		{Name: "size", Type: "Ch", Value: "s, m", Options: []string{"Small", "m"}},
+		{Name: "ok", Type: "Btn", Value: "yes"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestFieldCycles(t *testing.T) {
+	// a field listing itself as its kid, walked once
+	data := writePDF(0,
+		"<< /Type /Catalog /Pages 2 0 R /AcroForm << /Fields [3 0 R] >> >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		"<< /T (address) /FT /Tx /Kids [3 0 R 3 0 R 3 0 R 3 0 R << /T (city) /V (Vienna) >>] >>",
+	)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := r.Fields(); len(fields) != 1 || fields[0].Name != "address.city" || fields[0].Value != "Vienna" {
+		t.Errorf("fields %+v", fields)
+	}
+}
+
+func TestParseXFA(t *testing.T) {
+	got := parseXFA([]byte(`<xdp:xdp xmlns:xdp="http://ns.adobe.com/xdp/"><template><subform name="form1"/></template>
+<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/"><xfa:data><form1>
+<name>Ana</name><items><item><qty>2</qty></item><item><qty>5</qty></item></items>
+<note><body xmlns="http://www.w3.org/1999/xhtml"><p>first</p><p>second</p></body></note>
+</form1></xfa:data></xfa:datasets></xdp:xdp>`))
+	want := []Field{
+		{Name: "form1.name", Value: "Ana"},
+		{Name: "form1.items.item[0].qty", Value: "2"},
+		{Name: "form1.items.item[1].qty", Value: "5"},
+		{Name: "form1.note", Value: "first\nsecond"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if k := somKey("form1[0].#subform[0].name[0]"); k != "form1.name" {
+		t.Errorf("somKey = %q", k)
+	}
+
+	// the values nested more than 32 elements deep are dropped
+	deep := strings.Repeat("<a>", 100) + "x" + strings.Repeat("</a>", 100)
+	if got := parseXFA([]byte(`<xfa:data xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/"><name>Ana</name>` +
+		deep + `</xfa:data>`)); len(got) != 1 || got[0].Name != "name" {
+		t.Errorf("deep data: %d fields", len(got))
+	}
+}
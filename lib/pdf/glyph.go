@@ -0,0 +1,81 @@
+package pdf
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// glyphNameText returns the text of a glyph name along the Adobe Glyph List specification
+// (https://github.com/adobe-type-tools/agl-specification): the name is cut at its first period
+// ("a.sc"), split into components at its underscores ("f_f_i"), and each component is looked up in
+// the glyph list, or in the ZapfDingbats one for that font, or read as "uni" followed by code units
+// ("uni00410301") or as "u" followed by a code point ("u1F600"). Components of another form map to
+// nothing, and so does a name of which no component maps.
+func glyphNameText(name string, zapf bool) string {
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	var b strings.Builder
+	for _, c := range strings.Split(name, "_") {
+		if zapf {
+			if r, ok := zapfNameToRune[c]; ok {
+				b.WriteRune(r)
+				continue
+			}
+		}
+		if s, ok := nameToText[c]; ok {
+			b.WriteString(s)
+		} else if r, ok := nameToRune[c]; ok {
+			b.WriteRune(r)
+		} else if len(c) > 3 && len(c)%4 == 3 && strings.HasPrefix(c, "uni") {
+			var s strings.Builder
+			for i := 3; i < len(c); i += 4 {
+				r, ok := hexRune(c[i:i+4], 4)
+				if !ok {
+					s.Reset()
+					break
+				}
+				s.WriteRune(r)
+			}
+			b.WriteString(s.String())
+		} else if len(c) >= 5 && len(c) <= 7 && c[0] == 'u' {
+			if r, ok := hexRune(c[1:], len(c)-1); ok {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// hexRune reads a code point written with n hexadecimal digits, surrogates excluded
+func hexRune(s string, n int) (rune, bool) {
+	if len(s) != n {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	r := rune(v)
+	return r, utf8.ValidRune(r) && r > 0
+}
+
+// glyphNumber reads the glyph names made of a glyph ID or a CID, which some writers give the glyphs
+// of subset fonts: "g123", "glyph123", "gid123", "index0x7B", and "cid123" or "cid:123"
+func glyphNumber(name string) (n int, cid bool, ok bool) {
+	for _, p := range []struct {
+		prefix string
+		base   int
+		cid    bool
+	}{{"glyph", 10, false}, {"gid", 10, false}, {"g", 10, false}, {"index0x", 16, false}, {"cid:", 10, true}, {"cid", 10, true}} {
+		if digits, found := strings.CutPrefix(name, p.prefix); found && digits != "" {
+			v, err := strconv.ParseUint(digits, p.base, 16)
+			if err != nil {
+				return 0, false, false
+			}
+			return int(v), p.cid, true
+		}
+	}
+	return 0, false, false
+}
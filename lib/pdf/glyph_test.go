@@ -0,0 +1,29 @@
+package pdf
+
+import "testing"
+
+func TestGlyphNameText(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		zapf bool
+		want string
+	}{
+		{"A", false, "A"},
+		{"eacute.sc", false, "é"},
+		{"f_f_i", false, "ffi"},
+		{"uni00410301", false, "Á"},
+		{"uniD800", false, ""},
+		{"u1F600", false, "😀"},
+		{"dalethatafpatah", false, "דֲ"},
+		{"a12", true, "☞"},
+		{"a12", false, ""},
+		{"g123", false, ""},
+	} {
+		if got := glyphNameText(c.name, c.zapf); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+	if n, cid, ok := glyphNumber("cid:42"); n != 42 || !cid || !ok {
+		t.Errorf("cid:42: got %d %v %v", n, cid, ok)
+	}
+}
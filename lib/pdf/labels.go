@@ -0,0 +1,139 @@
+package pdf
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the page labels, which are built from untrusted numbers: the roman and letter numbers above
+// maxLabelNumber are written in decimal, and the labels are cut at maxLabelLength bytes
+const (
+	maxLabelNumber = 3999
+	maxLabelLength = 64
+)
+
+// PageLabels returns the labels of the pages, as shown by viewers instead of the page numbers, such as
+// "iv" or "A-3" (PDF 32000-1:2008, §12.4.2): the label of page n is at index n-1. It is nil for the
+// documents without labels, and malformed labels end at the range which cannot be read.
+func (r *Reader) PageLabels() (labels []string) {
+	defer func() {
+		recover()
+	}()
+	tree := r.Trailer().Key("Root").Key("PageLabels")
+	if tree.Kind() != Dict {
+		return nil
+	}
+	var starts []int
+	var styles []Value
+	walkNumberTree(tree, func(key int, v Value) {
+		if len(starts) == 0 || key > starts[len(starts)-1] {
+			starts = append(starts, key)
+			styles = append(styles, v)
+		}
+	})
+	if len(starts) == 0 {
+		return nil
+	}
+	n := r.NumPage()
+	n = min(n, len(r.xref)) // every page is an object, whatever the Count of the page tree says
+	if n <= 0 {
+		return nil
+	}
+	labels = make([]string, n)
+	for i := range starts {
+		end := n
+		if i+1 < len(starts) {
+			end = min(starts[i+1], n)
+		}
+		prefix := truncateLabel(styles[i].Key("P").Text())
+		style := styles[i].Key("S").Name()
+		first := 1
+		if st := styles[i].Key("St"); st.Kind() == Integer {
+			first = int(st.Int64())
+		}
+		for page := max(starts[i], 0); page < end; page++ {
+			labels[page] = truncateLabel(prefix + pageLabelNumber(first+page-starts[i], style))
+		}
+	}
+	return labels
+}
+
+// pageLabelNumber writes the number of a page in a page label style: decimal (D), roman (R, r) or letters
+// (A, a), which run A to Z, then AA to ZZ and so on. Labels without style have no number.
+func pageLabelNumber(n int, style string) string {
+	if n > maxLabelNumber && style != "" {
+		style = "D"
+	}
+	switch style {
+	case "D":
+		return strconv.Itoa(n)
+	case "R":
+		return strings.ToUpper(roman(n))
+	case "r":
+		return roman(n)
+	case "A":
+		return strings.ToUpper(letters(n))
+	case "a":
+		return letters(n)
+	}
+	return ""
+}
+
+// truncateLabel cuts a label at maxLabelLength bytes, on a rune boundary
+func truncateLabel(s string) string {
+	if len(s) <= maxLabelLength {
+		return s
+	}
+	n := maxLabelLength
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func roman(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	var b strings.Builder
+	for _, d := range []struct {
+		v int
+		s string
+	}{{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"}, {100, "c"}, {90, "xc"}, {50, "l"}, {40, "xl"}, {10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"}} {
+		for ; n >= d.v; n -= d.v {
+			b.WriteString(d.s)
+		}
+	}
+	return b.String()
+}
+
+func letters(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	return strings.Repeat(string(rune('a'+(n-1)%26)), (n-1)/26+1)
+}
+
+// walkNumberTree calls fn with the keys and values of a number tree (PDF 32000-1:2008, §7.9.7) in order
+func walkNumberTree(node Value, fn func(key int, v Value)) {
+	seen := map[objptr]bool{}
+	var walk func(node Value, ref bool, depth int)
+	walk = func(node Value, ref bool, depth int) {
+		if node.Kind() != Dict || depth > 32 || ref && seen[node.ptr] {
+			return
+		}
+		if ref { // direct nodes cannot make a cycle
+			seen[node.ptr] = true
+		}
+		nums := node.Key("Nums")
+		for i := 0; i+1 < nums.Len(); i += 2 {
+			fn(int(nums.Index(i).Int64()), nums.Index(i+1))
+		}
+		kids := node.Key("Kids")
+		for i := 0; i < kids.Len(); i++ {
+			walk(kids.Index(i), kids.isRef(i), depth+1)
+		}
+	}
+	walk(node, true, 0)
+}
@@ -0,0 +1,59 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPageLabelNumber(t *testing.T) {
+	for _, c := range []struct {
+		n     int
+		style string
+		want  string
+	}{
+		{4, "r", "iv"}, {100000000000000, "a", "100000000000000"}, {2600000000, "R", "2600000000"}, {1994, "R", "MCMXCIV"}, {3, "D", "3"}, {2, "A", "B"}, {28, "a", "bb"}, {5, "", ""},
+	} {
+		if got := pageLabelNumber(c.n, c.style); got != c.want {
+			t.Errorf("pageLabelNumber(%d, %q) = %q, want %q", c.n, c.style, got, c.want)
+		}
+	}
+}
+
+func TestPageLabelsHugeStart(t *testing.T) {
+	// the numbers of a crafted file must not decide the size of the labels, nor the Count the number of pages
+	data := writePDF(0,
+		"<< /Type /Catalog /Pages 2 0 R /PageLabels << /Nums [0 << /S /a /St 100000000000000 /P (Appendix ) >>] >> >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 2000000000 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+	)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := r.PageLabels()
+	if len(labels) > 4 || len(labels) == 0 || labels[0] != "Appendix 100000000000000" {
+		t.Errorf("got %d labels, first %q", len(labels), labels[:min(len(labels), 1)])
+	}
+	for _, l := range labels {
+		if len(l) > maxLabelLength {
+			t.Errorf("label of %d bytes", len(l))
+		}
+	}
+}
+
+func TestPageLabelsCyclicTree(t *testing.T) {
+	// a node listing itself as its kids, walked once
+	data := writePDF(0,
+		"<< /Type /Catalog /Pages 2 0 R /PageLabels 4 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+		"<< /Kids [4 0 R 4 0 R 4 0 R 4 0 R << /Nums [0 << /S /r >>] >>] >>",
+	)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := r.PageLabels(); len(labels) != 1 || labels[0] != "i" {
+		t.Errorf("got %q", labels)
+	}
+}
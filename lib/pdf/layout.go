@@ -0,0 +1,431 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Reading order: the glyphs of a page are cut into regions along the whitespace between them (XY-cut). A
+// band of whitespace running down a region, with lines of text on both sides (a river), separates columns,
+// read from left to right; otherwise the widest gap between lines separates what is above from what is
+// below. The lines of the regions left are split into words at the gaps wider than the spacing of their
+// letters, and gathered into blocks that break at paragraphs: a wider gap, another font size or an indented
+// first line.
+
+// A Word is a run of glyphs of a line without space between them
+type Word struct {
+	Rect
+	S        string
+	FontSize float64
+}
+
+// A Line is a row of words sharing a baseline, or a column of them in vertical writing
+type Line struct {
+	Rect
+	Words []Word
+}
+
+// String returns the words of the line separated by spaces
+func (l Line) String() string {
+	s := make([]string, len(l.Words))
+	for i, w := range l.Words {
+		s[i] = w.S
+	}
+	return strings.Join(s, " ")
+}
+
+// A Block is a paragraph, or another run of lines read together such as a heading
+type Block struct {
+	Rect
+	Lines    []Line
+	Vertical bool // whether the lines are columns written top to bottom, read from right to left
+}
+
+// String returns the lines of the block, one per line
+func (b Block) String() string {
+	s := make([]string, len(b.Lines))
+	for i, l := range b.Lines {
+		s[i] = l.String()
+	}
+	return strings.Join(s, "\n")
+}
+
+// A box is a glyph placed in the frame of its writing direction, where lines run from left to right and
+// follow each other downwards: vertical writing is turned a quarter turn counterclockwise
+type box struct {
+	x0, x1, y0, y1 float64
+	size           float64
+	text           Text
+	space          bool // a white space glyph, which separates words but takes no part in the cuts
+}
+
+func newBox(t Text) box {
+	s := math.Abs(t.FontSize)
+	b := box{size: s, text: t, space: strings.TrimSpace(t.S) == ""}
+	if t.Vertical {
+		// the glyph fills the em square below its vertical origin, less the descent
+		b.x0, b.x1 = -(t.Y + 0.9*s), -(t.Y - 0.1*s)
+		b.y0, b.y1 = t.X, t.X+math.Abs(t.W)
+		return b
+	}
+	b.x0, b.x1 = min(t.X, t.X+t.W), max(t.X, t.X+t.W)
+	b.y0, b.y1 = t.Y-0.2*s, t.Y+0.7*s
+	return b
+}
+
+func (b box) base() float64 { return b.y0 + 0.2*b.size }
+
+// rect returns the rectangle of the frame on the page
+func (b box) rect() Rect {
+	if b.text.Vertical {
+		return Rect{Point{b.y0, -b.x1}, Point{b.y1, -b.x0}}
+	}
+	return Rect{Point{b.x0, b.y0}, Point{b.x1, b.y1}}
+}
+
+func union(r, s Rect) Rect {
+	return Rect{Point{min(r.Min.X, s.Min.X), min(r.Min.Y, s.Min.Y)}, Point{max(r.Max.X, s.Max.X), max(r.Max.Y, s.Max.Y)}}
+}
+
+// Layout returns the text of the page as blocks in reading order. The text written in the other direction
+// than most of the page, such as the page numbers of vertical Japanese, comes after, and the glyphs
+// turned out of both directions last, in the order they are shown.
+func (p Page) Layout() (blocks []Block, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			blocks = nil
+			err = errors.New(fmt.Sprint(r))
+		}
+	}()
+	return layout(p.Content().Text), nil
+}
+
+func layout(text []Text) (blocks []Block) {
+	var frames [2][]box // horizontal and vertical writing
+	var turned []Text
+	for _, t := range text {
+		if t.S == "\n" && t.W == 0 {
+			continue
+		}
+		if math.Abs(t.FontSize) < 0.01 {
+			if strings.TrimSpace(t.S) != "" {
+				turned = append(turned, t)
+			}
+			continue
+		}
+		if t.Vertical {
+			frames[1] = append(frames[1], newBox(t))
+		} else {
+			frames[0] = append(frames[0], newBox(t))
+		}
+	}
+	if len(frames[1]) > len(frames[0]) {
+		frames[0], frames[1] = frames[1], frames[0]
+	}
+	for _, boxes := range frames {
+		blocks = append(blocks, paragraphs(cut(boxes, nil))...)
+	}
+	if len(turned) > 0 {
+		w := Word{Rect{Point{turned[0].X, turned[0].Y}, Point{turned[0].X, turned[0].Y}}, "", 0}
+		for _, t := range turned {
+			w.S += t.S
+			w.Rect = union(w.Rect, Rect{Point{t.X, t.Y}, Point{t.X, t.Y}})
+		}
+		blocks = append(blocks, Block{w.Rect, []Line{{w.Rect, []Word{w}}}, false})
+	}
+	return blocks
+}
+
+// GetLayoutText returns the page's text in reading order, a line per line and a blank line between the
+// paragraphs
+func (p Page) GetLayoutText() (string, error) {
+	blocks, err := p.Layout()
+	if err != nil {
+		return "", err
+	}
+	s := make([]string, len(blocks))
+	for i, b := range blocks {
+		s[i] = b.String()
+	}
+	return strings.Join(s, "\n\n"), nil
+}
+
+// GetLayoutText returns all the text in the PDF file in reading order, the plain text of the pages of
+// which the layout cannot be read
+func (r *Reader) GetLayoutText() (reader io.Reader, err error) {
+	var buf bytes.Buffer
+	for i := 1; i <= r.NumPage(); i++ {
+		p := r.Page(i)
+		text, err := p.GetLayoutText()
+		if err != nil {
+			text, _ = p.GetPlainText(nil)
+		}
+		if text == "" {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\n\n")
+		}
+		buf.WriteString(text)
+	}
+	return &buf, nil
+}
+
+// cut appends the lines of the boxes of a region to lines, in reading order
+func cut(boxes []box, lines [][]box) [][]box {
+	if len(boxes) == 0 {
+		return lines
+	}
+	if left, right, ok := river(boxes); ok {
+		return cut(right, cut(left, lines))
+	}
+	if above, below, ok := gap(boxes); ok {
+		return cut(below, cut(above, lines))
+	}
+	return append(lines, splitLines(boxes)...)
+}
+
+// bands merges the extents of the boxes along an axis, in increasing order
+func bands(boxes []box, x bool) [][2]float64 {
+	var spans [][2]float64
+	for _, b := range boxes {
+		if b.space {
+			continue
+		}
+		if x {
+			spans = append(spans, [2]float64{b.x0, b.x1})
+		} else {
+			spans = append(spans, [2]float64{b.y0, b.y1})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	var merged [][2]float64
+	for _, s := range spans {
+		if n := len(merged) - 1; n >= 0 && s[0] <= merged[n][1] {
+			merged[n][1] = max(merged[n][1], s[1])
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// medianSize returns the median font size of the glyphs of the boxes
+func medianSize(boxes []box) float64 {
+	var sizes []float64
+	for _, b := range boxes {
+		if !b.space {
+			sizes = append(sizes, b.size)
+		}
+	}
+	if len(sizes) == 0 {
+		return 0
+	}
+	sort.Float64s(sizes)
+	return sizes[len(sizes)/2]
+}
+
+// river splits the boxes at the widest band of whitespace running down them, at least an em wide, with
+// columns of several lines and several ems on each side, so as not to take lists and the word spaces
+// that happen to line up for columns
+func river(boxes []box) (left, right []box, ok bool) {
+	size := medianSize(boxes)
+	spans := bands(boxes, true)
+	if size == 0 || len(spans) < 2 {
+		return nil, nil, false
+	}
+	widest := 0.0
+	for i := 1; i < len(spans); i++ {
+		x0, x1 := spans[i-1][1], spans[i][0]
+		if x1-x0 < size || x1-x0 <= widest || x0-spans[0][0] < 6*size || spans[len(spans)-1][1]-x1 < 6*size {
+			continue
+		}
+		l, r := partition(boxes, func(b box) bool { return (b.x0+b.x1)/2 < (x0+x1)/2 })
+		if len(bands(l, false)) >= 3 && len(bands(r, false)) >= 3 {
+			left, right, ok, widest = l, r, true, x1-x0
+		}
+	}
+	return left, right, ok
+}
+
+// gap splits the boxes at the widest gap between their lines, the topmost of equal ones
+func gap(boxes []box) (above, below []box, ok bool) {
+	spans := bands(boxes, false)
+	at, widest := 0.0, 0.0
+	for i := len(spans) - 1; i > 0; i-- {
+		if g := spans[i][0] - spans[i-1][1]; g > widest {
+			at, widest = (spans[i][0]+spans[i-1][1])/2, g
+		}
+	}
+	if widest == 0 {
+		return nil, nil, false
+	}
+	above, below = partition(boxes, func(b box) bool { return (b.y0+b.y1)/2 > at })
+	return above, below, len(above) > 0 && len(below) > 0
+}
+
+func partition(boxes []box, in func(b box) bool) (yes, no []box) {
+	for _, b := range boxes {
+		if in(b) {
+			yes = append(yes, b)
+		} else {
+			no = append(no, b)
+		}
+	}
+	return yes, no
+}
+
+// splitLines gathers the boxes of a region with no gap between its lines, as with a tight leading, into
+// lines: the glyphs of the largest size set the baselines, and the smaller ones go to the nearest line,
+// within half their size for subscripts and superscripts
+func splitLines(boxes []box) [][]box {
+	sort.SliceStable(boxes, func(i, j int) bool { return boxes[i].size > boxes[j].size })
+	var lines [][]box
+	for _, b := range boxes {
+		best, dist := -1, 0.0
+		for i, l := range lines {
+			if d := math.Abs(l[0].base() - b.base()); d <= 0.5*max(b.size, 0.01) && (best < 0 || d < dist) {
+				best, dist = i, d
+			}
+		}
+		if best < 0 {
+			lines = append(lines, []box{b})
+			continue
+		}
+		lines[best] = append(lines[best], b)
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i][0].base() > lines[j][0].base() })
+	return lines
+}
+
+// A textLine is a line of a region, in the frame of its writing direction
+type textLine struct {
+	x0, x1, y0, y1 float64
+	size           float64
+	line           Line
+}
+
+// words splits a line into words at its white space glyphs and at the gaps wider than the spacing of its
+// letters, plus a sixth of an em: the lower quartile of the gaps, as most of them are within words, but
+// dot leaders may take many
+func words(boxes []box) (textLine, bool) {
+	sort.SliceStable(boxes, func(i, j int) bool { return boxes[i].x0 < boxes[j].x0 })
+	var glyphs []box
+	var spaced []bool // a white space glyph comes before the glyph
+	space := false
+	for _, b := range boxes {
+		if b.space {
+			space = true
+			continue
+		}
+		// glyphs shown twice, a bit apart, for a bold effect
+		if n := len(glyphs) - 1; n >= 0 && b.text.S == glyphs[n].text.S && b.x0-glyphs[n].x0 < 0.3*(b.x1-b.x0) && math.Abs(b.base()-glyphs[n].base()) < 0.1*b.size {
+			continue
+		}
+		glyphs = append(glyphs, b)
+		spaced = append(spaced, space)
+		space = false
+	}
+	if len(glyphs) == 0 {
+		return textLine{}, false
+	}
+
+	var gaps []float64
+	for i := 1; i < len(glyphs); i++ {
+		if !spaced[i] {
+			gaps = append(gaps, glyphs[i].x0-glyphs[i-1].x1)
+		}
+	}
+	tracking := 0.0
+	if len(gaps) >= 3 {
+		sort.Float64s(gaps)
+		tracking = max(gaps[len(gaps)/4], 0)
+	}
+
+	l := textLine{x0: glyphs[0].x0, x1: glyphs[0].x1, y0: glyphs[0].y0, y1: glyphs[0].y1, size: medianSize(glyphs)}
+	var w Word
+	var text strings.Builder
+	for i, b := range glyphs {
+		if i > 0 && (spaced[i] || b.x0-glyphs[i-1].x1 > tracking+max(b.size, glyphs[i-1].size)/6) {
+			w.S = text.String()
+			l.line.Words = append(l.line.Words, w)
+			text.Reset()
+		}
+		if text.Len() == 0 {
+			w = Word{Rect: b.rect(), FontSize: b.size}
+		}
+		text.WriteString(b.text.S)
+		w.Rect = union(w.Rect, b.rect())
+		w.FontSize = max(w.FontSize, b.size)
+		l.x0, l.x1 = min(l.x0, b.x0), max(l.x1, b.x1)
+		l.y0, l.y1 = min(l.y0, b.y0), max(l.y1, b.y1)
+	}
+	w.S = text.String()
+	l.line.Words = append(l.line.Words, w)
+	l.line.Rect = l.line.Words[0].Rect
+	for _, w := range l.line.Words {
+		l.line.Rect = union(l.line.Rect, w.Rect)
+	}
+	return l, true
+}
+
+// paragraphs gathers lines in reading order into blocks, breaking at the lines which do not follow the
+// previous one closely below it, or are of another size, and at the first lines of paragraphs
+func paragraphs(regions [][]box) []Block {
+	var lines []textLine
+	vertical := false
+	for _, r := range regions {
+		if l, ok := words(r); ok {
+			lines = append(lines, l)
+			vertical = r[0].text.Vertical
+		}
+	}
+
+	// the usual gap between the lines of a paragraph
+	var gaps []float64
+	for i := 1; i < len(lines); i++ {
+		a, b := lines[i-1], lines[i]
+		if g := a.y0 - b.y1; g >= 0 && b.x0 < a.x1 && b.x1 > a.x0 {
+			gaps = append(gaps, g)
+		}
+	}
+	leading := 0.0
+	if len(gaps) > 0 {
+		sort.Float64s(gaps)
+		leading = gaps[len(gaps)/2]
+	}
+
+	var blocks []Block
+	for i := range lines {
+		if i == 0 || breaks(lines[i-1], lines[i], lines[i+1:], leading) {
+			blocks = append(blocks, Block{Rect: lines[i].line.Rect, Vertical: vertical})
+		}
+		b := &blocks[len(blocks)-1]
+		b.Lines = append(b.Lines, lines[i].line)
+		b.Rect = union(b.Rect, lines[i].line.Rect)
+	}
+	return blocks
+}
+
+// breaks tells whether a line starts another block than the previous line
+func breaks(prev, l textLine, next []textLine, leading float64) bool {
+	size := max(l.size, prev.size)
+	switch {
+	case l.x0 >= prev.x1 || l.x1 <= prev.x0: // not below it
+		return true
+	case prev.y0-l.y1 < -0.5*size: // above it, in another column
+		return true
+	case math.Abs(l.size-prev.size) > 0.15*size: // a heading
+		return true
+	case prev.y0-l.y1 > leading+0.4*size: // a wider gap
+		return true
+	}
+
+	// an indented first line, with the next line back to the margin
+	return len(next) > 0 && l.x0 > prev.x0+size && next[0].x0 < l.x0-size && math.Abs(next[0].x0-prev.x0) < size
+}
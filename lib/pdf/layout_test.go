@@ -0,0 +1,49 @@
+package pdf
+
+import "testing"
+
+// show places the glyphs of s from x on the baseline y, the letters 0.6 em wide and the spaces 0.3
+func show(text []Text, x, y, size float64, s string) []Text {
+	for _, r := range s {
+		w := 0.6 * size
+		if r == ' ' {
+			w = 0.3 * size
+		}
+		text = append(text, Text{"Courier", size, x, y, w, string(r), false})
+		x += w
+	}
+	return text
+}
+
+func TestLayout(t *testing.T) {
+	// a title over two columns of three lines each, shown across the columns line after line
+	var text []Text
+	text = show(text, 100, 700, 20, "Title")
+	for i, l := range [][2]string{
+		{"first words of it", "second column text"},
+		{"    an indented line", "goes on down here"},
+		{"back at the margin", "to the very end"},
+	} {
+		y := 660 - 12*float64(i)
+		text = show(text, 50, y, 10, l[0])
+		text = show(text, 300, y, 10, l[1])
+	}
+	// a subscript
+	text = show(text, 50, 600, 10, "H")
+	text = show(text, 56, 597, 7, "2")
+	text = show(text, 60.2, 600, 10, "O")
+
+	var got []string
+	for _, b := range layout(text) {
+		got = append(got, b.String())
+	}
+	want := []string{"Title", "first words of it", "an indented line\nback at the margin", "H2O", "second column text\ngoes on down here\nto the very end"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("block %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata of a document, from its XMP metadata stream or its document information dictionary
+type Metadata struct {
+	Title        string
+	Author       string
+	Subject      string
+	Keywords     string
+	Creator      string // application which created the original document
+	Producer     string // application which converted it to PDF
+	CreationDate time.Time
+	ModDate      time.Time
+}
+
+// XMP namespaces of the properties mapped to Metadata
+const (
+	nsDC  = "http://purl.org/dc/elements/1.1/"
+	nsXMP = "http://ns.adobe.com/xap/1.0/"
+	nsPDF = "http://ns.adobe.com/pdf/1.3/"
+	nsRDF = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+)
+
+// Metadata returns the metadata of the document. PDF 2.0 deprecates the document information dictionary
+// (Info) in favour of the XMP metadata stream of the catalog, so the XMP properties win over the Info
+// entries, unless Info was modified after the XMP metadata by a PDF 1.x writer which ignores it.
+func (r *Reader) Metadata() Metadata {
+	info := r.Trailer().safeKey("Info")
+	m := Metadata{
+		Title:        info.safeKey("Title").Text(),
+		Author:       info.safeKey("Author").Text(),
+		Subject:      info.safeKey("Subject").Text(),
+		Keywords:     info.safeKey("Keywords").Text(),
+		Creator:      info.safeKey("Creator").Text(),
+		Producer:     info.safeKey("Producer").Text(),
+		CreationDate: parseDate(info.safeKey("CreationDate").Text()),
+		ModDate:      parseDate(info.safeKey("ModDate").Text()),
+	}
+	strm := r.Trailer().safeKey("Root").safeKey("Metadata")
+	if strm.Kind() != Stream {
+		return m
+	}
+	data, err := io.ReadAll(strm.Reader())
+	if err != nil && len(data) == 0 {
+		return m
+	}
+	xmp := parseXMP(data)
+	modified := parseXMPDate(xmp[nsXMP+" ModifyDate"])
+	if metadataDate := parseXMPDate(xmp[nsXMP+" MetadataDate"]); metadataDate.After(modified) {
+		modified = metadataDate
+	}
+	if r.Version() < "2.0" && !m.ModDate.IsZero() && m.ModDate.After(modified) {
+		return m
+	}
+	for _, p := range []struct {
+		field *string
+		key   string
+	}{
+		{&m.Title, nsDC + " title"},
+		{&m.Author, nsDC + " creator"},
+		{&m.Subject, nsDC + " description"},
+		{&m.Keywords, nsPDF + " Keywords"},
+		{&m.Creator, nsXMP + " CreatorTool"},
+		{&m.Producer, nsPDF + " Producer"},
+	} {
+		if v := xmp[p.key]; v != "" {
+			*p.field = v
+		}
+	}
+	if t := parseXMPDate(xmp[nsXMP+" CreateDate"]); !t.IsZero() {
+		m.CreationDate = t
+	}
+	if t := parseXMPDate(xmp[nsXMP+" ModifyDate"]); !t.IsZero() {
+		m.ModDate = t
+	}
+	return m
+}
+
+// parseXMP returns the simple properties of an XMP packet, keyed by namespace and local name. The
+// items of ordered and unordered arrays are joined with ", ", language alternatives give their default.
+func parseXMP(data []byte) map[string]string {
+	props := map[string]string{}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	var (
+		depth, descDepth int    // of the current element and of the rdf:Description being read
+		prop             string // property being read
+		alt              bool   // whose value is a language alternative
+		items            []string
+		text             strings.Builder
+	)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return props
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case t.Name.Space == nsRDF && t.Name.Local == "Description":
+				descDepth = depth
+				for _, a := range t.Attr {
+					if a.Name.Space != "" && a.Name.Space != nsRDF && a.Name.Space != "xmlns" {
+						props[a.Name.Space+" "+a.Name.Local] = a.Value
+					}
+				}
+			case descDepth != 0 && depth == descDepth+1:
+				prop = t.Name.Space + " " + t.Name.Local
+				alt, items = false, nil
+				text.Reset()
+			case prop != "" && t.Name.Space == nsRDF && t.Name.Local == "Alt":
+				alt = true
+			case prop != "" && t.Name.Space == nsRDF && t.Name.Local == "li":
+				text.Reset()
+				if alt {
+					for _, a := range t.Attr {
+						if a.Name.Local == "lang" && a.Value == "x-default" {
+							items = nil
+						}
+					}
+				}
+			}
+		case xml.CharData:
+			if prop != "" {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch {
+			case prop != "" && t.Name.Space == nsRDF && t.Name.Local == "li":
+				if s := strings.TrimSpace(text.String()); s != "" && !(alt && len(items) > 0) {
+					items = append(items, s)
+				}
+				text.Reset()
+			case prop != "" && depth == descDepth+1:
+				if items != nil {
+					props[prop] = strings.Join(items, ", ")
+				} else if s := strings.TrimSpace(text.String()); s != "" {
+					props[prop] = s
+				}
+				prop = ""
+			case depth == descDepth:
+				descDepth = 0
+			}
+			depth--
+		}
+	}
+}
+
+// parseDate parses a date of the form D:YYYYMMDDHHmmSSOHH'mm' (PDF 32000-1:2008, §7.9.4), of which
+// everything after the year is optional
+func parseDate(s string) time.Time {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "D:")
+	fields := []int{0, 1, 1, 0, 0, 0} // year, month, day, hour, minute, second
+	widths := []int{4, 2, 2, 2, 2, 2}
+	for i, w := range widths {
+		if len(s) < w {
+			if i == 0 {
+				return time.Time{}
+			}
+			break
+		}
+		n, err := strconv.Atoi(s[:w])
+		if err != nil || s[0] < '0' || s[0] > '9' {
+			if i == 0 {
+				return time.Time{}
+			}
+			break
+		}
+		fields[i] = n
+		s = s[w:]
+	}
+	loc := time.UTC
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		tz := strings.NewReplacer("'", "", ":", "").Replace(s[1:])
+		hh, _ := strconv.Atoi(tz[:min(2, len(tz))])
+		mm := 0
+		if len(tz) >= 4 {
+			mm, _ = strconv.Atoi(tz[2:4])
+		}
+		offset := hh*3600 + mm*60
+		if s[0] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+	return time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], 0, loc)
+}
+
+// parseXMPDate parses the ISO 8601 dates of XMP, which may stop after the year, month or day
+func parseXMPDate(s string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02T15:04:05", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
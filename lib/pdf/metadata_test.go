@@ -0,0 +1,61 @@
+package pdf
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want time.Time
+	}{
+		{"D:199812231952-08'00'", time.Date(1998, 12, 23, 19, 52, 0, 0, time.FixedZone("", -8*3600))},
+		{"D:20240301100000Z", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{"D:2001", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		if got := parseDate(c.in); !got.Equal(c.want) {
+			t.Errorf("parseDate(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseXMP(t *testing.T) {
+	props := parseXMP([]byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:pdf="http://ns.adobe.com/pdf/1.3/" pdf:Producer="p">
+<dc:title><rdf:Alt><rdf:li xml:lang="fr">Facture</rdf:li><rdf:li xml:lang="x-default">Invoice</rdf:li></rdf:Alt></dc:title>
+<dc:creator><rdf:Seq><rdf:li>Ana</rdf:li><rdf:li>Bo</rdf:li></rdf:Seq></dc:creator>
+</rdf:Description></rdf:RDF></x:xmpmeta>`))
+	for key, want := range map[string]string{nsDC + " title": "Invoice", nsDC + " creator": "Ana, Bo", nsPDF + " Producer": "p"} {
+		if props[key] != want {
+			t.Errorf("%s = %q, want %q", key, props[key], want)
+		}
+	}
+}
+
+func TestTextUTF8(t *testing.T) {
+	if got := (Value{data: "\xef\xbb\xbfd\xc3\xa4ta"}).Text(); got != "däta" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestMetadataCorruptStream(t *testing.T) {
+	// a FlateDecode stream without a valid zlib header
+	data := writePDF(0,
+		"<< /Type /Catalog /Pages 2 0 R /Metadata 3 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		"<< /Type /Metadata /Subtype /XML /Filter /FlateDecode /Length 8 >>\nstream\nnot zlib\nendstream",
+	)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := r.Metadata(); m.Title != "" {
+		t.Errorf("got %+v", m)
+	}
+	if _, err := io.ReadAll(r.Trailer().Key("Root").Key("Metadata").Reader()); err == nil {
+		t.Error("no error from the corrupt stream")
+	}
+}
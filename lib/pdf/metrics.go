@@ -0,0 +1,190 @@
+package pdf
+
+import "strings"
+
+// Glyph metrics (PDF 32000-1:2008, §9.2.4 and §9.7.4.3): the widths of simple fonts are in thousandths
+// of text space units, but those of Type 3 fonts, which the FontMatrix maps to text space, and composite
+// fonts give the horizontal widths of their CIDs (W, DW) and, written vertically, their vertical
+// displacements and position vectors (W2, DW2).
+
+// A glyph is a code shown with a font, with its text and its metrics for a font size of 1
+type glyph struct {
+	code   string
+	text   string
+	w0     float64 // horizontal displacement
+	w1     float64 // vertical displacement, negative downwards
+	vx, vy float64 // position vector from the horizontal to the vertical origin of the glyph
+}
+
+// A textFont is a font set up to show text: the encoding of its codes and the metrics of its glyphs
+type textFont struct {
+	Font
+	enc      TextEncoding
+	cids     *cidEncoder  // of a composite font
+	vertical bool         // whether a composite font is written top to bottom
+	scale    float64      // of the widths of a simple font to text space
+	widths   [256]float64 // of the codes of a simple font
+	w        map[int]float64
+	dw       float64
+	w2       map[int][3]float64 // w1, vx and vy of CIDs
+	dw2      [2]float64         // vy and w1 of the other CIDs
+}
+
+// A fontKey identifies a font by its resource name and the object holding it, as pages may give the same
+// name to other fonts
+type fontKey struct {
+	ptr  objptr
+	name string
+}
+
+// textFont returns the font of the page resources of that name, set up once per document
+func (p Page) textFont(name string) *textFont {
+	f := p.Font(name)
+	r := f.V.r
+	if r == nil {
+		return newTextFont(f)
+	}
+	key := fontKey{f.V.ptr, name}
+	r.mu.Lock()
+	t, ok := r.textFonts[key]
+	r.mu.Unlock()
+	if ok {
+		return t
+	}
+	t = newTextFont(f) // unlocked, it reads the font program
+	r.mu.Lock()
+	if r.textFonts == nil {
+		r.textFonts = map[fontKey]*textFont{}
+	}
+	r.textFonts[key] = t
+	r.mu.Unlock()
+	return t
+}
+
+func newTextFont(f Font) *textFont {
+	t := &textFont{Font: f, enc: f.Encoder(), scale: 0.001}
+	if t.enc == nil {
+		t.enc = &nopEncoder{}
+	}
+	if m := f.V.Key("FontMatrix"); f.V.Key("Subtype").Name() == "Type3" && m.Len() == 6 {
+		t.scale = m.Index(0).Float64()
+	}
+	e, ok := t.enc.(*cidEncoder)
+	if !ok {
+		t.readWidths()
+		return t
+	}
+	t.cids = e
+	desc := f.V.Key("DescendantFonts").Index(0)
+	t.dw = 1000
+	if dw := desc.Key("DW"); dw.Kind() == Integer || dw.Kind() == Real {
+		t.dw = dw.Float64()
+	}
+	t.w = map[int]float64{}
+	cidMetrics(desc.Key("W"), 1, func(cid int, v []float64) {
+		t.w[cid] = v[0]
+	})
+	if e.encoding.wmode != 1 {
+		return t
+	}
+	t.vertical = true
+	t.dw2 = [2]float64{880, -1000}
+	if dw2 := desc.Key("DW2"); dw2.Len() == 2 {
+		t.dw2 = [2]float64{dw2.Index(0).Float64(), dw2.Index(1).Float64()}
+	}
+	t.w2 = map[int][3]float64{}
+	cidMetrics(desc.Key("W2"), 3, func(cid int, v []float64) {
+		t.w2[cid] = [3]float64{v[0], v[1], v[2]}
+	})
+	return t
+}
+
+// cidMetrics reads a W or W2 array, of which the elements are either "c [m1 m2 ...]", giving the
+// metrics of consecutive CIDs from c, or "first last m", giving all the CIDs of a range the same ones.
+// There are n numbers per CID.
+func cidMetrics(w Value, n int, fn func(cid int, v []float64)) {
+	const maxRange = 1 << 16
+	values := func(a Value, from int) []float64 {
+		v := make([]float64, n)
+		for i := range v {
+			v[i] = a.Index(from + i).Float64()
+		}
+		return v
+	}
+	for i := 0; i < w.Len(); {
+		first := int(w.Index(i).Float64())
+		if next := w.Index(i + 1); next.Kind() == Array {
+			for j := 0; j+n <= next.Len(); j += n {
+				fn(first+j/n, values(next, j))
+			}
+			i += 2
+		} else if i+2+n <= w.Len() {
+			last := int(next.Float64())
+			v := values(w, i+2)
+			for cid := max(first, 0); cid <= last && cid-first < maxRange; cid++ {
+				fn(cid, v)
+			}
+			i += 2 + n
+		} else {
+			break
+		}
+	}
+}
+
+// glyphs splits a string shown with the font into its glyphs
+func (f *textFont) glyphs(raw string) []glyph {
+	var glyphs []glyph
+	for len(raw) > 0 {
+		var g glyph
+		if f.cids != nil {
+			g.code, _ = f.cids.encoding.nextCode(raw)
+			g.text = f.cids.text(g.code)
+			cid, _ := f.cids.encoding.cid(g.code)
+			w, ok := f.w[cid]
+			if !ok {
+				w = f.dw
+			}
+			g.w0 = w / 1000
+			if f.vertical {
+				v, ok := f.w2[cid]
+				if !ok {
+					v = [3]float64{f.dw2[1], w / 2, f.dw2[0]}
+				}
+				g.w1, g.vx, g.vy = v[0]/1000, v[1]/1000, v[2]/1000
+			}
+		} else {
+			g.code = raw[:1]
+			g.text = f.enc.Decode(g.code)
+			g.w0 = f.widths[raw[0]] * f.scale
+		}
+		raw = raw[len(g.code):]
+		glyphs = append(glyphs, g)
+	}
+	return glyphs
+}
+
+// readWidths reads the widths of the codes of a simple font, the MissingWidth of the font descriptor for
+// the codes without one. The standard fonts may have no Widths, nor descriptor: their glyphs are given
+// the average width of the font, or a half em, and those of Courier the 600 units of all its glyphs.
+func (f *textFont) readWidths() {
+	desc := f.V.Key("FontDescriptor")
+	missing := desc.Key("MissingWidth").Float64()
+	widths := f.V.Key("Widths")
+	if widths.Len() == 0 && missing == 0 && f.V.Key("Subtype").Name() != "Type3" {
+		missing = desc.Key("AvgWidth").Float64()
+		if strings.HasPrefix(f.BaseFont(), "Courier") {
+			missing = 600
+		} else if missing == 0 {
+			missing = 500
+		}
+	}
+	first, last := f.FirstChar(), f.LastChar()
+	for code := range f.widths {
+		f.widths[code] = missing
+		if code >= first && code <= last {
+			if w := widths.Index(code - first); w.Kind() == Integer || w.Kind() == Real {
+				f.widths[code] = w.Float64()
+			}
+		}
+	}
+}
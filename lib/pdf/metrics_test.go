@@ -0,0 +1,32 @@
+package pdf
+
+import "testing"
+
+func TestCIDMetrics(t *testing.T) {
+	w := Value{data: array{int64(1), array{int64(500), 600.0}, int64(10), int64(12), int64(250)}}
+	got := map[int]float64{}
+	cidMetrics(w, 1, func(cid int, v []float64) {
+		got[cid] = v[0]
+	})
+	want := map[int]float64{1: 500, 2: 600, 10: 250, 11: 250, 12: 250}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for cid, v := range want {
+		if got[cid] != v {
+			t.Errorf("CID %d: got %v, want %v", cid, got[cid], v)
+		}
+	}
+
+	w2 := Value{data: array{int64(5), int64(6), int64(-1000), int64(500), int64(880)}}
+	n := 0
+	cidMetrics(w2, 3, func(cid int, v []float64) {
+		if v[0] != -1000 || v[1] != 500 || v[2] != 880 {
+			t.Errorf("CID %d: got %v", cid, v)
+		}
+		n++
+	})
+	if n != 2 {
+		t.Errorf("got %d CIDs, want 2", n)
+	}
+}
@@ -278,6 +278,7 @@ var nameToRune = map[string]rune{
 	"guilsinglleft":                  0x2039,
 	"guilsinglright":                 0x203A,
 	"Euro":                           0x20AC,
+	"euro":                           0x20AC,
 	"controlSTX":                     0x0001,
 	"controlSOT":                     0x0002,
 	"controlETX":                     0x0003,
@@ -4284,3 +4285,294 @@ var nameToRune = map[string]rune{
 	"yenmonospace":                        0xFFE5,
 	"wonmonospace":                        0xFFE6,
 }
+
+// Names of the Adobe Glyph List (https://github.com/adobe-type-tools/agl-aglfn) which stand for
+// several characters
+var nameToText = map[string]string{
+	"dalethatafpatah":          "\u05D3\u05B2",
+	"dalethatafpatahhebrew":    "\u05D3\u05B2",
+	"dalethatafsegol":          "\u05D3\u05B1",
+	"dalethatafsegolhebrew":    "\u05D3\u05B1",
+	"dalethiriq":               "\u05D3\u05B4",
+	"dalethiriqhebrew":         "\u05D3\u05B4",
+	"daletholam":               "\u05D3\u05B9",
+	"daletholamhebrew":         "\u05D3\u05B9",
+	"daletpatah":               "\u05D3\u05B7",
+	"daletpatahhebrew":         "\u05D3\u05B7",
+	"daletqamats":              "\u05D3\u05B8",
+	"daletqamatshebrew":        "\u05D3\u05B8",
+	"daletqubuts":              "\u05D3\u05BB",
+	"daletqubutshebrew":        "\u05D3\u05BB",
+	"daletsegol":               "\u05D3\u05B6",
+	"daletsegolhebrew":         "\u05D3\u05B6",
+	"daletsheva":               "\u05D3\u05B0",
+	"daletshevahebrew":         "\u05D3\u05B0",
+	"dalettsere":               "\u05D3\u05B5",
+	"dalettserehebrew":         "\u05D3\u05B5",
+	"finalkafqamats":           "\u05DA\u05B8",
+	"finalkafqamatshebrew":     "\u05DA\u05B8",
+	"finalkafsheva":            "\u05DA\u05B0",
+	"finalkafshevahebrew":      "\u05DA\u05B0",
+	"hamzadammaarabic":         "\u0621\u064F",
+	"hamzadammatanarabic":      "\u0621\u064C",
+	"hamzafathaarabic":         "\u0621\u064E",
+	"hamzafathatanarabic":      "\u0621\u064B",
+	"hamzalowkasraarabic":      "\u0621\u0650",
+	"hamzalowkasratanarabic":   "\u0621\u064D",
+	"hamzasukunarabic":         "\u0621\u0652",
+	"lamedholam":               "\u05DC\u05B9",
+	"lamedholamdagesh":         "\u05DC\u05B9\u05BC",
+	"lamedholamdageshhebrew":   "\u05DC\u05B9\u05BC",
+	"lamedholamhebrew":         "\u05DC\u05B9",
+	"lammeemjeeminitialarabic": "\uFEDF\uFEE4\uFEA0",
+	"lammeemkhahinitialarabic": "\uFEDF\uFEE4\uFEA8",
+	"noonhehinitialarabic":     "\uFEE7\uFEEC",
+	"qofhatafpatah":            "\u05E7\u05B2",
+	"qofhatafpatahhebrew":      "\u05E7\u05B2",
+	"qofhatafsegol":            "\u05E7\u05B1",
+	"qofhatafsegolhebrew":      "\u05E7\u05B1",
+	"qofhiriq":                 "\u05E7\u05B4",
+	"qofhiriqhebrew":           "\u05E7\u05B4",
+	"qofholam":                 "\u05E7\u05B9",
+	"qofholamhebrew":           "\u05E7\u05B9",
+	"qofpatah":                 "\u05E7\u05B7",
+	"qofpatahhebrew":           "\u05E7\u05B7",
+	"qofqamats":                "\u05E7\u05B8",
+	"qofqamatshebrew":          "\u05E7\u05B8",
+	"qofqubuts":                "\u05E7\u05BB",
+	"qofqubutshebrew":          "\u05E7\u05BB",
+	"qofsegol":                 "\u05E7\u05B6",
+	"qofsegolhebrew":           "\u05E7\u05B6",
+	"qofsheva":                 "\u05E7\u05B0",
+	"qofshevahebrew":           "\u05E7\u05B0",
+	"qoftsere":                 "\u05E7\u05B5",
+	"qoftserehebrew":           "\u05E7\u05B5",
+	"rehyehaleflamarabic":      "\u0631\uFEF3\uFE8E\u0644",
+	"reshhatafpatah":           "\u05E8\u05B2",
+	"reshhatafpatahhebrew":     "\u05E8\u05B2",
+	"reshhatafsegol":           "\u05E8\u05B1",
+	"reshhatafsegolhebrew":     "\u05E8\u05B1",
+	"reshhiriq":                "\u05E8\u05B4",
+	"reshhiriqhebrew":          "\u05E8\u05B4",
+	"reshholam":                "\u05E8\u05B9",
+	"reshholamhebrew":          "\u05E8\u05B9",
+	"reshpatah":                "\u05E8\u05B7",
+	"reshpatahhebrew":          "\u05E8\u05B7",
+	"reshqamats":               "\u05E8\u05B8",
+	"reshqamatshebrew":         "\u05E8\u05B8",
+	"reshqubuts":               "\u05E8\u05BB",
+	"reshqubutshebrew":         "\u05E8\u05BB",
+	"reshsegol":                "\u05E8\u05B6",
+	"reshsegolhebrew":          "\u05E8\u05B6",
+	"reshsheva":                "\u05E8\u05B0",
+	"reshshevahebrew":          "\u05E8\u05B0",
+	"reshtsere":                "\u05E8\u05B5",
+	"reshtserehebrew":          "\u05E8\u05B5",
+	"shaddafathatanarabic":     "\u0651\u064B",
+	"tchehmeeminitialarabic":   "\uFB7C\uFEE4",
+}
+
+// Glyph names of the ZapfDingbats font, from the ITC Zapf Dingbats Glyph List
+var zapfNameToRune = map[string]rune{
+	"a1":   0x2701,
+	"a2":   0x2702,
+	"a3":   0x2704,
+	"a4":   0x260E,
+	"a5":   0x2706,
+	"a6":   0x271D,
+	"a7":   0x271E,
+	"a8":   0x271F,
+	"a9":   0x2720,
+	"a10":  0x2721,
+	"a11":  0x261B,
+	"a12":  0x261E,
+	"a13":  0x270C,
+	"a14":  0x270D,
+	"a15":  0x270E,
+	"a16":  0x270F,
+	"a17":  0x2711,
+	"a18":  0x2712,
+	"a19":  0x2713,
+	"a20":  0x2714,
+	"a21":  0x2715,
+	"a22":  0x2716,
+	"a23":  0x2717,
+	"a24":  0x2718,
+	"a25":  0x2719,
+	"a26":  0x271A,
+	"a27":  0x271B,
+	"a28":  0x271C,
+	"a29":  0x2722,
+	"a30":  0x2723,
+	"a31":  0x2724,
+	"a32":  0x2725,
+	"a33":  0x2726,
+	"a34":  0x2727,
+	"a35":  0x2605,
+	"a36":  0x2729,
+	"a37":  0x272A,
+	"a38":  0x272B,
+	"a39":  0x272C,
+	"a40":  0x272D,
+	"a41":  0x272E,
+	"a42":  0x272F,
+	"a43":  0x2730,
+	"a44":  0x2731,
+	"a45":  0x2732,
+	"a46":  0x2733,
+	"a47":  0x2734,
+	"a48":  0x2735,
+	"a49":  0x2736,
+	"a50":  0x2737,
+	"a51":  0x2738,
+	"a52":  0x2739,
+	"a53":  0x273A,
+	"a54":  0x273B,
+	"a55":  0x273C,
+	"a56":  0x273D,
+	"a57":  0x273E,
+	"a58":  0x273F,
+	"a59":  0x2740,
+	"a60":  0x2741,
+	"a61":  0x2742,
+	"a62":  0x2743,
+	"a63":  0x2744,
+	"a64":  0x2745,
+	"a65":  0x2746,
+	"a66":  0x2747,
+	"a67":  0x2748,
+	"a68":  0x2749,
+	"a69":  0x274A,
+	"a70":  0x274B,
+	"a71":  0x25CF,
+	"a72":  0x274D,
+	"a73":  0x25A0,
+	"a74":  0x274F,
+	"a75":  0x2751,
+	"a76":  0x25B2,
+	"a77":  0x25BC,
+	"a78":  0x25C6,
+	"a79":  0x2756,
+	"a81":  0x25D7,
+	"a82":  0x2758,
+	"a83":  0x2759,
+	"a84":  0x275A,
+	"a85":  0x276F,
+	"a86":  0x2771,
+	"a87":  0x2772,
+	"a88":  0x2773,
+	"a89":  0x2768,
+	"a90":  0x2769,
+	"a91":  0x276C,
+	"a92":  0x276D,
+	"a93":  0x276A,
+	"a94":  0x276B,
+	"a95":  0x2774,
+	"a96":  0x2775,
+	"a97":  0x275B,
+	"a98":  0x275C,
+	"a99":  0x275D,
+	"a100": 0x275E,
+	"a101": 0x2761,
+	"a102": 0x2762,
+	"a103": 0x2763,
+	"a104": 0x2764,
+	"a105": 0x2710,
+	"a106": 0x2765,
+	"a107": 0x2766,
+	"a108": 0x2767,
+	"a109": 0x2660,
+	"a110": 0x2665,
+	"a111": 0x2666,
+	"a112": 0x2663,
+	"a117": 0x2709,
+	"a118": 0x2708,
+	"a119": 0x2707,
+	"a120": 0x2460,
+	"a121": 0x2461,
+	"a122": 0x2462,
+	"a123": 0x2463,
+	"a124": 0x2464,
+	"a125": 0x2465,
+	"a126": 0x2466,
+	"a127": 0x2467,
+	"a128": 0x2468,
+	"a129": 0x2469,
+	"a130": 0x2776,
+	"a131": 0x2777,
+	"a132": 0x2778,
+	"a133": 0x2779,
+	"a134": 0x277A,
+	"a135": 0x277B,
+	"a136": 0x277C,
+	"a137": 0x277D,
+	"a138": 0x277E,
+	"a139": 0x277F,
+	"a140": 0x2780,
+	"a141": 0x2781,
+	"a142": 0x2782,
+	"a143": 0x2783,
+	"a144": 0x2784,
+	"a145": 0x2785,
+	"a146": 0x2786,
+	"a147": 0x2787,
+	"a148": 0x2788,
+	"a149": 0x2789,
+	"a150": 0x278A,
+	"a151": 0x278B,
+	"a152": 0x278C,
+	"a153": 0x278D,
+	"a154": 0x278E,
+	"a155": 0x278F,
+	"a156": 0x2790,
+	"a157": 0x2791,
+	"a158": 0x2792,
+	"a159": 0x2793,
+	"a160": 0x2794,
+	"a161": 0x2192,
+	"a162": 0x27A3,
+	"a163": 0x2194,
+	"a164": 0x2195,
+	"a165": 0x2799,
+	"a166": 0x279B,
+	"a167": 0x279C,
+	"a168": 0x279D,
+	"a169": 0x279E,
+	"a170": 0x279F,
+	"a171": 0x27A0,
+	"a172": 0x27A1,
+	"a173": 0x27A2,
+	"a174": 0x27A4,
+	"a175": 0x27A5,
+	"a176": 0x27A6,
+	"a177": 0x27A7,
+	"a178": 0x27A8,
+	"a179": 0x27A9,
+	"a180": 0x27AB,
+	"a181": 0x27AD,
+	"a182": 0x27AF,
+	"a183": 0x27B2,
+	"a184": 0x27B3,
+	"a185": 0x27B5,
+	"a186": 0x27B8,
+	"a187": 0x27BA,
+	"a188": 0x27BB,
+	"a189": 0x27BC,
+	"a190": 0x27BD,
+	"a191": 0x27BE,
+	"a192": 0x279A,
+	"a193": 0x27AA,
+	"a194": 0x27B6,
+	"a195": 0x27B9,
+	"a196": 0x2798,
+	"a197": 0x27B4,
+	"a198": 0x27B7,
+	"a199": 0x27AC,
+	"a200": 0x27AE,
+	"a201": 0x27B1,
+	"a202": 0x2703,
+	"a203": 0x2750,
+	"a204": 0x2752,
+	"a205": 0x276E,
+	"a206": 0x2770,
+}
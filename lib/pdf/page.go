@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"sort"
 	"strings"
 )
@@ -22,7 +23,7 @@ func (r *Reader) Page(num int) Page {
 	num-- // now 0-indexed
 	page := r.Trailer().Key("Root").Key("Pages")
 Search:
-	for page.Key("Type").Name() == "Pages" {
+	for pageNodeType(page) == "Pages" {
 		count := int(page.Key("Count").Int64())
 		if count < num {
 			return Page{}
@@ -30,16 +31,15 @@ Search:
 		kids := page.Key("Kids")
 		for i := 0; i < kids.Len(); i++ {
 			kid := kids.Index(i)
-			if kid.Key("Type").Name() == "Pages" {
+			switch pageNodeType(kid) {
+			case "Pages":
 				c := int(kid.Key("Count").Int64())
 				if num < c {
 					page = kid
 					continue Search
 				}
 				num -= c
-				continue
-			}
-			if kid.Key("Type").Name() == "Page" {
+			case "Page":
 				if num == 0 {
 					return Page{kid}
 				}
@@ -51,6 +51,20 @@ Search:
 	return Page{}
 }
 
+// pageNodeType returns the type of a node of the page tree, Pages or Page, or "" for the other objects.
+// Nodes without Type, which some writers omit, are told apart by their Kids.
+func pageNodeType(node Value) string {
+	switch t := node.Key("Type").Name(); {
+	case t == "Pages" || t == "Page":
+		return t
+	case t != "" || node.Kind() != Dict:
+		return ""
+	case node.Key("Kids").Kind() == Array:
+		return "Pages"
+	}
+	return "Page"
+}
+
 // NumPage returns the number of pages in the PDF file.
 func (r *Reader) NumPage() int {
 	return int(r.Trailer().Key("Root").Key("Pages").Key("Count").Int64())
@@ -60,14 +74,18 @@ func (r *Reader) NumPage() int {
 func (r *Reader) GetPlainText() (reader io.Reader, err error) {
 	pages := r.NumPage()
 	var buf bytes.Buffer
-	fonts := make(map[string]*Font)
+	// cache fonts so we don't continually parse charmap
+	cache := make(map[fontKey]*Font)
 	for i := 1; i <= pages; i++ {
 		p := r.Page(i)
-		for _, name := range p.Fonts() { // cache fonts so we don't continually parse charmap
-			if _, ok := fonts[name]; !ok {
-				f := p.Font(name)
-				fonts[name] = &f
+		fonts := make(map[string]*Font)
+		for _, name := range p.Fonts() {
+			f := p.Font(name)
+			key := fontKey{f.V.ptr, name}
+			if _, ok := cache[key]; !ok {
+				cache[key] = &f
 			}
+			fonts[name] = cache[key]
 		}
 		text, _ := p.GetPlainText(fonts)
 		// if err != nil {
@@ -164,26 +182,24 @@ func (f Font) Encoder() TextEncoding {
 }
 
 func (f Font) getEncoder() TextEncoding {
+	if f.V.Key("Subtype").Name() == "Type0" {
+		return f.cidEncoding()
+	}
 	enc := f.V.Key("Encoding")
 	switch enc.Kind() {
 	case Name:
 		switch enc.Name() {
-		case "WinAnsiEncoding":
-			return &byteEncoder{&winAnsiEncoding}
-		case "MacRomanEncoding":
-			return &byteEncoder{&macRomanEncoding}
+		case "WinAnsiEncoding", "MacRomanEncoding", "StandardEncoding":
 		case "Identity-H":
 			return f.charmapEncoding()
 		default:
 			if DebugOn {
 				println("unknown encoding", enc.Name())
 			}
-			return &nopEncoder{}
 		}
-	case Dict:
-		return &dictEncoder{enc.Key("Differences")}
-	case Null:
-		return f.charmapEncoding()
+		return f.simpleEncoding()
+	case Dict, Null:
+		return f.simpleEncoding()
 	default:
 		if DebugOn {
 			println("unexpected encoding", enc.String())
@@ -205,35 +221,116 @@ func (f *Font) charmapEncoding() TextEncoding {
 	return &byteEncoder{&pdfDocEncoding}
 }
 
-type dictEncoder struct {
-	v Value
+// simpleEncoding returns the encoding of a simple font, whose codes are single bytes. Their text comes
+// from the ToUnicode CMap of the font, else from the glyph names of the Differences of its encoding,
+// else from the base encoding: the one named, else the built-in encoding of the embedded font program,
+// else that of the standard fonts (StandardEncoding for the Latin Type 1 fonts).
+func (f Font) simpleEncoding() TextEncoding {
+	e := &simpleEncoder{}
+	if toUnicode := f.V.Key("ToUnicode"); toUnicode.Kind() == Stream {
+		e.toUnicode = readCmap(toUnicode)
+	}
+	var program *fontProgram
+	programRead := false
+	readProgram := func() *fontProgram {
+		if !programRead && f.V.r != nil {
+			program, programRead = f.V.r.fontProgram(f.V.Key("FontDescriptor")), true
+		}
+		return program
+	}
+	name := f.BaseFont()
+	if i := strings.IndexByte(name, '+'); i == 6 {
+		name = name[i+1:] // subset tag
+	}
+	zapf := strings.HasPrefix(name, "ZapfDingbats") || strings.HasPrefix(name, "Dingbats")
+
+	enc := f.V.Key("Encoding")
+	base := enc
+	if enc.Kind() == Dict {
+		base = enc.Key("BaseEncoding")
+	}
+	var table *[256]rune
+	switch base.Name() {
+	case "WinAnsiEncoding":
+		table = &winAnsiEncoding
+	case "MacRomanEncoding":
+		table = &macRomanEncoding
+	case "StandardEncoding":
+		table = &standardEncoding
+	}
+	var builtin [256]string
+	if table == nil {
+		switch {
+		case strings.HasPrefix(name, "Symbol"):
+			table = &symbolEncoding
+		case zapf:
+			table = &zapfDingbatsEncoding
+		case f.V.Key("Subtype").Name() == "Type1" || f.V.Key("Subtype").Name() == "MMType1":
+			table = &standardEncoding
+		default:
+			table = &pdfDocEncoding
+		}
+		if p := readProgram(); p != nil {
+			builtin = p.builtin
+		}
+	}
+	for code := range e.text {
+		switch {
+		case builtin[code] != "":
+			e.text[code] = builtin[code]
+		case table[code] == noRune && (code == '\t' || code == '\n' || code == '\r'):
+			e.text[code] = string(rune(code)) // as in PDFDocEncoding
+		default:
+			e.text[code] = string(table[code])
+		}
+	}
+
+	if enc.Kind() == Dict {
+		diff := enc.Key("Differences")
+		code := -1
+		for i := 0; i < diff.Len(); i++ {
+			switch x := diff.Index(i); x.Kind() {
+			case Integer:
+				code = int(x.Int64())
+			case Name:
+				if code >= 0 && code < len(e.text) {
+					s := glyphNameText(x.Name(), zapf)
+					if p := readProgram(); s == "" && p != nil {
+						s = p.nameText(x.Name())
+					}
+					if s != "" {
+						e.text[code] = s
+					}
+				}
+				code++
+			}
+		}
+	}
+	return e
+}
+
+type simpleEncoder struct {
+	toUnicode *cmap
+	text      [256]string
 }
 
-func (e *dictEncoder) Decode(raw string) (text string) {
-	r := make([]rune, 0, len(raw))
+func (e *simpleEncoder) Decode(raw string) (text string) {
+	var b strings.Builder
 	for i := 0; i < len(raw); i++ {
-		ch := rune(raw[i])
-		n := -1
-		for j := 0; j < e.v.Len(); j++ {
-			x := e.v.Index(j)
-			if x.Kind() == Integer {
-				n = int(x.Int64())
+		if e.toUnicode != nil {
+			// some writers give the codes of simple fonts on two bytes
+			if s, ok := e.toUnicode.unicode(raw[i : i+1]); ok {
+				b.WriteString(s)
 				continue
 			}
-			if x.Kind() == Name {
-				if int(raw[i]) == n {
-					r := nameToRune[x.Name()]
-					if r != 0 {
-						ch = r
-						break
-					}
-				}
-				n++
+			if s, ok := e.toUnicode.unicode("\x00" + raw[i:i+1]); ok {
+				b.WriteString(s)
+				continue
 			}
 		}
-		r = append(r, ch)
+		b.WriteString(e.text[raw[i]])
 	}
-	return string(r)
+	return b.String()
 }
 
 // A TextEncoding represents a mapping between
@@ -268,9 +365,15 @@ type byteRange struct {
 	high string
 }
 
-type bfchar struct {
-	orig string
-	repl string
+// contains tells whether each byte of code lies between those of the range: codespace ranges are
+// rectangles, <8140> <9FFC> holding the codes of first byte 81 to 9F and second byte 40 to FC
+func (r byteRange) contains(code string) bool {
+	for i := 0; i < len(code); i++ {
+		if code[i] < r.low[i] || code[i] > r.high[i] {
+			return false
+		}
+	}
+	return true
 }
 
 type bfrange struct {
@@ -279,78 +382,185 @@ type bfrange struct {
 	dst Value
 }
 
+// a cidrange maps the codes from lo to hi to consecutive CIDs, a cidchar being a range of one code
+type cidrange struct {
+	lo  string
+	hi  string
+	cid int
+}
+
 type cmap struct {
-	space   [4][]byteRange // codespace range
-	bfrange []bfrange
-	bfchar  []bfchar
+	space    [4][]byteRange // codespace range
+	bfchar   map[string]string
+	bfrange  []bfrange  // sorted by length and low code
+	cidrange []cidrange // sorted by length and low code
+	notdef   []cidrange
+	usecmap  *cmap  // CMap whose mappings this one extends
+	wmode    int    // 1 for vertical writing
+	ordering string // character collection of the CIDs, as "Adobe-Japan1"
+	form     string // UCS2, UTF16, UTF8 or UTF32 for the CMaps whose codes are Unicode text
 }
 
-func (m *cmap) Decode(raw string) (text string) {
-	var r []rune
-Parse:
-	for len(raw) > 0 {
-		for n := 1; n <= 4 && n <= len(raw); n++ { // number of digits in character replacement (1-4 possible)
-			for _, space := range m.space[n-1] { // find matching codespace Ranges for number of digits
-				if space.low <= raw[:n] && raw[:n] <= space.high { // see if value is in range
-					text := raw[:n]
-					raw = raw[n:]
-					for _, bfchar := range m.bfchar { // check for matching bfchar
-						if len(bfchar.orig) == n && bfchar.orig == text {
-							r = append(r, []rune(utf16Decode(bfchar.repl))...)
-							continue Parse
-						}
-					}
-					for _, bfrange := range m.bfrange { // check for matching bfrange
-						if len(bfrange.lo) == n && bfrange.lo <= text && text <= bfrange.hi {
-							if bfrange.dst.Kind() == String {
-								s := bfrange.dst.RawString()
-								if bfrange.lo != text { // value isn't at the beginning of the range so scale result
-									b := []byte(s)
-									b[len(b)-1] += text[len(text)-1] - bfrange.lo[len(bfrange.lo)-1] // increment last byte by difference
-									s = string(b)
-								}
-								r = append(r, []rune(utf16Decode(s))...)
-								continue Parse
-							}
-							if bfrange.dst.Kind() == Array {
-								n := text[len(text)-1] - bfrange.lo[len(bfrange.lo)-1]
-								v := bfrange.dst.Index(int(n))
-								if v.Kind() == String {
-									s := v.RawString()
-									r = append(r, []rune(utf16Decode(s))...)
-									continue Parse
-								}
-								if DebugOn {
-									fmt.Printf("array %v\n", bfrange.dst)
-								}
-							} else {
-								if DebugOn {
-									fmt.Printf("unknown dst %v\n", bfrange.dst)
-								}
-							}
-							r = append(r, noRune)
-							continue Parse
-						}
-					}
-					r = append(r, noRune)
-					continue Parse
+// nextCode splits the first code off raw along the codespace ranges. Bytes outside of them are
+// skipped one at a time, with ok false.
+func (m *cmap) nextCode(raw string) (code string, ok bool) {
+	for n := 1; n <= 4 && n <= len(raw); n++ {
+		for c := m; c != nil; c = c.usecmap {
+			for _, space := range c.space[n-1] {
+				if space.contains(raw[:n]) {
+					return raw[:n], true
 				}
 			}
 		}
-		if DebugOn {
-			println("no code space found")
+	}
+	if DebugOn {
+		println("no code space found")
+	}
+	return raw[:1], false
+}
+
+// unicode returns the text of a code, from the bfchar and bfrange mappings of a ToUnicode CMap or
+// from the code itself for the CMaps of Unicode encoding forms
+func (m *cmap) unicode(code string) (string, bool) {
+	for c := m; c != nil; c = c.usecmap {
+		if c.form != "" {
+			return decodeForm(c.form, code)
+		}
+		if repl, ok := c.bfchar[code]; ok {
+			return utf16Text(repl), true
+		}
+		// the ranges are sorted, overlapping ones being only looked for in small CMaps
+		ranges := c.bfrange
+		i := sort.Search(len(ranges), func(i int) bool {
+			return len(ranges[i].lo) > len(code) || len(ranges[i].lo) == len(code) && ranges[i].lo > code
+		})
+		if i > 0 && len(ranges[i-1].lo) == len(code) && code <= ranges[i-1].hi {
+			ranges = ranges[i-1 : i]
+		} else if len(ranges) > 64 {
+			ranges = nil
+		}
+		for _, r := range ranges {
+			if len(r.lo) != len(code) || code < r.lo || code > r.hi {
+				continue
+			}
+			offset := codeValue(code) - codeValue(r.lo)
+			switch r.dst.Kind() {
+			case String:
+				// the last byte of the destination string is incremented, with a carry for the writers
+				// which let it overflow
+				b := []byte(r.dst.RawString())
+				for i := len(b) - 1; i >= 0 && offset > 0; i-- {
+					v := int(b[i]) + offset
+					b[i], offset = byte(v), v>>8
+				}
+				return utf16Text(string(b)), true
+			case Array:
+				if v := r.dst.Index(offset); v.Kind() == String {
+					return utf16Text(v.RawString()), true
+				}
+			}
+			if DebugOn {
+				fmt.Printf("unknown dst %v\n", r.dst)
+			}
+			return "", false
 		}
-		r = append(r, noRune)
-		raw = raw[1:]
+	}
+	return "", false
+}
+
+// cid returns the CID of a code, from the cidchar and cidrange mappings, else the notdefrange ones
+func (m *cmap) cid(code string) (int, bool) {
+	for c := m; c != nil; c = c.usecmap {
+		ranges := c.cidrange
+		i := sort.Search(len(ranges), func(i int) bool {
+			return len(ranges[i].lo) > len(code) || len(ranges[i].lo) == len(code) && ranges[i].lo > code
+		})
+		if i > 0 && len(ranges[i-1].lo) == len(code) && code <= ranges[i-1].hi {
+			return ranges[i-1].cid + codeValue(code) - codeValue(ranges[i-1].lo), true
+		}
+		if c.form != "" {
+			if text, ok := decodeForm(c.form, code); ok {
+				if cid, ok := collectionCID(c.ordering, text); ok {
+					return cid, true
+				}
+			}
+		}
+	}
+	for c := m; c != nil; c = c.usecmap {
+		for _, r := range c.notdef {
+			if len(r.lo) == len(code) && r.lo <= code && code <= r.hi {
+				return r.cid, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (m *cmap) Decode(raw string) (text string) {
+	var r []rune
+	for len(raw) > 0 {
+		code, ok := m.nextCode(raw)
+		raw = raw[len(code):]
+		s, found := m.unicode(code)
+		if !ok || !found {
+			r = append(r, noRune)
+			continue
+		}
+		r = append(r, []rune(s)...)
 	}
 	return string(r)
 }
 
+// codeValue is the number of a code, its bytes being big-endian
+func codeValue(code string) int {
+	v := 0
+	for i := 0; i < len(code); i++ {
+		v = v<<8 | int(code[i])
+	}
+	return v
+}
+
+// utf16Text decodes the UTF-16BE destination of a bfchar or bfrange mapping (of which a few writers
+// give a single byte)
+func utf16Text(s string) string {
+	if len(s)%2 == 1 {
+		s = "\x00" + s
+	}
+	return utf16Decode(s)
+}
+
 func readCmap(toUnicode Value) *cmap {
+	m := parseCmap(toUnicode.Reader())
+	if m == nil {
+		return nil
+	}
+	// embedded CMaps give their parameters in the stream dictionary as well
+	if m.wmode == 0 {
+		m.wmode = int(toUnicode.Key("WMode").Int64())
+	}
+	if m.ordering == "" {
+		m.ordering = collectionName(toUnicode.Key("CIDSystemInfo"))
+	}
+	if m.usecmap == nil {
+		switch use := toUnicode.Key("UseCMap"); use.Kind() {
+		case Name:
+			m.usecmap = predefinedCMap(use.Name())
+		case Stream:
+			if use.Key("UseCMap").Kind() == Null {
+				m.usecmap = readCmap(use)
+			}
+		}
+	}
+	return m
+}
+
+// parseCmap interprets the PostScript program of a CMap (Adobe Technical Note #5014, CMap and CIDFont
+// files specification)
+func parseCmap(rd io.Reader) *cmap {
 	n := -1
-	var m cmap
+	m := cmap{bfchar: map[string]string{}}
 	ok := true
-	Interpret(toUnicode, func(stk *Stack, op string) {
+	interpret(rd, func(stk *Stack, op string) {
 		if !ok {
 			return
 		}
@@ -363,6 +573,8 @@ func readCmap(toUnicode Value) *cmap {
 			stk.Push(newDict())
 		case "endcmap":
 			stk.Pop()
+		case "usecmap":
+			m.usecmap = predefinedCMap(stk.Pop().Name())
 		case "begincodespacerange":
 			n = int(stk.Pop().Int64())
 		case "endcodespacerange":
@@ -375,7 +587,7 @@ func readCmap(toUnicode Value) *cmap {
 			}
 			for i := 0; i < n; i++ {
 				hi, lo := stk.Pop().RawString(), stk.Pop().RawString()
-				if len(lo) == 0 || len(lo) != len(hi) {
+				if len(lo) == 0 || len(lo) > 4 || len(lo) != len(hi) {
 					if DebugOn {
 						println("bad codespace range")
 					}
@@ -385,7 +597,7 @@ func readCmap(toUnicode Value) *cmap {
 				m.space[len(lo)-1] = append(m.space[len(lo)-1], byteRange{lo, hi})
 			}
 			n = -1
-		case "beginbfchar":
+		case "beginbfchar", "beginbfrange", "begincidchar", "begincidrange", "beginnotdefchar", "beginnotdefrange":
 			n = int(stk.Pop().Int64())
 		case "endbfchar":
 			if n < 0 {
@@ -393,10 +605,10 @@ func readCmap(toUnicode Value) *cmap {
 			}
 			for i := 0; i < n; i++ {
 				repl, orig := stk.Pop().RawString(), stk.Pop().RawString()
-				m.bfchar = append(m.bfchar, bfchar{orig, repl})
+				if _, dup := m.bfchar[orig]; !dup {
+					m.bfchar[orig] = repl
+				}
 			}
-		case "beginbfrange":
-			n = int(stk.Pop().Int64())
 		case "endbfrange":
 			if n < 0 {
 				panic("missing beginbfrange")
@@ -405,11 +617,36 @@ func readCmap(toUnicode Value) *cmap {
 				dst, srcHi, srcLo := stk.Pop(), stk.Pop().RawString(), stk.Pop().RawString()
 				m.bfrange = append(m.bfrange, bfrange{srcLo, srcHi, dst})
 			}
+		case "endcidchar", "endnotdefchar":
+			for i := 0; i < n; i++ {
+				cid, code := stk.Pop().Int64(), stk.Pop().RawString()
+				r := cidrange{code, code, int(cid)}
+				if op == "endcidchar" {
+					m.cidrange = append(m.cidrange, r)
+				} else {
+					m.notdef = append(m.notdef, r)
+				}
+			}
+		case "endcidrange", "endnotdefrange":
+			for i := 0; i < n; i++ {
+				cid, hi, lo := stk.Pop().Int64(), stk.Pop().RawString(), stk.Pop().RawString()
+				if len(lo) != len(hi) {
+					continue
+				}
+				r := cidrange{lo, hi, int(cid)}
+				if op == "endcidrange" {
+					m.cidrange = append(m.cidrange, r)
+				} else {
+					m.notdef = append(m.notdef, r)
+				}
+			}
 		case "defineresource":
 			stk.Pop().Name() // category
 			value := stk.Pop()
 			stk.Pop().Name() // key
 			stk.Push(value)
+			m.wmode = int(value.Key("WMode").Int64())
+			m.ordering = collectionName(value.Key("CIDSystemInfo"))
 		default:
 			if DebugOn {
 				println("interp\t", op)
@@ -419,9 +656,36 @@ func readCmap(toUnicode Value) *cmap {
 	if !ok {
 		return nil
 	}
+	sort.SliceStable(m.cidrange, func(i, j int) bool {
+		a, b := m.cidrange[i], m.cidrange[j]
+		return len(a.lo) < len(b.lo) || len(a.lo) == len(b.lo) && a.lo < b.lo
+	})
+	sort.SliceStable(m.bfrange, func(i, j int) bool {
+		a, b := m.bfrange[i], m.bfrange[j]
+		return len(a.lo) < len(b.lo) || len(a.lo) == len(b.lo) && a.lo < b.lo
+	})
+	// some writers leave out the codespace ranges of ToUnicode CMaps: the lengths of the mapped codes tell them
+	if !m.hasSpace() {
+		for orig := range m.bfchar {
+			m.addFullSpace(len(orig))
+		}
+		for _, r := range m.bfrange {
+			m.addFullSpace(len(r.lo))
+		}
+	}
 	return &m
 }
 
+func (m *cmap) hasSpace() bool {
+	return len(m.space[0])+len(m.space[1])+len(m.space[2])+len(m.space[3]) > 0
+}
+
+func (m *cmap) addFullSpace(n int) {
+	if n >= 1 && n <= 4 && len(m.space[n-1]) == 0 {
+		m.space[n-1] = []byteRange{{strings.Repeat("\x00", n), strings.Repeat("\xff", n)}}
+	}
+}
+
 type matrix [3][3]float64
 
 var ident = matrix{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
@@ -446,6 +710,7 @@ type Text struct {
 	Y        float64 // the Y coordinate, in points, increasing bottom to top
 	W        float64 // the width of the text, in points
 	S        string  // the actual UTF-8 text
+	Vertical bool    // whether the text is written top to bottom, X and Y giving its lower left corner
 }
 
 // A Rect represents a rectangle.
@@ -459,10 +724,24 @@ type Point struct {
 	Y float64
 }
 
+// A Segment is a straight line, from one point to another.
+type Segment struct {
+	From, To Point
+}
+
+func (s Segment) horizontal() bool {
+	return math.Abs(s.From.Y-s.To.Y) < 0.5 && math.Abs(s.From.X-s.To.X) >= 1
+}
+
+func (s Segment) vertical() bool {
+	return math.Abs(s.From.X-s.To.X) < 0.5 && math.Abs(s.From.Y-s.To.Y) >= 1
+}
+
 // Content describes the basic content on a page: the text and any drawn rectangles.
 type Content struct {
-	Text []Text
-	Rect []Rect
+	Text    []Text
+	Rect    []Rect
+	Rulings []Segment // the horizontal and vertical lines of the stroked and filled paths, in device space
 }
 
 type gstate struct {
@@ -470,7 +749,7 @@ type gstate struct {
 	Tw    float64
 	Th    float64
 	Tl    float64
-	Tf    Font
+	Tf    *textFont
 	Tfs   float64
 	Tmode int
 	Trise float64
@@ -480,6 +759,148 @@ type gstate struct {
 	CTM   matrix
 }
 
+// update applies the operators which set the text state, the text matrix and the current transformation
+// matrix, telling whether op is one of them
+func (g *gstate) update(op string, args []Value) bool {
+	switch op {
+	default:
+		return false
+
+	case "cm": // update g.CTM
+		if len(args) != 6 {
+			panic("bad g.Tm")
+		}
+		var m matrix
+		for i := 0; i < 6; i++ {
+			m[i/2][i%2] = args[i].Float64()
+		}
+		m[2][2] = 1
+		g.CTM = m.mul(g.CTM)
+
+	case "BT": // begin text (reset text matrix and line matrix)
+		g.Tm = ident
+		g.Tlm = g.Tm
+
+	case "T*": // move to start of next line
+		g.nextLine()
+
+	case "Tc": // set character spacing
+		if len(args) != 1 {
+			panic("bad g.Tc")
+		}
+		g.Tc = args[0].Float64()
+
+	case "TD": // move text position and set leading
+		if len(args) != 2 {
+			panic("bad Td")
+		}
+		g.Tl = -args[1].Float64()
+		fallthrough
+	case "Td": // move text position
+		if len(args) != 2 {
+			panic("bad Td")
+		}
+		tx := args[0].Float64()
+		ty := args[1].Float64()
+		x := matrix{{1, 0, 0}, {0, 1, 0}, {tx, ty, 1}}
+		g.Tlm = x.mul(g.Tlm)
+		g.Tm = g.Tlm
+
+	case "TL": // set text leading
+		if len(args) != 1 {
+			panic("bad TL")
+		}
+		g.Tl = args[0].Float64()
+
+	case "Tm": // set text matrix and line matrix
+		if len(args) != 6 {
+			panic("bad g.Tm")
+		}
+		var m matrix
+		for i := 0; i < 6; i++ {
+			m[i/2][i%2] = args[i].Float64()
+		}
+		m[2][2] = 1
+		g.Tm = m
+		g.Tlm = m
+
+	case "Tr": // set text rendering mode
+		if len(args) != 1 {
+			panic("bad Tr")
+		}
+		g.Tmode = int(args[0].Int64())
+
+	case "Ts": // set text rise
+		if len(args) != 1 {
+			panic("bad Ts")
+		}
+		g.Trise = args[0].Float64()
+
+	case "Tw": // set word spacing
+		if len(args) != 1 {
+			panic("bad g.Tw")
+		}
+		g.Tw = args[0].Float64()
+
+	case "Tz": // set horizontal text scaling
+		if len(args) != 1 {
+			panic("bad Tz")
+		}
+		g.Th = args[0].Float64() / 100
+	}
+	return true
+}
+
+func (g *gstate) nextLine() {
+	x := matrix{{1, 0, 0}, {0, 1, 0}, {0, -g.Tl, 1}}
+	g.Tlm = x.mul(g.Tlm)
+	g.Tm = g.Tlm
+}
+
+// place returns the text rendering matrix of a glyph shown at the current point, which is the vertical
+// origin of the glyphs of vertical fonts: their horizontal origin is the position vector below it
+func (g *gstate) place(gl glyph) matrix {
+	Trm := matrix{{g.Tfs * g.Th, 0, 0}, {0, g.Tfs, 0}, {0, g.Trise, 1}}.mul(g.Tm).mul(g.CTM)
+	if g.Tf.vertical {
+		Trm = matrix{{1, 0, 0}, {0, 1, 0}, {-gl.vx, -gl.vy, 1}}.mul(Trm)
+	}
+	return Trm
+}
+
+// advance moves the text matrix past a glyph, down for vertical fonts
+func (g *gstate) advance(gl glyph) {
+	spacing := g.Tc
+	if gl.code == " " {
+		spacing += g.Tw
+	}
+	if g.Tf.vertical {
+		ty := gl.w1*g.Tfs + spacing
+		g.Tm = matrix{{1, 0, 0}, {0, 1, 0}, {0, ty, 1}}.mul(g.Tm)
+		return
+	}
+	tx := (gl.w0*g.Tfs + spacing) * g.Th
+	g.Tm = matrix{{1, 0, 0}, {0, 1, 0}, {tx, 0, 1}}.mul(g.Tm)
+}
+
+// kern moves the text matrix by a number of a TJ array, in thousandths of text space units
+func (g *gstate) kern(n float64) {
+	if g.Tf.vertical {
+		g.Tm = matrix{{1, 0, 0}, {0, 1, 0}, {0, -n / 1000 * g.Tfs, 1}}.mul(g.Tm)
+		return
+	}
+	tx := -n / 1000 * g.Tfs * g.Th
+	g.Tm = matrix{{1, 0, 0}, {0, 1, 0}, {tx, 0, 1}}.mul(g.Tm)
+}
+
+// fontName returns the name of the current font, without the tag of subset fonts
+func (g *gstate) fontName() string {
+	f := g.Tf.BaseFont()
+	if i := strings.Index(f, "+"); i >= 0 {
+		f = f[i+1:]
+	}
+	return f
+}
+
 // GetPlainText returns the page's all text without format.
 // fonts can be passed in (to improve parsing performance) or left nil
 func (p Page) GetPlainText(fonts map[string]*Font) (result string, err error) {
@@ -581,19 +1002,13 @@ func (p Page) GetTextByColumn() (Columns, error) {
 		}
 	}()
 
-	showText := func(enc TextEncoding, currentX, currentY float64, s string) {
-		var textBuilder bytes.Buffer
-
-		for _, ch := range enc.Decode(s) {
-			_, err := textBuilder.WriteRune(ch)
-			if err != nil {
-				panic(err)
-			}
-		}
-		text := Text{
-			S: textBuilder.String(),
-			X: currentX,
-			Y: currentY,
+	vertical := 0
+	showText := func(text Text) {
+		currentX := text.X
+		if text.Vertical {
+			vertical++
+		} else if text.S != "" {
+			vertical--
 		}
 
 		var currentColumn *Column
@@ -623,7 +1038,11 @@ func (p Page) GetTextByColumn() (Columns, error) {
 		sort.Sort(column.Content)
 	}
 
+	// columns of vertical writing are read right to left
 	sort.Slice(result, func(i, j int) bool {
+		if vertical > 0 {
+			return result[i].Position > result[j].Position
+		}
 		return result[i].Position < result[j].Position
 	})
 
@@ -651,24 +1070,8 @@ func (p Page) GetTextByRow() (Rows, error) {
 		}
 	}()
 
-	showText := func(enc TextEncoding, currentX, currentY float64, s string) {
-		var textBuilder bytes.Buffer
-		for _, ch := range enc.Decode(s) {
-			_, err := textBuilder.WriteRune(ch)
-			if err != nil {
-				panic(err)
-			}
-		}
-
-		// if DebugOn {
-		// 	fmt.Println(textBuilder.String())
-		// }
-
-		text := Text{
-			S: textBuilder.String(),
-			X: currentX,
-			Y: currentY,
-		}
+	showText := func(text Text) {
+		currentY := text.Y
 
 		var currentRow *Row
 		rowFound := false
@@ -704,17 +1107,30 @@ func (p Page) GetTextByRow() (Rows, error) {
 	return result, err
 }
 
-func (p Page) walkTextBlocks(walker func(enc TextEncoding, x, y float64, s string)) {
+// walkTextBlocks calls walker with the strings shown on the page, placed where they start
+func (p Page) walkTextBlocks(walker func(t Text)) {
 	strm := p.V.Key("Contents")
 
-	fonts := make(map[string]*Font)
-	for _, font := range p.Fonts() {
-		f := p.Font(font)
-		fonts[font] = &f
+	g := gstate{Th: 1, CTM: ident, Tf: &textFont{enc: &nopEncoder{}, scale: 0.001}}
+	var gstack []gstate
+	showText := func(s string) {
+		var b strings.Builder
+		var Trm matrix
+		w := 0.0
+		for i, gl := range g.Tf.glyphs(s) {
+			if i == 0 {
+				Trm = g.place(gl)
+			}
+			b.WriteString(gl.text)
+			w += gl.w0
+			g.advance(gl)
+		}
+		if s == "" {
+			Trm = g.place(glyph{})
+		}
+		walker(Text{g.fontName(), Trm[0][0], Trm[2][0], Trm[2][1], w * Trm[0][0], b.String(), g.Tf.vertical})
 	}
 
-	var enc TextEncoding = &nopEncoder{}
-	var currentX, currentY float64
 	Interpret(strm, func(stk *Stack, op string) {
 		n := stk.Len()
 		args := make([]Value, n)
@@ -726,49 +1142,58 @@ func (p Page) walkTextBlocks(walker func(enc TextEncoding, x, y float64, s strin
 		// 	fmt.Println(op, "->", args)
 		// }
 
+		if g.update(op, args) {
+			if op == "Td" {
+				showText("")
+			}
+			return
+		}
 		switch op {
 		default:
 			return
-		case "T*": // move to start of next line
+		case "q": // save graphics state
+			gstack = append(gstack, g)
+		case "Q": // restore graphics state
+			if n := len(gstack) - 1; n >= 0 {
+				g = gstack[n]
+				gstack = gstack[:n]
+			}
 		case "Tf": // set text font and size
 			if len(args) != 2 {
 				panic("bad TL")
 			}
-
-			if font, ok := fonts[args[0].Name()]; ok {
-				enc = font.Encoder()
-			} else {
-				enc = &nopEncoder{}
-			}
+			g.Tf = p.textFont(args[0].Name())
+			g.Tfs = args[1].Float64()
 		case "\"": // set spacing, move to next line, and show text
 			if len(args) != 3 {
 				panic("bad \" operator")
 			}
+			g.Tw = args[0].Float64()
+			g.Tc = args[1].Float64()
+			args = args[2:]
 			fallthrough
 		case "'": // move to next line and show text
 			if len(args) != 1 {
 				panic("bad ' operator")
 			}
+			g.nextLine()
 			fallthrough
 		case "Tj": // show text
 			if len(args) != 1 {
 				panic("bad Tj operator")
 			}
 
-			walker(enc, currentX, currentY, args[0].RawString())
+			showText(args[0].RawString())
 		case "TJ": // show text, allowing individual glyph positioning
 			v := args[0]
 			for i := 0; i < v.Len(); i++ {
 				x := v.Index(i)
 				if x.Kind() == String {
-					walker(enc, currentX, currentY, x.RawString())
+					showText(x.RawString())
+				} else {
+					g.kern(x.Float64())
 				}
 			}
-		case "Td":
-			walker(enc, currentX, currentY, "")
-		case "Tm":
-			currentX = args[4].Float64()
-			currentY = args[5].Float64()
 		}
 	})
 }
@@ -776,39 +1201,36 @@ func (p Page) walkTextBlocks(walker func(enc TextEncoding, x, y float64, s strin
 // Content returns the page's content.
 func (p Page) Content() Content {
 	strm := p.V.Key("Contents")
-	var enc TextEncoding = &nopEncoder{}
 
 	var g = gstate{
 		Th:  1,
 		CTM: ident,
+		Tf:  &textFont{enc: &nopEncoder{}, scale: 0.001},
 	}
 
 	var text []Text
 	showText := func(s string) {
-		n := 0
-		decoded := enc.Decode(s)
-		for _, ch := range decoded {
-			var w0 float64
-			if n < len(s) {
-				w0 = g.Tf.Width(int(s[n]))
-			}
-			n++
-
-			f := g.Tf.BaseFont()
-			if i := strings.Index(f, "+"); i >= 0 {
-				f = f[i+1:]
+		f := g.fontName()
+		for _, gl := range g.Tf.glyphs(s) {
+			if gl.text != "" {
+				Trm := g.place(gl)
+				text = append(text, Text{f, Trm[0][0], Trm[2][0], Trm[2][1], gl.w0 * Trm[0][0], gl.text, g.Tf.vertical})
 			}
-
-			Trm := matrix{{g.Tfs * g.Th, 0, 0}, {0, g.Tfs, 0}, {0, g.Trise, 1}}.mul(g.Tm).mul(g.CTM)
-			text = append(text, Text{f, Trm[0][0], Trm[2][0], Trm[2][1], w0 / 1000 * Trm[0][0], string(ch)})
-
-			tx := w0/1000*g.Tfs + g.Tc
-			tx *= g.Th
-			g.Tm = matrix{{1, 0, 0}, {0, 1, 0}, {tx, 0, 1}}.mul(g.Tm)
+			g.advance(gl)
 		}
 	}
 
 	var rect []Rect
+	var rulings, path []Segment
+	var start, current Point
+	point := func(x, y float64) Point { // in device space
+		m := matrix{{1, 0, 0}, {0, 1, 0}, {x, y, 1}}.mul(g.CTM)
+		return Point{m[2][0], m[2][1]}
+	}
+	lineTo := func(p Point) {
+		path = append(path, Segment{current, p})
+		current = p
+	}
 	var gstack []gstate
 	Interpret(strm, func(stk *Stack, op string) {
 		n := stk.Len()
@@ -816,6 +1238,9 @@ func (p Page) Content() Content {
 		for i := n - 1; i >= 0; i-- {
 			args[i] = stk.Pop()
 		}
+		if g.update(op, args) {
+			return
+		}
 		switch op {
 		default:
 			// if DebugOn {
@@ -823,33 +1248,27 @@ func (p Page) Content() Content {
 			// }
 			return
 
-		case "cm": // update g.CTM
-			if len(args) != 6 {
-				panic("bad g.Tm")
-			}
-			var m matrix
-			for i := 0; i < 6; i++ {
-				m[i/2][i%2] = args[i].Float64()
+		case "m": // begin new subpath
+			if len(args) != 2 {
+				panic("bad m")
 			}
-			m[2][2] = 1
-			g.CTM = m.mul(g.CTM)
+			current = point(args[0].Float64(), args[1].Float64())
+			start = current
 
-		case "gs": // set parameters from graphics state resource
-			//gs := p.Resources().Key("ExtGState").Key(args[0].Name())
-			//font := gs.Key("Font")
-			//if font.Kind() == Array && font.Len() == 2 {
-			// if DebugOn {
-			// 	fmt.Println("FONT", font)
-			// }
-			//}
+		case "l": // append straight line segment to path
+			if len(args) != 2 {
+				panic("bad l")
+			}
+			lineTo(point(args[0].Float64(), args[1].Float64()))
 
-		case "f": // fill
-		case "g": // setgray
-		case "l": // lineto
-		case "m": // moveto
+		case "c", "v", "y": // append curved segment to path
+			if len(args) < 4 {
+				panic("bad curve")
+			}
+			current = point(args[len(args)-2].Float64(), args[len(args)-1].Float64())
 
-		case "cs": // set colorspace non-stroking
-		case "scn": // set color non-stroking
+		case "h": // close subpath
+			lineTo(start)
 
 		case "re": // append rectangle to path
 			if len(args) != 4 {
@@ -857,61 +1276,41 @@ func (p Page) Content() Content {
 			}
 			x, y, w, h := args[0].Float64(), args[1].Float64(), args[2].Float64(), args[3].Float64()
 			rect = append(rect, Rect{Point{x, y}, Point{x + w, y + h}})
+			start = point(x, y)
+			current = start
+			lineTo(point(x+w, y))
+			lineTo(point(x+w, y+h))
+			lineTo(point(x, y+h))
+			lineTo(start)
+
+		case "s", "b", "b*": // close, and stroke or fill and stroke path
+			lineTo(start)
+			fallthrough
+		case "S", "f", "F", "f*", "B", "B*": // stroke or fill path
+			for _, s := range path {
+				if s.horizontal() || s.vertical() {
+					rulings = append(rulings, s)
+				}
+			}
+			path = nil
+
+		case "n": // end path without filling or stroking
+			path = nil
 
 		case "q": // save graphics state
 			gstack = append(gstack, g)
 
 		case "Q": // restore graphics state
-			n := len(gstack) - 1
-			g = gstack[n]
-			gstack = gstack[:n]
-
-		case "BT": // begin text (reset text matrix and line matrix)
-			g.Tm = ident
-			g.Tlm = g.Tm
-
-		case "ET": // end text
-
-		case "T*": // move to start of next line
-			x := matrix{{1, 0, 0}, {0, 1, 0}, {0, -g.Tl, 1}}
-			g.Tlm = x.mul(g.Tlm)
-			g.Tm = g.Tlm
-
-		case "Tc": // set character spacing
-			if len(args) != 1 {
-				panic("bad g.Tc")
+			if n := len(gstack) - 1; n >= 0 {
+				g = gstack[n]
+				gstack = gstack[:n]
 			}
-			g.Tc = args[0].Float64()
-
-		case "TD": // move text position and set leading
-			if len(args) != 2 {
-				panic("bad Td")
-			}
-			g.Tl = -args[1].Float64()
-			fallthrough
-		case "Td": // move text position
-			if len(args) != 2 {
-				panic("bad Td")
-			}
-			tx := args[0].Float64()
-			ty := args[1].Float64()
-			x := matrix{{1, 0, 0}, {0, 1, 0}, {tx, ty, 1}}
-			g.Tlm = x.mul(g.Tlm)
-			g.Tm = g.Tlm
 
 		case "Tf": // set text font and size
 			if len(args) != 2 {
 				panic("bad TL")
 			}
-			f := args[0].Name()
-			g.Tf = p.Font(f)
-			enc = g.Tf.Encoder()
-			if enc == nil {
-				if DebugOn {
-					println("no cmap for", f)
-				}
-				enc = &nopEncoder{}
-			}
+			g.Tf = p.textFont(args[0].Name())
 			g.Tfs = args[1].Float64()
 
 		case "\"": // set spacing, move to next line, and show text
@@ -926,9 +1325,7 @@ func (p Page) Content() Content {
 			if len(args) != 1 {
 				panic("bad ' operator")
 			}
-			x := matrix{{1, 0, 0}, {0, 1, 0}, {0, -g.Tl, 1}}
-			g.Tlm = x.mul(g.Tlm)
-			g.Tm = g.Tlm
+			g.nextLine()
 			fallthrough
 		case "Tj": // show text
 			if len(args) != 1 {
@@ -943,56 +1340,14 @@ func (p Page) Content() Content {
 				if x.Kind() == String {
 					showText(x.RawString())
 				} else {
-					tx := -x.Float64() / 1000 * g.Tfs * g.Th
-					g.Tm = matrix{{1, 0, 0}, {0, 1, 0}, {tx, 0, 1}}.mul(g.Tm)
+					g.kern(x.Float64())
 				}
 			}
-			showText("\n")
-
-		case "TL": // set text leading
-			if len(args) != 1 {
-				panic("bad TL")
-			}
-			g.Tl = args[0].Float64()
-
-		case "Tm": // set text matrix and line matrix
-			if len(args) != 6 {
-				panic("bad g.Tm")
-			}
-			var m matrix
-			for i := 0; i < 6; i++ {
-				m[i/2][i%2] = args[i].Float64()
-			}
-			m[2][2] = 1
-			g.Tm = m
-			g.Tlm = m
-
-		case "Tr": // set text rendering mode
-			if len(args) != 1 {
-				panic("bad Tr")
-			}
-			g.Tmode = int(args[0].Int64())
-
-		case "Ts": // set text rise
-			if len(args) != 1 {
-				panic("bad Ts")
-			}
-			g.Trise = args[0].Float64()
-
-		case "Tw": // set word spacing
-			if len(args) != 1 {
-				panic("bad g.Tw")
-			}
-			g.Tw = args[0].Float64()
-
-		case "Tz": // set horizontal text scaling
-			if len(args) != 1 {
-				panic("bad Tz")
-			}
-			g.Th = args[0].Float64() / 100
+			Trm := g.place(glyph{})
+			text = append(text, Text{g.fontName(), Trm[0][0], Trm[2][0], Trm[2][1], 0, "\n", g.Tf.vertical})
 		}
 	})
-	return Content{text, rect}
+	return Content{text, rect, rulings}
 }
 
 // TextVertical implements sort.Interface for sorting
@@ -1027,21 +1382,34 @@ func (x TextHorizontal) Less(i, j int) bool {
 // of a document.
 type Outline struct {
 	Title string    // title for this element
+	Page  int       // number of the page the element leads to, 0 if none
+	URI   string    // target of an element which opens a URI or another file
 	Child []Outline // child elements
 }
 
 // Outline returns the document outline.
 // The Outline returned is the root of the outline tree and typically has no Title itself.
 // That is, the children of the returned root are the top-level entries in the outline.
-func (r *Reader) Outline() Outline {
-	return buildOutline(r.Trailer().Key("Root").Key("Outlines"))
+// The elements lead to pages through their destination (Dest), named or not, or their GoTo action.
+// A malformed outline ends at the element which cannot be read.
+func (r *Reader) Outline() (x Outline) {
+	defer func() {
+		recover()
+	}()
+	buildOutline(r.Trailer().Key("Root").Key("Outlines"), &x, map[objptr]bool{})
+	return x
 }
 
-func buildOutline(entry Value) Outline {
-	var x Outline
+func buildOutline(entry Value, x *Outline, seen map[objptr]bool) {
 	x.Title = entry.Key("Title").Text()
-	for child := entry.Key("First"); child.Kind() == Dict; child = child.Key("Next") {
-		x.Child = append(x.Child, buildOutline(child))
+	if dest := entry.Key("Dest"); dest.Kind() != Null {
+		x.Page = entry.r.destPage(dest)
+	} else if a := entry.Key("A"); a.Kind() == Dict {
+		x.Page, x.URI = entry.r.actionTarget(a)
+	}
+	for child := entry.Key("First"); child.Kind() == Dict && !seen[child.ptr]; child = child.Key("Next") {
+		seen[child.ptr] = true
+		x.Child = append(x.Child, Outline{})
+		buildOutline(child, &x.Child[len(x.Child)-1], seen)
 	}
-	return x
 }
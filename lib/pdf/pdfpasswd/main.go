@@ -66,7 +66,7 @@ func main() {
 		if err == pdf.ErrInvalidPassword {
 			log.Fatal("password not found")
 		}
-		log.Fatal("reading pdf: %v", err)
+		log.Fatalf("reading pdf: %v", err)
 	}
 	fmt.Printf("password: %q\n", last)
 }
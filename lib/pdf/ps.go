@@ -38,7 +38,7 @@ func newDict() Value {
 // operators. The do function may push or pop values from the stack as needed
 // to implement op.
 //
-// Interpret handles the operators "dict", "currentdict", "begin", "end", "def", and "pop" itself.
+// Interpret handles the operators "dict", "currentdict", "begin", "end", "def", "dup" and "pop" itself.
 //
 // Interpret is not a full-blown PostScript interpreter. Its job is to handle the
 // very limited PostScript found in certain supporting file formats embedded
@@ -47,8 +47,10 @@ func newDict() Value {
 //
 // There is no support for executable blocks, among other limitations.
 func Interpret(strm Value, do func(stk *Stack, op string)) {
+	interpret(strm.Reader(), do)
+}
 
-	rd := strm.Reader()
+func interpret(rd io.Reader, do func(stk *Stack, op string)) {
 	b := newBuffer(rd, 0)
 	b.allowEOF = true
 	b.allowObjptr = false
@@ -111,6 +113,11 @@ Reading:
 			case "pop":
 				stk.Pop()
 				continue
+			case "dup":
+				v := stk.Pop()
+				stk.Push(v)
+				stk.Push(v)
+				continue
 			}
 		}
 		b.unreadToken(tok)
@@ -69,12 +69,13 @@ import (
 	"os"
 	"sort"
 	"strconv"
+	"sync"
 )
 
 // DebugOn is responsible for logging messages into stdout. If problems arise during reading, set it true.
 var DebugOn = false
 
-// A Reader is a single PDF file open for reading.
+// A Reader is a single PDF file open for reading. It may be read from several goroutines.
 type Reader struct {
 	f          io.ReaderAt
 	end        int64
@@ -83,6 +84,16 @@ type Reader struct {
 	trailerptr objptr
 	key        []byte
 	useAES     bool
+	password   string // PasswordUser or PasswordOwner once an encrypted file is opened
+	version    string // of the header, as "1.7" or "2.0"
+	repairs    []string
+	objStms    []objptr // object streams of a rebuilt cross-reference table
+
+	mu           sync.Mutex // guards the maps below, filled as the document is read
+	fontPrograms map[objptr]*fontProgram
+	textFonts    map[fontKey]*textFont
+	pageNums     map[objptr]int   // page numbers by page object, see pageNumber
+	dests        map[string]Value // named destinations, see namedDest
 }
 
 type xref struct {
@@ -126,57 +137,66 @@ func NewReader(f io.ReaderAt, size int64) (*Reader, error) {
 // to try. If pw returns the empty string, NewReaderEncrypted stops trying to decrypt
 // the file and returns an error.
 func NewReaderEncrypted(f io.ReaderAt, size int64, pw func() string) (*Reader, error) {
-	buf := make([]byte, 10)
-	f.ReadAt(buf, 0)
-	if !bytes.HasPrefix(buf, []byte("%PDF-1.")) || buf[7] < '0' || buf[7] > '7' || buf[8] != '\r' && buf[8] != '\n' {
+	start := findHeader(f, size)
+	if start < 0 {
 		return nil, fmt.Errorf("not a PDF file: invalid header")
 	}
-	end := size
-	const endChunk = 100
-	buf = make([]byte, endChunk)
-	f.ReadAt(buf, end-endChunk)
-	for len(buf) > 0 && buf[len(buf)-1] == '\n' || buf[len(buf)-1] == '\r' {
-		buf = buf[:len(buf)-1]
-	}
-	buf = bytes.TrimRight(buf, "\r\n\t ")
-	if !bytes.HasSuffix(buf, []byte("%%EOF")) {
-		return nil, fmt.Errorf("not a PDF file: missing %%%%EOF")
-	}
-	i := findLastLine(buf, "startxref")
-	if i < 0 {
-		return nil, fmt.Errorf("malformed PDF file: missing final startxref")
-	}
-
 	r := &Reader{
 		f:   f,
-		end: end,
+		end: size,
 	}
-	pos := end - endChunk + int64(i)
-	b := newBuffer(io.NewSectionReader(f, pos, end-pos), pos)
-	if b.readToken() != keyword("startxref") {
-		return nil, fmt.Errorf("malformed PDF file: missing startxref")
+	if start > 0 {
+		// offsets count from the header, as if the preamble was not there
+		r.f = io.NewSectionReader(f, start, size-start)
+		r.end = size - start
+		r.repairf("ignored %d bytes before the header", start)
 	}
-	startxref, ok := b.readToken().(int64)
-	if !ok {
-		return nil, fmt.Errorf("malformed PDF file: startxref not followed by integer")
+	buf := make([]byte, 10)
+	r.f.ReadAt(buf, 0)
+	if !(buf[5] == '1' && buf[7] >= '0' && buf[7] <= '7' || buf[5] == '2' && buf[7] == '0') || buf[6] != '.' || '0' <= buf[8] && buf[8] <= '9' {
+		return nil, fmt.Errorf("not a PDF file: invalid header")
 	}
-	b = newBuffer(io.NewSectionReader(r.f, startxref, r.end-startxref), startxref)
-	xref, trailerptr, trailer, err := readXref(r, b)
+	r.version = string(buf[5:8])
+	err := catch(func() error {
+		startxref, err := r.readTail()
+		if err != nil {
+			return err
+		}
+		b := newBuffer(io.NewSectionReader(r.f, startxref, r.end-startxref), startxref)
+		if r.xref, r.trailerptr, r.trailer, err = readXref(r, b); err != nil {
+			return err
+		}
+		return r.checkXref()
+	})
 	if err != nil {
-		return nil, err
+		r.repairf("rebuilt the cross-reference table: %v", err)
+		r.trailerptr = objptr{}
+		if err := r.rebuildXref(); err != nil {
+			return nil, err
+		}
+	}
+	if r.trailer["Encrypt"] != nil {
+		if err := r.openEncrypted(pw); err != nil {
+			return nil, err
+		}
+	}
+	if r.objStms != nil {
+		r.indexObjStms()
 	}
-	r.xref = xref
-	r.trailer = trailer
-	r.trailerptr = trailerptr
-	if trailer["Encrypt"] == nil {
-		return r, nil
+	if _, ok := r.trailer["Root"].(objptr); !ok {
+		return nil, fmt.Errorf("malformed PDF: document catalog not found")
 	}
-	err = r.initEncrypt("")
+	return r, nil
+}
+
+// openEncrypted initializes the decryption of the file with the empty password, then with those of pw
+func (r *Reader) openEncrypted(pw func() string) error {
+	err := r.initEncrypt("")
 	if err == nil {
-		return r, nil
+		return nil
 	}
 	if pw == nil || err != ErrInvalidPassword {
-		return nil, err
+		return err
 	}
 	for {
 		next := pw()
@@ -184,10 +204,25 @@ func NewReaderEncrypted(f io.ReaderAt, size int64, pw func() string) (*Reader, e
 			break
 		}
 		if r.initEncrypt(next) == nil {
-			return r, nil
+			return nil
 		}
 	}
-	return nil, err
+	return err
+}
+
+// Version returns the version of PDF the file conforms to, as "1.7" or "2.0": that of the header, or
+// the Version entry of the document catalog if it is later (as incremental updates may set it).
+func (r *Reader) Version() string {
+	if v := r.safeResolve(objptr{}, r.trailer["Root"]).Key("Version").Name(); len(v) == 3 && v > r.version && v <= "2.0" {
+		return v
+	}
+	return r.version
+}
+
+// Password tells which password opened an encrypted file: PasswordOwner, PasswordUser (which may be the
+// empty password of files only protected against modification), or "" if the file is not encrypted.
+func (r *Reader) Password() string {
+	return r.password
 }
 
 // Trailer returns the file's Trailer value.
@@ -515,6 +550,9 @@ func objfmt(x interface{}) string {
 		if isUTF16(x) {
 			return strconv.Quote(utf16Decode(x[2:]))
 		}
+		if isUTF8(x) {
+			return strconv.Quote(x[3:])
+		}
 		return strconv.Quote(x)
 	case name:
 		return "/" + string(x)
@@ -606,8 +644,8 @@ func (v Value) RawString() string {
 	return x
 }
 
-// Text returns v's string value interpreted as a “text string” (defined in the PDF spec)
-// and converted to UTF-8.
+// Text returns v's string value interpreted as a “text string” (defined in the PDF spec:
+// PDFDocEncoding, UTF-16BE or, since PDF 2.0, UTF-8 with a byte order mark) and converted to UTF-8.
 // If v.Kind() != String, Text returns the empty string.
 func (v Value) Text() string {
 	x, ok := v.data.(string)
@@ -620,6 +658,9 @@ func (v Value) Text() string {
 	if isUTF16(x) {
 		return utf16Decode(x[2:])
 	}
+	if isUTF8(x) {
+		return x[3:]
+	}
 	return x
 }
 
@@ -701,6 +742,17 @@ func (v Value) Index(i int) Value {
 	return v.r.resolve(v.ptr, x[i])
 }
 
+// isRef reports whether the i'th element in the array v is a reference to an indirect object. Direct
+// elements share the object of the array, so that a reference to it cannot be told apart otherwise.
+func (v Value) isRef(i int) bool {
+	x, ok := v.data.(array)
+	if !ok || i < 0 || i >= len(x) {
+		return false
+	}
+	_, ok = x[i].(objptr)
+	return ok
+}
+
 // Len returns the length of the array v.
 // If v.Kind() != Array, Len returns 0.
 func (v Value) Len() int {
@@ -761,7 +813,6 @@ func (r *Reader) resolve(parent objptr, x interface{}) Value {
 			def, ok := obj.(objdef)
 			if !ok {
 				panic(fmt.Errorf("loading %v: found %T instead of objdef", ptr, obj))
-				return Value{}
 			}
 			if def.ptr != ptr {
 				panic(fmt.Errorf("loading %v: found %v", ptr, def.ptr))
@@ -795,22 +846,28 @@ func (e *errorReadCloser) Close() error {
 
 // Reader returns the data contained in the stream v.
 // If v.Kind() != Stream, Reader returns a ReadCloser that
-// responds to all reads with a “stream not present” error.
-func (v Value) Reader() io.ReadCloser {
+// responds to all reads with a “stream not present” error,
+// and so it does with the error of a malformed stream or filter.
+func (v Value) Reader() (rc io.ReadCloser) {
 	x, ok := v.data.(stream)
 	if !ok {
 		return &errorReadCloser{fmt.Errorf("stream not present")}
 	}
+	defer func() {
+		if e := recover(); e != nil {
+			rc = &errorReadCloser{fmt.Errorf("malformed PDF: %v", e)}
+		}
+	}()
 	var rd io.Reader
 	rd = io.NewSectionReader(v.r.f, x.offset, v.Key("Length").Int64())
-	if v.r.key != nil {
-		rd = decryptStream(v.r.key, v.r.useAES, x.ptr, rd)
-	}
 	filter := v.Key("Filter")
 	param := v.Key("DecodeParms")
+	if v.r.key != nil && !identityCrypt(filter, param) {
+		rd = decryptStream(v.r.key, v.r.useAES, x.ptr, rd)
+	}
 	switch filter.Kind() {
 	default:
-		panic(fmt.Errorf("unsupported filter %v", filter))
+		return &errorReadCloser{fmt.Errorf("unsupported filter %v", filter)}
 	case Null:
 		// ok
 	case Name:
@@ -824,30 +881,45 @@ func (v Value) Reader() io.ReadCloser {
 	return ioutil.NopCloser(rd)
 }
 
+// identityCrypt tells whether the first filter of a stream is a Crypt filter which leaves it in clear.
+// Other crypt filters are taken for the default one, the security handlers having a single cipher.
+func identityCrypt(filter, param Value) bool {
+	if filter.Kind() == Array {
+		filter, param = filter.Index(0), param.Index(0)
+	}
+	if filter.Name() != "Crypt" {
+		return false
+	}
+	n := param.Key("Name").Name()
+	return n == "" || n == "Identity"
+}
+
 func applyFilter(rd io.Reader, name string, param Value) io.Reader {
 	switch name {
 	default:
-		panic("unknown filter " + name)
-	case "FlateDecode":
+		return &errorReadCloser{fmt.Errorf("unknown filter %s", name)}
+	case "FlateDecode", "Fl":
 		zr, err := zlib.NewReader(rd)
 		if err != nil {
-			panic(err)
+			return &errorReadCloser{err}
 		}
-		pred := param.Key("Predictor")
-		if pred.Kind() == Null {
-			return zr
+		return applyPredictor(zr, param)
+	case "LZWDecode", "LZW":
+		earlyChange := 1
+		if ec := param.Key("EarlyChange"); ec.Kind() == Integer {
+			earlyChange = int(ec.Int64())
 		}
-		columns := param.Key("Columns").Int64()
-		switch pred.Int64() {
-		default:
-			if DebugOn {
-				fmt.Println("unknown predictor", pred)
-			}
-			panic("pred")
-		case 12:
-			return &pngUpReader{r: zr, hist: make([]byte, 1+columns), tmp: make([]byte, 1+columns)}
-		}
-	case "ASCII85Decode":
+		lzw := &decodedReader{rd: rd, decode: func(b []byte) ([]byte, error) { return decodeLZW(b, earlyChange) }}
+		return applyPredictor(lzw, param)
+	case "ASCIIHexDecode", "AHx":
+		return &decodedReader{rd: rd, decode: decodeASCIIHex}
+	case "RunLengthDecode", "RL":
+		return &decodedReader{rd: rd, decode: decodeRunLength}
+	case "Crypt":
+		return rd // see identityCrypt
+	case "DCTDecode", "DCT", "JPXDecode", "CCITTFaxDecode", "CCF", "JBIG2Decode":
+		return rd // image data, left encoded
+	case "ASCII85Decode", "A85":
 		cleanASCII85 := newAlphaReader(rd)
 		decoder := ascii85.NewDecoder(cleanASCII85)
 
@@ -856,45 +928,13 @@ func applyFilter(rd io.Reader, name string, param Value) io.Reader {
 			if DebugOn {
 				fmt.Println("param=", param)
 			}
-			panic("not expected DecodeParms for ascii85")
+			return &errorReadCloser{fmt.Errorf("not expected DecodeParms for ascii85")}
 		case nil:
 			return decoder
 		}
 	}
 }
 
-type pngUpReader struct {
-	r    io.Reader
-	hist []byte
-	tmp  []byte
-	pend []byte
-}
-
-func (r *pngUpReader) Read(b []byte) (int, error) {
-	n := 0
-	for len(b) > 0 {
-		if len(r.pend) > 0 {
-			m := copy(b, r.pend)
-			n += m
-			b = b[m:]
-			r.pend = r.pend[m:]
-			continue
-		}
-		_, err := io.ReadFull(r.r, r.tmp)
-		if err != nil {
-			return n, err
-		}
-		if r.tmp[0] != 2 {
-			return n, fmt.Errorf("malformed PNG-Up encoding")
-		}
-		for i, b := range r.tmp {
-			r.hist[i] += b
-		}
-		r.pend = r.hist[1:]
-	}
-	return n, nil
-}
-
 var passwordPad = []byte{
 	0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41, 0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
 	0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80, 0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
@@ -906,6 +946,10 @@ func (r *Reader) initEncrypt(password string) error {
 	if encrypt["Filter"] != name("Standard") {
 		return fmt.Errorf("unsupported PDF: encryption filter %v", objfmt(encrypt["Filter"]))
 	}
+	V, _ := encrypt["V"].(int64)
+	if V == 5 {
+		return r.initEncryptAES256(encrypt, password)
+	}
 	n, _ := encrypt["Length"].(int64)
 	if n == 0 {
 		n = 40
@@ -913,8 +957,8 @@ func (r *Reader) initEncrypt(password string) error {
 	if n%8 != 0 || n > 128 || n < 40 {
 		return fmt.Errorf("malformed PDF: %d-bit encryption key", n)
 	}
-	V, _ := encrypt["V"].(int64)
-	if V != 1 && V != 2 && (V != 4 || !okayV4(encrypt)) {
+	useAES := V == 4 && okayCryptFilters(encrypt, "AESV2", 16)
+	if V != 1 && V != 2 && (V != 4 || !useAES && !okayCryptFilters(encrypt, "V2", n/8)) {
 		return fmt.Errorf("unsupported PDF: encryption version V=%d; %v", V, objfmt(encrypt))
 	}
 
@@ -943,108 +987,121 @@ func (r *Reader) initEncrypt(password string) error {
 	p, _ := encrypt["P"].(int64)
 	P := uint32(p)
 
-	// TODO: Password should be converted to Latin-1.
-	pw := []byte(password)
-	h := md5.New()
-	if len(pw) >= 32 {
-		h.Write(pw[:32])
-	} else {
-		h.Write(pw)
-		h.Write(passwordPad[:32-len(pw)])
+	// userKey computes the file key from the user password and checks it against U
+	userKey := func(pw []byte) []byte {
+		h := md5.New()
+		h.Write(padPassword(pw))
+		h.Write([]byte(O))
+		h.Write([]byte{byte(P), byte(P >> 8), byte(P >> 16), byte(P >> 24)})
+		h.Write([]byte(ID))
+		key := h.Sum(nil)
+
+		if R >= 3 {
+			for i := 0; i < 50; i++ {
+				h.Reset()
+				h.Write(key[:n/8])
+				key = h.Sum(key[:0])
+			}
+			key = key[:n/8]
+		} else {
+			key = key[:40/8]
+		}
+
+		c, _ := rc4.NewCipher(key)
+		var u []byte
+		if R == 2 {
+			u = make([]byte, 32)
+			copy(u, passwordPad)
+			c.XORKeyStream(u, u)
+		} else {
+			h.Reset()
+			h.Write(passwordPad)
+			h.Write([]byte(ID))
+			u = h.Sum(nil)
+			c.XORKeyStream(u, u)
+
+			for i := 1; i <= 19; i++ {
+				c, _ = rc4.NewCipher(xorKey(key, byte(i)))
+				c.XORKeyStream(u, u)
+			}
+		}
+
+		if !bytes.HasPrefix([]byte(U), u) {
+			return nil
+		}
+		return key
 	}
-	h.Write([]byte(O))
-	h.Write([]byte{byte(P), byte(P >> 8), byte(P >> 16), byte(P >> 24)})
-	h.Write([]byte(ID))
-	key := h.Sum(nil)
 
+	// The owner password decrypts O into the user password (algorithm 7 of §7.6.3.4).
+	// TODO: Password should be converted to Latin-1.
+	h := md5.New()
+	h.Write(padPassword([]byte(password)))
+	ownerKey := h.Sum(nil)
 	if R >= 3 {
 		for i := 0; i < 50; i++ {
 			h.Reset()
-			h.Write(key[:n/8])
-			key = h.Sum(key[:0])
+			h.Write(ownerKey)
+			ownerKey = h.Sum(ownerKey[:0])
 		}
-		key = key[:n/8]
+		ownerKey = ownerKey[:n/8]
 	} else {
-		key = key[:40/8]
-	}
-
-	c, err := rc4.NewCipher(key)
-	if err != nil {
-		return fmt.Errorf("malformed PDF: invalid RC4 key: %v", err)
+		ownerKey = ownerKey[:40/8]
 	}
-
-	var u []byte
+	user := []byte(O)
 	if R == 2 {
-		u = make([]byte, 32)
-		copy(u, passwordPad)
-		c.XORKeyStream(u, u)
+		c, _ := rc4.NewCipher(ownerKey)
+		c.XORKeyStream(user, user)
 	} else {
-		h.Reset()
-		h.Write(passwordPad)
-		h.Write([]byte(ID))
-		u = h.Sum(nil)
-		c.XORKeyStream(u, u)
-
-		for i := 1; i <= 19; i++ {
-			key1 := make([]byte, len(key))
-			copy(key1, key)
-			for j := range key1 {
-				key1[j] ^= byte(i)
-			}
-			c, _ = rc4.NewCipher(key1)
-			c.XORKeyStream(u, u)
+		for i := 19; i >= 0; i-- {
+			c, _ := rc4.NewCipher(xorKey(ownerKey, byte(i)))
+			c.XORKeyStream(user, user)
 		}
 	}
 
-	if !bytes.HasPrefix([]byte(U), u) {
+	if key := userKey(user); key != nil {
+		r.key = key
+		r.password = PasswordOwner
+	} else if key := userKey([]byte(password)); key != nil {
+		r.key = key
+		r.password = PasswordUser
+	} else {
 		return ErrInvalidPassword
 	}
-
-	r.key = key
-	r.useAES = V == 4
+	r.useAES = useAES
 
 	return nil
 }
 
-var ErrInvalidPassword = fmt.Errorf("encrypted PDF: invalid password")
-
-func okayV4(encrypt dict) bool {
-	cf, ok := encrypt["CF"].(dict)
-	if !ok {
-		return false
-	}
-	stmf, ok := encrypt["StmF"].(name)
-	if !ok {
-		return false
-	}
-	strf, ok := encrypt["StrF"].(name)
-	if !ok {
-		return false
-	}
-	if stmf != strf {
-		return false
-	}
-	cfparam, ok := cf[stmf].(dict)
-	if cfparam["AuthEvent"] != nil && cfparam["AuthEvent"] != name("DocOpen") {
-		return false
-	}
-	if cfparam["Length"] != nil && cfparam["Length"] != int64(16) {
-		return false
+// padPassword truncates or pads a password to 32 bytes
+func padPassword(pw []byte) []byte {
+	if len(pw) >= 32 {
+		return pw[:32]
 	}
-	if cfparam["CFM"] != name("AESV2") {
-		return false
+	return append(append([]byte{}, pw...), passwordPad[:32-len(pw)]...)
+}
+
+// xorKey returns a copy of key with each byte XORed with b
+func xorKey(key []byte, b byte) []byte {
+	key1 := make([]byte, len(key))
+	for j := range key {
+		key1[j] = key[j] ^ b
 	}
-	return true
+	return key1
 }
 
+var ErrInvalidPassword = fmt.Errorf("encrypted PDF: invalid password")
+
 func cryptKey(key []byte, useAES bool, ptr objptr) []byte {
+	if len(key) == 32 {
+		return key // AES-256 (V=5) uses the file key for every object
+	}
 	h := md5.New()
 	h.Write(key)
 	h.Write([]byte{byte(ptr.id), byte(ptr.id >> 8), byte(ptr.id >> 16), byte(ptr.gen), byte(ptr.gen >> 8)})
 	if useAES {
 		h.Write([]byte("sAlT"))
 	}
-	return h.Sum(nil)
+	return h.Sum(nil)[:min(len(key)+5, 16)]
 }
 
 func decryptString(key []byte, useAES bool, ptr objptr, x string) string {
@@ -1061,7 +1118,7 @@ func decryptString(key []byte, useAES bool, ptr objptr, x string) string {
 
 		stream := cipher.NewCBCDecrypter(block, iv)
 		stream.CryptBlocks(s, s)
-		x = string(s)
+		x = string(unpad(s))
 	} else {
 		c, _ := rc4.NewCipher(key)
 		data := []byte(x)
@@ -1084,7 +1141,7 @@ func decryptStream(key []byte, useAES bool, ptr objptr, rd io.Reader) io.Reader
 		rd = &cbcReader{cbc: cbc, rd: rd, buf: make([]byte, 16)}
 	} else {
 		c, _ := rc4.NewCipher(key)
-		rd = &cipher.StreamReader{c, rd}
+		rd = &cipher.StreamReader{S: c, R: rd}
 	}
 	return rd
 }
@@ -1094,18 +1151,58 @@ type cbcReader struct {
 	rd   io.Reader
 	buf  []byte
 	pend []byte
+	next []byte // block read ahead, to drop the padding of the last block
 }
 
 func (r *cbcReader) Read(b []byte) (n int, err error) {
 	if len(r.pend) == 0 {
-		_, err = io.ReadFull(r.rd, r.buf)
-		if err != nil {
+		if r.next == nil {
+			r.next = make([]byte, len(r.buf))
+			if _, err = io.ReadFull(r.rd, r.next); err != nil {
+				return 0, err
+			}
+			r.cbc.CryptBlocks(r.next, r.next)
+		}
+		r.buf, r.next = r.next, r.buf
+		_, err = io.ReadFull(r.rd, r.next)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			r.pend = unpad(r.buf)
+			r.next = nil
+			r.rd = eofReader{}
+			if len(r.pend) == 0 {
+				return 0, io.EOF
+			}
+		} else if err != nil {
 			return 0, err
+		} else {
+			r.cbc.CryptBlocks(r.next, r.next)
+			r.pend = r.buf
 		}
-		r.cbc.CryptBlocks(r.buf, r.buf)
-		r.pend = r.buf
 	}
 	n = copy(b, r.pend)
 	r.pend = r.pend[n:]
 	return n, nil
 }
+
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+// unpad removes the PKCS#5 padding of AES encrypted data, if valid
+func unpad(b []byte) []byte {
+	if len(b) == 0 {
+		return b
+	}
+	n := int(b[len(b)-1])
+	if n == 0 || n > aes.BlockSize || n > len(b) {
+		return b
+	}
+	for _, c := range b[len(b)-n:] {
+		if int(c) != n {
+			return b
+		}
+	}
+	return b[:len(b)-n]
+}
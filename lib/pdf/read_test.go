@@ -0,0 +1,50 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// the lazily read fonts, page numbers and destinations of a reader are shared by its goroutines, which go
+// test -race checks
+func TestConcurrentReads(t *testing.T) {
+	content := func(s string) string {
+		ops := fmt.Sprintf("BT /F1 12 Tf 72 700 Td (%s) Tj ET", s)
+		return fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(ops), ops)
+	}
+	data := writePDF(0,
+		"<< /Type /Catalog /Pages 2 0 R /Dests << /intro [3 0 R /Fit] >> >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 6 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 7 0 R /Annots [8 0 R] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		content("Introduction"),
+		content("See the introduction"),
+		"<< /Type /Annot /Subtype /Link /Rect [72 690 200 715] /Dest /intro >>",
+	)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			annots := r.Annotations()
+			if len(annots) != 1 || annots[0].Page != 2 || annots[0].Dest != 1 || annots[0].Text != "See the introduction" {
+				t.Errorf("annotations %+v", annots)
+			}
+			var text bytes.Buffer
+			for _, s := range r.Page(1).Content().Text {
+				text.WriteString(s.S)
+			}
+			if text.String() != "Introduction" {
+				t.Errorf("text %q", text.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
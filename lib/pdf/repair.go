@@ -0,0 +1,322 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Repair of damaged files, common with scanners and mail gateways: data before the header or after
+// %%EOF, a wrong startxref offset, and missing or inconsistent cross-reference sections, which are
+// rebuilt from the "N G obj" markers and the trailers found in the file.
+
+// Repairs describes the damage worked around to open the file, or is empty if the file is well formed
+func (r *Reader) Repairs() []string {
+	return r.repairs
+}
+
+func (r *Reader) repairf(format string, args ...interface{}) {
+	r.repairs = append(r.repairs, fmt.Sprintf(format, args...))
+}
+
+// catch runs f, turning the panics of the parser into errors
+func catch(f func() error) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("malformed PDF: %v", e)
+		}
+	}()
+	return f()
+}
+
+// findHeader returns the offset of the %PDF- header within the first kilobyte of the file, or -1
+func findHeader(f io.ReaderAt, size int64) int64 {
+	const headerChunk = 1024
+	buf := make([]byte, headerChunk)
+	n, _ := f.ReadAt(buf, 0)
+	return int64(bytes.Index(buf[:n], []byte("%PDF-")))
+}
+
+// readTail finds the last startxref of the file in its last kilobyte, ignoring what follows %%EOF
+func (r *Reader) readTail() (int64, error) {
+	const endChunk = 1024
+	pos := r.end - endChunk
+	if pos < 0 {
+		pos = 0
+	}
+	buf := make([]byte, r.end-pos)
+	n, _ := r.f.ReadAt(buf, pos)
+	buf = buf[:n]
+	eof := bytes.LastIndex(buf, []byte("%%EOF"))
+	if eof < 0 {
+		r.repairf("missing %%%%EOF")
+	} else {
+		if junk := bytes.TrimRight(buf[eof+5:], "\x00\r\n\t\f "); len(junk) > 0 {
+			r.repairf("ignored %d bytes after %%%%EOF", len(buf)-eof-5)
+		}
+		buf = append(buf[:eof:eof], '\n')
+	}
+	i := findLastLine(buf, "startxref")
+	if i < 0 {
+		return 0, fmt.Errorf("malformed PDF file: missing final startxref")
+	}
+	var startxref int64
+	err := catch(func() error {
+		b := newBuffer(bytes.NewReader(buf[i:]), pos+int64(i))
+		b.allowEOF = true
+		b.readToken()
+		var ok bool
+		if startxref, ok = b.readToken().(int64); !ok || startxref < 0 || startxref >= r.end {
+			return fmt.Errorf("malformed PDF file: startxref not followed by offset")
+		}
+		return nil
+	})
+	return startxref, err
+}
+
+// number of cross-reference entries which checkXref compares with the objects they point to
+const xrefSample = 32
+
+// checkXref makes sure that the trailer names the document catalog and that the cross-reference
+// entries point to the objects they describe: those of the catalog and of a sample spread over the
+// table, which finds the shifted offsets of damaged files without reading every object on open
+func (r *Reader) checkXref() error {
+	root, ok := r.trailer["Root"].(objptr)
+	if !ok {
+		return fmt.Errorf("malformed PDF: trailer missing /Root entry")
+	}
+	buf := make([]byte, 32)
+	check := func(id int) error {
+		if id < 0 || id >= len(r.xref) {
+			return nil
+		}
+		x := r.xref[id]
+		if x.ptr.id != uint32(id) || x.inStream || x.offset == 0 {
+			return nil
+		}
+		n, _ := r.f.ReadAt(buf, x.offset)
+		if ptr, _, ok := objHeader(buf[:n], 0); !ok || ptr != x.ptr {
+			return fmt.Errorf("malformed PDF: object %d %d not found at offset %d", x.ptr.id, x.ptr.gen, x.offset)
+		}
+		return nil
+	}
+	if err := check(int(root.id)); err != nil {
+		return err
+	}
+	step := max(len(r.xref)/xrefSample, 1)
+	for id := 0; id < len(r.xref); id += step {
+		if err := check(id); err != nil {
+			return err
+		}
+	}
+	// objects streams cannot be read before the decryption of an encrypted file is set up
+	if r.trailer["Encrypt"] == nil && r.safeResolve(objptr{}, root).Kind() != Dict {
+		return fmt.Errorf("malformed PDF: document catalog not found")
+	}
+	return nil
+}
+
+// objHeader parses "N G obj" at the start of buf (after white space), returning the end of the marker
+func objHeader(buf []byte, i int) (objptr, int, bool) {
+	num := func() (int64, bool) {
+		for i < len(buf) && isSpace(buf[i]) {
+			i++
+		}
+		start := i
+		var v int64
+		for i < len(buf) && '0' <= buf[i] && buf[i] <= '9' && i-start < 10 {
+			v = v*10 + int64(buf[i]-'0')
+			i++
+		}
+		return v, i > start
+	}
+	id, ok1 := num()
+	gen, ok2 := num()
+	for i < len(buf) && isSpace(buf[i]) {
+		i++
+	}
+	if !ok1 || !ok2 || id > 1<<32-1 || gen > 65535 || !bytes.HasPrefix(buf[i:], []byte("obj")) {
+		return objptr{}, 0, false
+	}
+	i += 3
+	if i < len(buf) && !isSpace(buf[i]) && !isDelim(buf[i]) {
+		return objptr{}, 0, false
+	}
+	return objptr{uint32(id), uint16(gen)}, i, true
+}
+
+// size of the chunks in which the file is scanned, and of their overlap, which holds the start of the
+// object markers and the words found across two chunks
+const (
+	scanChunk   = 1 << 16
+	scanOverlap = 256
+)
+
+// scan calls found for every occurrence of word in the file, with the chunk holding it (up to
+// scanOverlap bytes before and after), the offset of the chunk in the file and that of word in the chunk
+func (r *Reader) scan(word string, found func(chunk []byte, base int64, at int)) {
+	buf := make([]byte, scanOverlap+scanChunk+scanOverlap)
+	for pos := int64(0); pos < r.end; pos += scanChunk {
+		base := max(pos-scanOverlap, 0)
+		n, _ := r.f.ReadAt(buf[:min(int64(len(buf)), r.end-base)], base)
+		chunk := buf[:n]
+		// occurrences starting before pos were found in the previous chunk, after pos+scanChunk in the next
+		for i := int(pos - base); ; {
+			j := bytes.Index(chunk[i:], []byte(word))
+			if j < 0 || base+int64(i+j) >= pos+scanChunk {
+				break
+			}
+			found(chunk, base, i+j)
+			i += j + len(word)
+		}
+	}
+}
+
+// rebuildXref scans the file for object definitions, the last one of an object number winning as with
+// incremental updates, and merges the trailers and cross-reference stream dictionaries found. The
+// objects of object streams are indexed by indexObjStms, once the file can be decrypted.
+func (r *Reader) rebuildXref() error {
+	var table []xref
+	r.scan("obj", func(data []byte, base int64, at int) {
+		// walk back over "N G " to the start of the marker
+		k := at
+		for field := 0; field < 2; field++ {
+			for k > 0 && isSpace(data[k-1]) {
+				k--
+			}
+			digits := k
+			for k > 0 && '0' <= data[k-1] && data[k-1] <= '9' {
+				k--
+			}
+			if k == digits {
+				k = -1
+				break
+			}
+		}
+		if k < 0 || k == 0 && base > 0 || k > 0 && !isSpace(data[k-1]) && !isDelim(data[k-1]) {
+			return
+		}
+		ptr, _, ok := objHeader(data[k:], 0)
+		if !ok || ptr.id == 0 {
+			return
+		}
+		for len(table) <= int(ptr.id) {
+			table = append(table, xref{})
+		}
+		table[ptr.id] = xref{ptr: ptr, offset: base + int64(k)}
+	})
+	if len(table) == 0 {
+		return fmt.Errorf("malformed PDF: no objects found")
+	}
+	r.xref = table
+
+	trailer := dict{}
+	r.scan("trailer", func(_ []byte, base int64, at int) {
+		offset := base + int64(at+len("trailer"))
+		catch(func() error {
+			b := newBuffer(io.NewSectionReader(r.f, offset, r.end-offset), offset)
+			b.allowEOF = true
+			if d, ok := b.readObject().(dict); ok {
+				for k, v := range d {
+					trailer[k] = v
+				}
+			}
+			return nil
+		})
+	})
+
+	// cross-reference streams hold the trailer of newer files; object streams are kept for later
+	type found struct {
+		offset int64
+		ptr    objptr
+		hdr    dict
+	}
+	var xrefStms []found
+	var catalog objptr
+	for _, x := range table {
+		if x.offset == 0 {
+			continue
+		}
+		catch(func() error {
+			b := newBuffer(io.NewSectionReader(r.f, x.offset, r.end-x.offset), x.offset)
+			b.allowEOF = true
+			def, ok := b.readObject().(objdef)
+			if !ok {
+				return nil
+			}
+			switch obj := def.obj.(type) {
+			case stream:
+				switch obj.hdr["Type"] {
+				case name("XRef"):
+					xrefStms = append(xrefStms, found{x.offset, x.ptr, obj.hdr})
+				case name("ObjStm"):
+					r.objStms = append(r.objStms, x.ptr)
+				}
+			case dict:
+				if obj["Type"] == name("Catalog") {
+					catalog = x.ptr
+				}
+			}
+			return nil
+		})
+	}
+	sort.Slice(xrefStms, func(i, j int) bool { return xrefStms[i].offset < xrefStms[j].offset })
+	sort.Slice(r.objStms, func(i, j int) bool { return table[r.objStms[i].id].offset < table[r.objStms[j].id].offset })
+	for _, x := range xrefStms {
+		for _, k := range []name{"Root", "Info", "ID", "Encrypt"} {
+			if v, ok := x.hdr[k]; ok {
+				trailer[k] = v
+			}
+		}
+		r.trailerptr = x.ptr
+	}
+	delete(trailer, "Prev")
+	delete(trailer, "XRefStm")
+	trailer["Size"] = int64(len(table))
+	if _, ok := trailer["Root"].(objptr); !ok && catalog != (objptr{}) {
+		trailer["Root"] = catalog
+	}
+	r.trailer = trailer
+	return nil
+}
+
+// indexObjStms adds to a rebuilt cross-reference table the objects of the object streams found, unless
+// they are also defined in the file itself
+func (r *Reader) indexObjStms() {
+	inStream := map[uint32]objptr{}
+	for _, ptr := range r.objStms {
+		catch(func() error {
+			strm := r.resolve(objptr{}, ptr)
+			b := newBuffer(strm.Reader(), 0)
+			b.allowEOF = true
+			for i := int64(0); i < strm.Key("N").Int64(); i++ {
+				id, ok1 := b.readToken().(int64)
+				_, ok2 := b.readToken().(int64)
+				if !ok1 || !ok2 || id <= 0 || id > 1<<32-1 {
+					break
+				}
+				inStream[uint32(id)] = ptr
+			}
+			return nil
+		})
+	}
+	for id, strm := range inStream {
+		for len(r.xref) <= int(id) {
+			r.xref = append(r.xref, xref{})
+		}
+		if x := r.xref[id]; x.ptr.id != id || x.offset == 0 {
+			r.xref[id] = xref{ptr: objptr{id, 0}, inStream: true, stream: strm}
+		}
+	}
+	r.trailer["Size"] = int64(len(r.xref))
+	if _, ok := r.trailer["Root"].(objptr); ok {
+		return
+	}
+	for id := range inStream {
+		if r.safeResolve(objptr{}, objptr{id, 0}).Key("Type").Name() == "Catalog" {
+			r.trailer["Root"] = objptr{id, 0}
+			return
+		}
+	}
+}
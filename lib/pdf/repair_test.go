@@ -0,0 +1,85 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// minimalPDF returns a one page file whose cross-reference table points to offsets shifted by skew
+func minimalPDF(skew int) []byte {
+	return writePDF(skew,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+	)
+}
+
+// writePDF returns a file of the objects, numbered from 1, whose first one is the catalog
+func writePDF(skew int, objs ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	var offsets []int
+	for i, obj := range objs {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off+skew)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+	return b.Bytes()
+}
+
+func TestRepair(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		data    []byte
+		repairs int
+	}{
+		{"well formed", minimalPDF(0), 0},
+		{"preamble", append([]byte("Content-Type: application/pdf\r\n\r\n"), minimalPDF(0)...), 1},
+		{"trailing garbage", append(minimalPDF(0), "\r\n--boundary--\r\n"...), 1},
+		{"inconsistent xref", minimalPDF(3), 1},
+		{"no xref", bytes.Replace(minimalPDF(0), []byte("xref\n0 4"), []byte("junk\n0 4"), 1), 1},
+	} {
+		r, err := NewReader(bytes.NewReader(c.data), int64(len(c.data)))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if len(r.Repairs()) != c.repairs {
+			t.Errorf("%s: repairs %q", c.name, r.Repairs())
+		}
+		if n := r.NumPage(); n != 1 {
+			t.Errorf("%s: %d pages", c.name, n)
+		}
+	}
+}
+
+// the file is rebuilt from chunks, across which markers are found once
+func TestRepairChunks(t *testing.T) {
+	file := func(pad int) []byte {
+		return writePDF(3,
+			"<< /Type /Catalog /Pages 3 0 R >>",
+			"("+strings.Repeat("x", pad)+")",
+			"<< /Type /Pages /Kids [4 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 3 0 R /MediaBox [0 0 612 792] >>",
+		)
+	}
+	start := 2*scanChunk - bytes.Index(file(0), []byte("3 0 obj")) - 10
+	for pad := start; pad < start+16; pad++ {
+		data := file(pad)
+		r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Errorf("3 0 obj at %d: %v", bytes.Index(data, []byte("3 0 obj")), err)
+			continue
+		}
+		if n := r.NumPage(); n != 1 || len(r.xref) != 5 || r.xref[3].offset != int64(bytes.Index(data, []byte("3 0 obj"))) {
+			t.Errorf("3 0 obj at %d: %d pages, xref %v", bytes.Index(data, []byte("3 0 obj")), n, r.xref)
+		}
+	}
+}
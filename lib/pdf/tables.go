@@ -0,0 +1,533 @@
+package pdf
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Tables: the horizontal and vertical rulings of a page which cross each other make the grids of ruled
+// tables, and horizontal ones stacked over the same width, three at least, those of the tables ruled
+// between their rows only. The columns and rows which no ruling separates come from the alignment of the
+// text: a band of whitespace running down all the lines of a column, or all but one of many, separates
+// columns, and the lines of a row which each fill several cells are rows of their own. Where the ruling
+// between two cells is missing, or text runs over the whitespace between them, one cell spans both.
+
+const ruleTolerance = 2 // the distance in points within which rulings meet, or make one line
+
+// A Table is a grid of cells found on a page.
+type Table struct {
+	Rect
+	Rows, Cols int
+	Cells      []Cell // row by row, each spanning cell at its top left position
+}
+
+// A Cell is a cell of a table, which may span several rows and columns.
+type Cell struct {
+	Rect
+	Row, Col         int
+	RowSpan, ColSpan int
+	Text             string // the lines of the cell, separated by newlines
+}
+
+// Grid returns the text of the cells as rows of columns, that of the spanning cells at their top left
+// position and nothing in the other ones
+func (t Table) Grid() [][]string {
+	grid := make([][]string, t.Rows)
+	for i := range grid {
+		grid[i] = make([]string, t.Cols)
+	}
+	for _, c := range t.Cells {
+		grid[c.Row][c.Col] = c.Text
+	}
+	return grid
+}
+
+// CSV returns the table as comma-separated values (RFC 4180)
+func (t Table) CSV() string {
+	var b strings.Builder
+	csv.NewWriter(&b).WriteAll(t.Grid())
+	return b.String()
+}
+
+// Markdown returns the table in GitHub Flavored Markdown, its first row as the header
+func (t Table) Markdown() string {
+	var b strings.Builder
+	for i, row := range t.Grid() {
+		b.WriteString("|")
+		for _, s := range row {
+			s = strings.ReplaceAll(s, "|", `\|`)
+			s = strings.ReplaceAll(s, "\n", "<br>")
+			b.WriteString(" " + s + " |")
+		}
+		b.WriteString("\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", t.Cols) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Tables returns the tables of the page, top to bottom.
+func (p Page) Tables() (tables []Table, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			tables = nil
+			err = errors.New(fmt.Sprint(r))
+		}
+	}()
+	return findTables(p.Content()), nil
+}
+
+// A rule is a horizontal or vertical ruling: at is its y or x, and from and to its extent along it
+type rule struct {
+	at, from, to float64
+}
+
+func crosses(h, v rule) bool {
+	return v.at >= h.from-ruleTolerance && v.at <= h.to+ruleTolerance && h.at >= v.from-ruleTolerance && h.at <= v.to+ruleTolerance
+}
+
+// mergeRules joins the rulings along the same line which touch or overlap, as the lines drawn in pieces or
+// as thin rectangles
+func mergeRules(rules []rule) []rule {
+	sort.Slice(rules, func(i, j int) bool { return rules[i].at < rules[j].at })
+	var merged []rule
+	for i := 0; i < len(rules); {
+		j, at := i, 0.0
+		for ; j < len(rules) && rules[j].at-rules[i].at <= ruleTolerance; j++ {
+			at += rules[j].at
+		}
+		at /= float64(j - i)
+		line := rules[i:j]
+		sort.Slice(line, func(a, b int) bool { return line[a].from < line[b].from })
+		first := len(merged)
+		for _, r := range line {
+			if n := len(merged) - 1; n >= first && r.from <= merged[n].to+ruleTolerance {
+				merged[n].to = max(merged[n].to, r.to)
+				continue
+			}
+			merged = append(merged, rule{at, r.from, r.to})
+		}
+		i = j
+	}
+	return merged
+}
+
+// covered returns the share of the extent from from to to that the rulings along at cover
+func covered(rules []rule, at, from, to float64) float64 {
+	var spans [][2]float64
+	for _, r := range rules {
+		if math.Abs(r.at-at) <= ruleTolerance && r.to > from && r.from < to {
+			spans = append(spans, [2]float64{max(r.from, from), min(r.to, to)})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	length, end := 0.0, from
+	for _, s := range spans {
+		if s[1] > end {
+			length += s[1] - max(s[0], end)
+			end = s[1]
+		}
+	}
+	return length / (to - from)
+}
+
+// cluster returns the means of the values within tolerance of each other, in increasing order
+func cluster(values []float64, tolerance float64) []float64 {
+	sort.Float64s(values)
+	var means []float64
+	for i := 0; i < len(values); {
+		j, sum := i, 0.0
+		for ; j < len(values) && values[j]-values[i] <= tolerance; j++ {
+			sum += values[j]
+		}
+		means = append(means, sum/float64(j-i))
+		i = j
+	}
+	return means
+}
+
+func findTables(c Content) []Table {
+	var hs, vs []rule
+	for _, s := range c.Rulings {
+		if s.horizontal() {
+			hs = append(hs, rule{(s.From.Y + s.To.Y) / 2, min(s.From.X, s.To.X), max(s.From.X, s.To.X)})
+		} else if s.vertical() {
+			vs = append(vs, rule{(s.From.X + s.To.X) / 2, min(s.From.Y, s.To.Y), max(s.From.Y, s.To.Y)})
+		}
+	}
+	hs, vs = mergeRules(hs), mergeRules(vs)
+
+	// the sets of rulings crossing each other
+	parent := make([]int, len(hs)+len(vs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i, h := range hs {
+		for j, v := range vs {
+			if crosses(h, v) {
+				parent[find(i)] = find(len(hs) + j)
+			}
+		}
+	}
+	var roots []int
+	sets := map[int]*grid{}
+	for i := range parent {
+		root := find(i)
+		if sets[root] == nil {
+			sets[root] = &grid{}
+			roots = append(roots, root)
+		}
+		if i < len(hs) {
+			sets[root].hs = append(sets[root].hs, hs[i])
+		} else {
+			sets[root].vs = append(sets[root].vs, vs[i-len(hs)])
+		}
+	}
+
+	var grids []*grid
+	var loose []rule // horizontal rulings of no grid
+	for _, root := range roots {
+		g := sets[root]
+		if len(g.hs) >= 2 && len(g.vs) >= 2 && len(g.hs)+len(g.vs) >= 5 {
+			grids = append(grids, g)
+		} else {
+			loose = append(loose, g.hs...)
+		}
+	}
+	grids = append(grids, stacks(loose)...)
+
+	var boxes []box
+	for _, t := range c.Text {
+		if !t.Vertical && math.Abs(t.FontSize) >= 0.01 && !(t.S == "\n" && t.W == 0) {
+			boxes = append(boxes, newBox(t))
+		}
+	}
+	var tables []Table
+	for _, g := range grids {
+		if t, ok := g.table(boxes); ok {
+			tables = append(tables, t)
+		}
+	}
+	sort.SliceStable(tables, func(i, j int) bool { return tables[i].Max.Y > tables[j].Max.Y })
+	return tables
+}
+
+// stacks gathers the horizontal rulings of no grid into those stacked within the width of the topmost one,
+// three at least
+func stacks(hs []rule) []*grid {
+	sort.Slice(hs, func(i, j int) bool { return hs[i].at > hs[j].at })
+	used := make([]bool, len(hs))
+	var grids []*grid
+	for i, top := range hs {
+		if used[i] {
+			continue
+		}
+		stack := []int{i}
+		for j := i + 1; j < len(hs); j++ {
+			if r := hs[j]; !used[j] && min(top.to, r.to)-max(top.from, r.from) >= 0.8*(r.to-r.from) {
+				stack = append(stack, j)
+			}
+		}
+		if len(stack) < 3 {
+			continue
+		}
+		g := &grid{}
+		for _, j := range stack {
+			g.hs = append(g.hs, hs[j])
+			used[j] = true
+		}
+		grids = append(grids, g)
+	}
+	return grids
+}
+
+// A grid is the rulings of a table
+type grid struct {
+	hs, vs []rule
+}
+
+// A bound is a side of the columns or rows of a table, ruled or in the whitespace between their text
+type bound struct {
+	at    float64
+	ruled bool
+}
+
+// A tableLine is a line of text of a table, its words cut at the rulings of its columns
+type tableLine struct {
+	y0, y1 float64
+	words  []Word
+}
+
+func center(r Rect) Point { return Point{(r.Min.X + r.Max.X) / 2, (r.Min.Y + r.Max.Y) / 2} }
+
+// table reads the cells of the grid from the glyphs inside it
+func (g *grid) table(boxes []box) (Table, bool) {
+	// the bounds of the rulings, and those of the grid where no ruling closes it
+	var xs, ys []bound
+	for _, x := range cluster(ats(g.vs), 3) {
+		xs = append(xs, bound{x, true})
+	}
+	for _, y := range cluster(ats(g.hs), 3) {
+		ys = append(ys, bound{y, true})
+	}
+	left, right := extent(g.hs)
+	bottom, top := extent(g.vs)
+	if len(xs) == 0 || left < xs[0].at-3 {
+		xs = append([]bound{{left, false}}, xs...)
+	}
+	if right > xs[len(xs)-1].at+3 {
+		xs = append(xs, bound{right, false})
+	}
+	if bottom < ys[0].at-3 {
+		ys = append([]bound{{bottom, false}}, ys...)
+	}
+	if top > ys[len(ys)-1].at+3 {
+		ys = append(ys, bound{top, false})
+	}
+	reverse(ys) // top to bottom
+
+	var inside []box
+	for _, b := range boxes {
+		x, y := (b.x0+b.x1)/2, b.base()
+		if x > xs[0].at && x < xs[len(xs)-1].at && y < ys[0].at && y > ys[len(ys)-1].at {
+			inside = append(inside, b)
+		}
+	}
+	size := medianSize(inside)
+	if size == 0 {
+		return Table{}, false
+	}
+
+	var lines []tableLine
+	for _, l := range splitLines(inside) {
+		columns := make([][]box, len(xs)-1)
+		for _, b := range l {
+			c := column(xs, (b.x0+b.x1)/2)
+			columns[c] = append(columns[c], b)
+		}
+		tl := tableLine{y0: math.Inf(1), y1: math.Inf(-1)}
+		for _, c := range columns {
+			if w, ok := words(c); ok {
+				tl.words = append(tl.words, w.line.Words...)
+				tl.y0, tl.y1 = min(tl.y0, w.y0), max(tl.y1, w.y1)
+			}
+		}
+		if len(tl.words) > 0 {
+			lines = append(lines, tl)
+		}
+	}
+
+	// the columns aligned within those of the rulings
+	for c := len(xs) - 2; c >= 0; c-- {
+		var spans [][][2]float64
+		for _, l := range lines {
+			var s [][2]float64
+			for _, w := range l.words {
+				if column(xs, center(w.Rect).X) == c {
+					s = append(s, [2]float64{w.Min.X, w.Max.X})
+				}
+			}
+			if len(s) > 0 {
+				spans = append(spans, s)
+			}
+		}
+		allowed := 0
+		if len(spans) >= 4 {
+			allowed = 1
+		}
+		var added []bound
+		for _, x := range alignedGaps(spans, size, allowed) {
+			added = append(added, bound{x, false})
+		}
+		xs = append(xs[:c+1], append(added, xs[c+1:]...)...)
+	}
+
+	// the rows of the lines which each fill several cells
+	for r := len(ys) - 2; r >= 0; r-- {
+		var band []tableLine
+		for _, l := range lines {
+			if y := (l.y0 + l.y1) / 2; y < ys[r].at && y > ys[r+1].at {
+				band = append(band, l)
+			}
+		}
+		rows := len(band) >= 2
+		for _, l := range band {
+			filled := map[int]bool{}
+			for _, w := range l.words {
+				filled[column(xs, center(w.Rect).X)] = true
+			}
+			rows = rows && len(filled) >= 2
+		}
+		if !rows {
+			continue
+		}
+		var added []bound
+		for i := 1; i < len(band); i++ {
+			added = append(added, bound{(band[i-1].y0 + band[i].y1) / 2, false})
+		}
+		ys = append(ys[:r+1], append(added, ys[r+1:]...)...)
+	}
+
+	t := Table{Rect: Rect{Point{xs[0].at, ys[len(ys)-1].at}, Point{xs[len(xs)-1].at, ys[0].at}}, Rows: len(ys) - 1, Cols: len(xs) - 1}
+	if t.Rows < 2 || t.Cols < 2 {
+		return Table{}, false
+	}
+
+	// the sides of the cells: the rulings along most of them, and the whitespace no word runs over
+	rightOpen := func(r, c int) bool {
+		x := xs[c+1]
+		if x.ruled {
+			return covered(g.vs, x.at, ys[r+1].at, ys[r].at) < 0.5
+		}
+		for _, l := range lines {
+			if y := (l.y0 + l.y1) / 2; y < ys[r].at && y > ys[r+1].at {
+				for _, w := range l.words {
+					if w.Min.X < x.at && w.Max.X > x.at {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+	bottomOpen := func(r, c int) bool {
+		y := ys[r+1]
+		return y.ruled && covered(g.hs, y.at, xs[c].at, xs[c+1].at) < 0.5
+	}
+
+	taken := make([][]bool, t.Rows)
+	for r := range taken {
+		taken[r] = make([]bool, t.Cols)
+	}
+	filledRows := 0
+	for r := 0; r < t.Rows; r++ {
+		filled := 0
+		for c := 0; c < t.Cols; c++ {
+			if taken[r][c] {
+				continue
+			}
+			cell := Cell{Row: r, Col: c, RowSpan: 1, ColSpan: 1}
+			for c+cell.ColSpan < t.Cols && rightOpen(r, c+cell.ColSpan-1) && !taken[r][c+cell.ColSpan] {
+				cell.ColSpan++
+			}
+			for r+cell.RowSpan < t.Rows {
+				open := true
+				for i := c; i < c+cell.ColSpan; i++ {
+					open = open && bottomOpen(r+cell.RowSpan-1, i)
+				}
+				if !open {
+					break
+				}
+				cell.RowSpan++
+			}
+			for i := r; i < r+cell.RowSpan; i++ {
+				for j := c; j < c+cell.ColSpan; j++ {
+					taken[i][j] = true
+				}
+			}
+			cell.Rect = Rect{Point{xs[c].at, ys[r+cell.RowSpan].at}, Point{xs[c+cell.ColSpan].at, ys[r].at}}
+			cell.Text = cellText(lines, cell.Rect)
+			if cell.Text != "" {
+				filled++
+			}
+			t.Cells = append(t.Cells, cell)
+		}
+		if filled >= 2 {
+			filledRows++
+		}
+	}
+	return t, filledRows >= 2
+}
+
+// cellText returns the words of the lines within a cell
+func cellText(lines []tableLine, r Rect) string {
+	var text []string
+	for _, l := range lines {
+		var words []string
+		for _, w := range l.words {
+			if p := center(w.Rect); p.X > r.Min.X && p.X < r.Max.X && p.Y > r.Min.Y && p.Y < r.Max.Y {
+				words = append(words, w.S)
+			}
+		}
+		if len(words) > 0 {
+			text = append(text, strings.Join(words, " "))
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+// column returns the column of the bounds in which x is
+func column(xs []bound, x float64) int {
+	c := sort.Search(len(xs), func(i int) bool { return xs[i].at > x }) - 1
+	return max(0, min(c, len(xs)-2))
+}
+
+// alignedGaps returns the middles of the bands of whitespace, at least width wide, that the words of at
+// most allowed lines run over and which have words on both sides
+func alignedGaps(lines [][][2]float64, width float64, allowed int) []float64 {
+	var xs []float64
+	for _, l := range lines {
+		for _, s := range l {
+			xs = append(xs, s[0], s[1])
+		}
+	}
+	sort.Float64s(xs)
+	var gaps []float64
+	start, words := math.NaN(), false
+	for i := 0; i+1 < len(xs); i++ {
+		mid := (xs[i] + xs[i+1]) / 2
+		n := 0
+		for _, l := range lines {
+			for _, s := range l {
+				if s[0] < mid && s[1] > mid {
+					n++
+					break
+				}
+			}
+		}
+		if n > allowed {
+			if !math.IsNaN(start) && xs[i]-start >= width {
+				gaps = append(gaps, (start+xs[i])/2)
+			}
+			start, words = math.NaN(), true
+		} else if words && math.IsNaN(start) {
+			start = xs[i]
+		}
+	}
+	return gaps
+}
+
+func ats(rules []rule) []float64 {
+	var at []float64
+	for _, r := range rules {
+		at = append(at, r.at)
+	}
+	return at
+}
+
+// extent returns the lowest and highest ends of the rulings
+func extent(rules []rule) (from, to float64) {
+	from, to = math.Inf(1), math.Inf(-1)
+	for _, r := range rules {
+		from, to = min(from, r.from), max(to, r.to)
+	}
+	return from, to
+}
+
+func reverse(bounds []bound) {
+	for i, j := 0, len(bounds)-1; i < j; i, j = i+1, j-1 {
+		bounds[i], bounds[j] = bounds[j], bounds[i]
+	}
+}
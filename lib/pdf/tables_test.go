@@ -0,0 +1,57 @@
+package pdf
+
+import "testing"
+
+func hline(y, x0, x1 float64) Segment { return Segment{Point{x0, y}, Point{x1, y}} }
+func vline(x, y0, y1 float64) Segment { return Segment{Point{x, y0}, Point{x, y1}} }
+
+func TestRuledTable(t *testing.T) {
+	// a grid of 3 rows and 3 columns, the header of the last two one cell
+	var c Content
+	for _, y := range []float64{700, 680, 660, 640} {
+		c.Rulings = append(c.Rulings, hline(y, 50, 350))
+	}
+	c.Rulings = append(c.Rulings, vline(50, 640, 700), vline(150, 640, 700), vline(250, 640, 680), vline(350, 640, 700))
+	for i, row := range [][3]string{{"Name", "Amount", ""}, {"apples", "3", "1.50"}, {"pears", "10", "4.00"}} {
+		y := 687 - 20*float64(i)
+		c.Text = show(c.Text, 55, y, 10, row[0])
+		c.Text = show(c.Text, 155, y, 10, row[1])
+		c.Text = show(c.Text, 255, y, 10, row[2])
+	}
+
+	tables := findTables(c)
+	if len(tables) != 1 {
+		t.Fatalf("got %d tables, want 1", len(tables))
+	}
+	tb := tables[0]
+	if tb.Rows != 3 || tb.Cols != 3 || len(tb.Cells) != 8 || tb.Cells[1].ColSpan != 2 {
+		t.Errorf("got %d rows, %d columns and cells %+v", tb.Rows, tb.Cols, tb.Cells)
+	}
+	if got, want := tb.CSV(), "Name,Amount,\napples,3,1.50\npears,10,4.00\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAlignedTable(t *testing.T) {
+	// a table ruled above and below its header and at its end, its columns aligned
+	var c Content
+	for _, y := range []float64{700, 680, 630} {
+		c.Rulings = append(c.Rulings, hline(y, 50, 350))
+	}
+	for i, row := range [][3]string{{"Item", "Qty", "Price"}, {"green apples", "3", "1.50"}, {"pears", "10", "4.00"}} {
+		y := []float64{687, 667, 650}[i]
+		c.Text = show(c.Text, 55, y, 10, row[0])
+		c.Text = show(c.Text, 200, y, 10, row[1])
+		c.Text = show(c.Text, 280, y, 10, row[2])
+	}
+	c.Text = show(c.Text, 55, 600, 10, "Not in the table")
+
+	tables := findTables(c)
+	if len(tables) != 1 {
+		t.Fatalf("got %d tables, want 1", len(tables))
+	}
+	want := "| Item | Qty | Price |\n| --- | --- | --- |\n| green apples | 3 | 1.50 |\n| pears | 10 | 4.00 |\n"
+	if got := tables[0].Markdown(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
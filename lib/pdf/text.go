@@ -1,6 +1,7 @@
 package pdf
 
 import (
+	"strings"
 	"unicode"
 	"unicode/utf16"
 )
@@ -8,7 +9,7 @@ import (
 const noRune = unicode.ReplacementChar
 
 func isPDFDocEncoded(s string) bool {
-	if isUTF16(s) {
+	if isUTF16(s) || isUTF8(s) {
 		return false
 	}
 	for i := 0; i < len(s); i++ {
@@ -39,6 +40,11 @@ func isUTF16(s string) bool {
 	return len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff && len(s)%2 == 0
 }
 
+// isUTF8 tells whether s is a UTF-8 text string of PDF 2.0, which starts with a byte order mark
+func isUTF8(s string) bool {
+	return strings.HasPrefix(s, "\xef\xbb\xbf")
+}
+
 func utf16Decode(s string) string {
 	var u []uint16
 	for i := 0; i < len(s); i += 2 {
@@ -152,3 +158,112 @@ var macRomanEncoding = [256]rune{
 	0xf8ff, 0x00d2, 0x00da, 0x00db, 0x00d9, 0x0131, 0x02c6, 0x02dc,
 	0x00af, 0x02d8, 0x02d9, 0x02da, 0x00b8, 0x02dd, 0x02db, 0x02c7,
 }
+
+// See PDF 32000-1:2008, Table D.2: StandardEncoding, the built-in encoding of the standard Latin fonts
+var standardEncoding = [256]rune{
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	0x0020, 0x0021, 0x0022, 0x0023, 0x0024, 0x0025, 0x0026, 0x2019,
+	0x0028, 0x0029, 0x002a, 0x002b, 0x002c, 0x002d, 0x002e, 0x002f,
+	0x0030, 0x0031, 0x0032, 0x0033, 0x0034, 0x0035, 0x0036, 0x0037,
+	0x0038, 0x0039, 0x003a, 0x003b, 0x003c, 0x003d, 0x003e, 0x003f,
+	0x0040, 0x0041, 0x0042, 0x0043, 0x0044, 0x0045, 0x0046, 0x0047,
+	0x0048, 0x0049, 0x004a, 0x004b, 0x004c, 0x004d, 0x004e, 0x004f,
+	0x0050, 0x0051, 0x0052, 0x0053, 0x0054, 0x0055, 0x0056, 0x0057,
+	0x0058, 0x0059, 0x005a, 0x005b, 0x005c, 0x005d, 0x005e, 0x005f,
+	0x2018, 0x0061, 0x0062, 0x0063, 0x0064, 0x0065, 0x0066, 0x0067,
+	0x0068, 0x0069, 0x006a, 0x006b, 0x006c, 0x006d, 0x006e, 0x006f,
+	0x0070, 0x0071, 0x0072, 0x0073, 0x0074, 0x0075, 0x0076, 0x0077,
+	0x0078, 0x0079, 0x007a, 0x007b, 0x007c, 0x007d, 0x007e, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, 0x00a1, 0x00a2, 0x00a3, 0x2044, 0x00a5, 0x0192, 0x00a7,
+	0x00a4, 0x0027, 0x201c, 0x00ab, 0x2039, 0x203a, 0xfb01, 0xfb02,
+	noRune, 0x2013, 0x2020, 0x2021, 0x00b7, noRune, 0x00b6, 0x2022,
+	0x201a, 0x201e, 0x201d, 0x00bb, 0x2026, 0x2030, noRune, 0x00bf,
+	noRune, 0x0060, 0x00b4, 0x02c6, 0x02dc, 0x00af, 0x02d8, 0x02d9,
+	0x00a8, noRune, 0x02da, 0x00b8, noRune, 0x02dd, 0x02db, 0x02c7,
+	0x2014, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, 0x00c6, noRune, 0x00aa, noRune, noRune, noRune, noRune,
+	0x0141, 0x00d8, 0x0152, 0x00ba, noRune, noRune, noRune, noRune,
+	noRune, 0x00e6, noRune, noRune, noRune, 0x0131, noRune, noRune,
+	0x0142, 0x00f8, 0x0153, 0x00df, noRune, noRune, noRune, noRune,
+}
+
+// See PDF 32000-1:2008, Table D.5: built-in encoding of the Symbol font, of which the serif and sans
+// serif registered, copyright and trademark signs are given their regular code points
+var symbolEncoding = [256]rune{
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	0x0020, 0x0021, 0x2200, 0x0023, 0x2203, 0x0025, 0x0026, 0x220b,
+	0x0028, 0x0029, 0x2217, 0x002b, 0x002c, 0x2212, 0x002e, 0x002f,
+	0x0030, 0x0031, 0x0032, 0x0033, 0x0034, 0x0035, 0x0036, 0x0037,
+	0x0038, 0x0039, 0x003a, 0x003b, 0x003c, 0x003d, 0x003e, 0x003f,
+	0x2245, 0x0391, 0x0392, 0x03a7, 0x2206, 0x0395, 0x03a6, 0x0393,
+	0x0397, 0x0399, 0x03d1, 0x039a, 0x039b, 0x039c, 0x039d, 0x039f,
+	0x03a0, 0x0398, 0x03a1, 0x03a3, 0x03a4, 0x03a5, 0x03c2, 0x2126,
+	0x039e, 0x03a8, 0x0396, 0x005b, 0x2234, 0x005d, 0x22a5, 0x005f,
+	0xf8e5, 0x03b1, 0x03b2, 0x03c7, 0x03b4, 0x03b5, 0x03c6, 0x03b3,
+	0x03b7, 0x03b9, 0x03d5, 0x03ba, 0x03bb, 0x00b5, 0x03bd, 0x03bf,
+	0x03c0, 0x03b8, 0x03c1, 0x03c3, 0x03c4, 0x03c5, 0x03d6, 0x03c9,
+	0x03be, 0x03c8, 0x03b6, 0x007b, 0x007c, 0x007d, 0x223c, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	0x20ac, 0x03d2, 0x2032, 0x2264, 0x2044, 0x221e, 0x0192, 0x2663,
+	0x2666, 0x2665, 0x2660, 0x2194, 0x2190, 0x2191, 0x2192, 0x2193,
+	0x00b0, 0x00b1, 0x2033, 0x2265, 0x00d7, 0x221d, 0x2202, 0x2022,
+	0x00f7, 0x2260, 0x2261, 0x2248, 0x2026, 0xf8e6, 0xf8e7, 0x21b5,
+	0x2135, 0x2111, 0x211c, 0x2118, 0x2297, 0x2295, 0x2205, 0x2229,
+	0x222a, 0x2283, 0x2287, 0x2284, 0x2282, 0x2286, 0x2208, 0x2209,
+	0x2220, 0x2207, 0x00ae, 0x00a9, 0x2122, 0x220f, 0x221a, 0x22c5,
+	0x00ac, 0x2227, 0x2228, 0x21d4, 0x21d0, 0x21d1, 0x21d2, 0x21d3,
+	0x25ca, 0x2329, 0x00ae, 0x00a9, 0x2122, 0x2211, 0xf8eb, 0xf8ec,
+	0xf8ed, 0xf8ee, 0xf8ef, 0xf8f0, 0xf8f1, 0xf8f2, 0xf8f3, 0xf8f4,
+	noRune, 0x232a, 0x222b, 0x2320, 0xf8f5, 0x2321, 0xf8f6, 0xf8f7,
+	0xf8f8, 0xf8f9, 0xf8fa, 0xf8fb, 0xf8fc, 0xf8fd, 0xf8fe, noRune,
+}
+
+// See PDF 32000-1:2008, Table D.6: built-in encoding of the ZapfDingbats font
+var zapfDingbatsEncoding = [256]rune{
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	0x0020, 0x2701, 0x2702, 0x2703, 0x2704, 0x260e, 0x2706, 0x2707,
+	0x2708, 0x2709, 0x261b, 0x261e, 0x270c, 0x270d, 0x270e, 0x270f,
+	0x2710, 0x2711, 0x2712, 0x2713, 0x2714, 0x2715, 0x2716, 0x2717,
+	0x2718, 0x2719, 0x271a, 0x271b, 0x271c, 0x271d, 0x271e, 0x271f,
+	0x2720, 0x2721, 0x2722, 0x2723, 0x2724, 0x2725, 0x2726, 0x2727,
+	0x2605, 0x2729, 0x272a, 0x272b, 0x272c, 0x272d, 0x272e, 0x272f,
+	0x2730, 0x2731, 0x2732, 0x2733, 0x2734, 0x2735, 0x2736, 0x2737,
+	0x2738, 0x2739, 0x273a, 0x273b, 0x273c, 0x273d, 0x273e, 0x273f,
+	0x2740, 0x2741, 0x2742, 0x2743, 0x2744, 0x2745, 0x2746, 0x2747,
+	0x2748, 0x2749, 0x274a, 0x274b, 0x25cf, 0x274d, 0x25a0, 0x274f,
+	0x2750, 0x2751, 0x2752, 0x25b2, 0x25bc, 0x25c6, 0x2756, 0x25d7,
+	0x2758, 0x2759, 0x275a, 0x275b, 0x275c, 0x275d, 0x275e, noRune,
+	0x2768, 0x2769, 0x276a, 0x276b, 0x276c, 0x276d, 0x276e, 0x276f,
+	0x2770, 0x2771, 0x2772, 0x2773, 0x2774, 0x2775, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, noRune, noRune, noRune, noRune, noRune, noRune, noRune,
+	noRune, 0x2761, 0x2762, 0x2763, 0x2764, 0x2765, 0x2766, 0x2767,
+	0x2663, 0x2666, 0x2665, 0x2660, 0x2460, 0x2461, 0x2462, 0x2463,
+	0x2464, 0x2465, 0x2466, 0x2467, 0x2468, 0x2469, 0x2776, 0x2777,
+	0x2778, 0x2779, 0x277a, 0x277b, 0x277c, 0x277d, 0x277e, 0x277f,
+	0x2780, 0x2781, 0x2782, 0x2783, 0x2784, 0x2785, 0x2786, 0x2787,
+	0x2788, 0x2789, 0x278a, 0x278b, 0x278c, 0x278d, 0x278e, 0x278f,
+	0x2790, 0x2791, 0x2792, 0x2793, 0x2794, 0x2192, 0x2194, 0x2195,
+	0x2798, 0x2799, 0x279a, 0x279b, 0x279c, 0x279d, 0x279e, 0x279f,
+	0x27a0, 0x27a1, 0x27a2, 0x27a3, 0x27a4, 0x27a5, 0x27a6, 0x27a7,
+	0x27a8, 0x27a9, 0x27aa, 0x27ab, 0x27ac, 0x27ad, 0x27ae, 0x27af,
+	noRune, 0x27b1, 0x27b2, 0x27b3, 0x27b4, 0x27b5, 0x27b6, 0x27b7,
+	0x27b8, 0x27b9, 0x27ba, 0x27bb, 0x27bc, 0x27bd, 0x27be, noRune,
+}
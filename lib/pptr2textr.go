@@ -104,7 +104,10 @@ func isValidPPT(currentUser, pptDocument *mscfb.File) error {
 		return err
 	}
 	headerToken := binary.LittleEndian.Uint32(b[:])
-	if headerToken != plainDocumentToken && headerToken != encryptedDocumentToken {
+	if headerToken == encryptedDocumentToken {
+		return ErrEncrypted
+	}
+	if headerToken != plainDocumentToken {
 		return fmt.Errorf("invalid UserEditAtom header token %X", headerToken)
 	}
 	return nil
@@ -112,7 +115,7 @@ func isValidPPT(currentUser, pptDocument *mscfb.File) error {
 
 // getUserEditAtomsData extracts "live record" and persist directory offsets
 // according to section 2.1.2 of specification (https://msopenspecs.azureedge.net/files/MS-PPT/%5bMS-PPT%5d-210422.pdf)
-func getUserEditAtomsData(currentUser, pptDocument *mscfb.File) (
+func getUserEditAtomsData(currentUser, pptDocument io.ReaderAt) (
 	persistDirectoryOffsets []int64,
 	liveRecord record,
 	err error,
@@ -152,7 +155,7 @@ func getUserEditAtomsData(currentUser, pptDocument *mscfb.File) (
 
 // getPersistDirectoryEntries transforms offsets into persists directory identifiers and persist offsets according
 // to section 2.1.2 of specification (https://msopenspecs.azureedge.net/files/MS-PPT/%5bMS-PPT%5d-210422.pdf)
-func getPersistDirectoryEntries(pptDocument *mscfb.File, offsets []int64) (map[uint32]int64, error) {
+func getPersistDirectoryEntries(pptDocument io.ReaderAt, offsets []int64) (map[uint32]int64, error) {
 	const persistOffsetEntrySize = 4
 
 	persistDirEntries := make(map[uint32]int64)
@@ -26,12 +26,14 @@ const (
 	recordTypeDrawing                  recordType = 0x040C
 	recordTypeList                     recordType = 0x07D0
 	recordTypeSoundCollection          recordType = 0x07E4
+	recordTypeExOleObjStg              recordType = 0x1011
 	recordTypeTextCharsAtom            recordType = 0x0FA0
 	recordTypeTextBytesAtom            recordType = 0x0FA8
 	recordTypeHeadersFooters           recordType = 0x0FD9
 	recordTypeSlideListWithText        recordType = 0x0FF0
 	recordTypeUserEditAtom             recordType = 0x0FF5
 	recordTypePersistDirectoryAtom     recordType = 0x1772
+	recordTypeCryptSession10Container  recordType = 0x2F14
 	recordTypeRoundTripSlideSyncInfo12 recordType = 0x3714
 )
 
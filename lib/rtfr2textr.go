@@ -0,0 +1,512 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// ---- file rtfr2textr.go ----
+// Text and metadata extraction of Rich Text Format files, following the RTF 1.9.1 specification.
+
+var errRTFInvalid = errors.New("not a RTF file")
+
+// RTFInfo contains the fields of the \info group of a RTF file
+type RTFInfo struct {
+	Title    string
+	Subject  string
+	Author   string
+	Operator string // last person who modified the document
+	Keywords string
+	Comment  string // \doccomm
+	Category string
+	Company  string
+	Version  string
+	Created  time.Time
+	Revised  time.Time
+}
+
+// IsFileRTF checks if the data indicates a RTF file
+func IsFileRTF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(`{\rtf`))
+}
+
+// RTF2Text parses the RTF file represented by r and returns its text, one line per paragraph and tab separated
+// cells for table rows, along with the document information of the \info group
+func RTF2Text(r io.Reader) (string, RTFInfo, error) {
+	p := &rtfParser{
+		r:       bufio.NewReader(r),
+		fonts:   make(map[int]int),
+		ansiCpg: 1252,
+		state:   rtfState{uc: 1, font: -1},
+	}
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(p.r, head); err != nil || !IsFileRTF(head) {
+		return "", p.info, errRTFInvalid
+	}
+	p.r = bufio.NewReader(io.MultiReader(bytes.NewReader(head), p.r))
+	if err := p.parse(); err != nil {
+		return "", p.info, wrapError(err)
+	}
+	p.endParagraph()
+	return strings.TrimRight(p.out.String(), "\n"), p.info, nil
+}
+
+// destinations of the group: where its text goes
+const (
+	rtfBody     = iota // document text
+	rtfSkip            // ignored destination (\*, pictures, style sheets...)
+	rtfFontTbl         // font table, only the charsets are read
+	rtfInfo            // \info group, text outside the fields below is ignored
+	rtfInfoText        // one of the text fields of \info
+	rtfInfoTime        // \creatim or \revtim
+)
+
+// rtfState is the part of the parser state saved by { and restored by }
+type rtfState struct {
+	dest  int
+	field string // name of the \info field being read
+	font  int    // current font, selects the codepage of \'hh bytes
+	uc    int    // number of fallback characters following \uN
+	intbl bool   // the paragraph is part of a table
+}
+
+type rtfParser struct {
+	r       *bufio.Reader
+	state   rtfState
+	stack   []rtfState
+	fonts   map[int]int // font number to codepage, from the font table
+	deff    int         // default font
+	ansiCpg int         // codepage of the document (\ansicpg)
+	high    rune        // high surrogate of a \uN pair waiting for its low half
+	skip    int         // fallback characters of a \uN still to skip
+	bytes   []byte      // pending \'hh bytes, decoded together so that double byte characters survive
+	field   strings.Builder
+	date    [5]int // year, month, day, hour and minute of \creatim or \revtim
+	line    strings.Builder
+	out     strings.Builder
+	info    RTFInfo
+}
+
+// destinations whose content is not text of the document
+var rtfSkipped = map[string]bool{
+	"pict": true, "objdata": true, "colortbl": true, "stylesheet": true, "listtable": true,
+	"listoverridetable": true, "revtbl": true, "rsidtbl": true, "generator": true, "xmlnstbl": true,
+	"themedata": true, "colorschememapping": true, "datastore": true, "latentstyles": true,
+	"fldinst": true, "filetbl": true, "header": true, "footer": true, "headerl": true, "headerr": true,
+	"headerf": true, "footerl": true, "footerr": true, "footerf": true, "private": true, "bkmkstart": true,
+	"bkmkend": true, "pgdsctbl": true, "mmathPr": true, "wgrffmtfilter": true, "docvar": true,
+}
+
+// text fields of the \info group
+var rtfInfoFields = map[string]bool{
+	"title": true, "subject": true, "author": true, "operator": true, "keywords": true,
+	"doccomm": true, "category": true, "company": true,
+}
+
+// control words of \creatim and \revtim, with their index in rtfParser.date
+var rtfDateParts = map[string]int{"yr": 0, "mo": 1, "dy": 2, "hr": 3, "min": 4}
+
+// characters written by control words
+var rtfSymbols = map[string]string{
+	"emdash": "—", "endash": "–", "bullet": "•", "lquote": "‘", "rquote": "’", "ldblquote": "“",
+	"rdblquote": "”", "emspace": " ", "enspace": " ", "qmspace": " ", "tab": "\t", "line": "\n",
+}
+
+func (p *rtfParser) parse() error {
+	ignorable := false // the group started with \*
+	for {
+		c, err := p.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch c {
+		case '{':
+			p.flushBytes()
+			p.skip = 0
+			p.stack = append(p.stack, p.state)
+		case '}':
+			p.flushBytes()
+			p.skip = 0
+			if len(p.stack) == 0 {
+				return nil // end of the document, trailing data is ignored
+			}
+			p.endGroup()
+			p.state = p.stack[len(p.stack)-1]
+			p.stack = p.stack[:len(p.stack)-1]
+		case '\\':
+			word, param, hasParam, err := p.readControl()
+			if err != nil {
+				return err
+			}
+			if word == "*" {
+				ignorable = true
+				continue
+			}
+			if ignorable && p.state.dest != rtfSkip && !p.isKnownDestination(word) {
+				p.state.dest = rtfSkip
+			}
+			ignorable = false
+			if err := p.control(word, param, hasParam); err != nil {
+				return err
+			}
+		case '\r', '\n':
+		default:
+			ignorable = false
+			if p.skip > 0 {
+				p.skip--
+				continue
+			}
+			p.bytes = append(p.bytes, c)
+		}
+	}
+}
+
+// readControl reads a control word with its optional numeric parameter, or a control symbol, after a backslash
+func (p *rtfParser) readControl() (string, int, bool, error) {
+	c, err := p.r.ReadByte()
+	if err != nil {
+		return "", 0, false, err
+	}
+	if !isASCIILetter(c) {
+		if c == '\'' {
+			hex := make([]byte, 2)
+			if _, err := io.ReadFull(p.r, hex); err != nil {
+				return "", 0, false, err
+			}
+			n, _ := strconv.ParseUint(string(hex), 16, 8)
+			return "'", int(n), true, nil
+		}
+		return string(c), 0, false, nil
+	}
+	word := []byte{c}
+	for {
+		c, err = p.r.ReadByte()
+		if err != nil {
+			return string(word), 0, false, nil
+		}
+		if !isASCIILetter(c) {
+			break
+		}
+		word = append(word, c)
+	}
+	var num []byte
+	if c == '-' || (c >= '0' && c <= '9') {
+		num = append(num, c)
+		for {
+			c, err = p.r.ReadByte()
+			if err != nil || c < '0' || c > '9' {
+				break
+			}
+			num = append(num, c)
+		}
+	}
+	if err == nil && c != ' ' {
+		p.r.UnreadByte() // the delimiter is part of the text unless it is a space
+	}
+	if len(num) == 0 || (len(num) == 1 && num[0] == '-') {
+		return string(word), 0, false, nil
+	}
+	n, _ := strconv.Atoi(string(num))
+	return string(word), n, true, nil
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isKnownDestination reports whether a destination marked with \* is still read
+func (p *rtfParser) isKnownDestination(word string) bool {
+	return word == "company" && p.state.dest == rtfInfo
+}
+
+func (p *rtfParser) control(word string, param int, hasParam bool) error {
+	if word == "'" {
+		if p.skip > 0 {
+			p.skip--
+		} else {
+			p.bytes = append(p.bytes, byte(param))
+		}
+		return nil
+	}
+	p.flushBytes()
+	if word == "bin" && hasParam && param > 0 { // binary data is never text
+		_, err := p.r.Discard(param)
+		return err
+	}
+	if p.skip > 0 && len(word) == 1 { // control symbols count as one fallback character
+		p.skip--
+		return nil
+	}
+	if p.state.dest == rtfSkip {
+		return nil
+	}
+
+	switch {
+	case rtfSkipped[word]:
+		p.state.dest = rtfSkip
+	case word == "fonttbl":
+		p.state.dest = rtfFontTbl
+	case word == "info":
+		p.state.dest = rtfInfo
+	case p.state.dest == rtfInfo && rtfInfoFields[word]:
+		p.state.dest, p.state.field = rtfInfoText, word
+		p.field.Reset()
+	case p.state.dest == rtfInfo && (word == "creatim" || word == "revtim"):
+		p.state.dest, p.state.field = rtfInfoTime, word
+		p.date = [5]int{}
+	case p.state.dest == rtfInfoTime:
+		if i, ok := rtfDateParts[word]; ok && hasParam {
+			p.date[i] = param
+		}
+	case p.state.dest == rtfInfo && word == "version" && hasParam:
+		p.info.Version = strconv.Itoa(param)
+
+	case word == "ansi":
+		p.ansiCpg = 1252
+	case word == "mac":
+		p.ansiCpg = 10000
+	case word == "pc":
+		p.ansiCpg = 437
+	case word == "pca":
+		p.ansiCpg = 850
+	case word == "ansicpg" && hasParam:
+		p.ansiCpg = param
+	case word == "deff" && hasParam:
+		p.deff = param
+	case word == "f" && hasParam:
+		p.state.font = param
+		if p.state.dest == rtfFontTbl {
+			p.fonts[param] = 0
+		}
+	case word == "fcharset" && p.state.dest == rtfFontTbl:
+		p.fonts[p.state.font] = rtfCharsetCodepage(param)
+	case word == "cpg" && p.state.dest == rtfFontTbl && hasParam:
+		p.fonts[p.state.font] = param
+	case word == "uc" && hasParam:
+		p.state.uc = param
+	case word == "u" && hasParam:
+		if param < 0 {
+			param += 65536
+		}
+		p.writeUnicode(rune(param))
+		p.skip = p.state.uc
+
+	case word == "pard":
+		p.state.intbl = false
+	case word == "intbl":
+		p.state.intbl = true
+	case word == "par" || word == "\n" || word == "\r":
+		if p.state.intbl {
+			p.write(" ")
+		} else {
+			p.endParagraph()
+		}
+	case word == "sect" || word == "page":
+		p.endParagraph()
+	case word == "cell":
+		p.write("\t")
+	case word == "nestcell":
+		p.write(" ")
+	case word == "row":
+		s := strings.TrimRight(p.line.String(), "\t ")
+		p.line.Reset()
+		p.line.WriteString(s)
+		p.endParagraph()
+	case word == "\\" || word == "{" || word == "}":
+		p.write(word)
+	case word == "~":
+		p.write(" ")
+	case word == "_":
+		p.write("-")
+	default:
+		if s, ok := rtfSymbols[word]; ok {
+			p.write(s)
+		}
+	}
+	return nil
+}
+
+// endGroup stores the \info field closed by the current }
+func (p *rtfParser) endGroup() {
+	parent := rtfBody
+	if len(p.stack) > 0 {
+		parent = p.stack[len(p.stack)-1].dest
+	}
+	if parent == rtfInfo && p.state.dest == rtfInfoText {
+		value := strings.TrimSpace(p.field.String())
+		switch p.state.field {
+		case "title":
+			p.info.Title = value
+		case "subject":
+			p.info.Subject = value
+		case "author":
+			p.info.Author = value
+		case "operator":
+			p.info.Operator = value
+		case "keywords":
+			p.info.Keywords = value
+		case "doccomm":
+			p.info.Comment = value
+		case "category":
+			p.info.Category = value
+		case "company":
+			p.info.Company = value
+		}
+	}
+	if parent == rtfInfo && p.state.dest == rtfInfoTime && p.date[0] > 0 {
+		t := time.Date(p.date[0], time.Month(max(p.date[1], 1)), max(p.date[2], 1), p.date[3], p.date[4], 0, 0, time.Local)
+		if p.state.field == "creatim" {
+			p.info.Created = t
+		} else {
+			p.info.Revised = t
+		}
+	}
+}
+
+// flushBytes decodes the pending text bytes with the codepage of the current font
+func (p *rtfParser) flushBytes() {
+	if len(p.bytes) == 0 {
+		return
+	}
+	b := p.bytes
+	p.bytes = p.bytes[:0]
+	if p.state.dest != rtfBody && p.state.dest != rtfInfoText {
+		return
+	}
+	cpg := p.ansiCpg
+	font := p.state.font
+	if font < 0 {
+		font = p.deff
+	}
+	if c, ok := p.fonts[font]; ok && c != 0 {
+		cpg = c
+	}
+	p.write(decodeCodepage(b, cpg))
+}
+
+// writeUnicode writes the character of \uN, joining UTF-16 surrogate pairs
+func (p *rtfParser) writeUnicode(r rune) {
+	switch {
+	case utf16.IsSurrogate(r) && r < 0xDC00:
+		p.high = r
+	case utf16.IsSurrogate(r):
+		if p.high != 0 {
+			p.write(string(utf16.DecodeRune(p.high, r)))
+		}
+		p.high = 0
+	default:
+		p.high = 0
+		p.write(string(r))
+	}
+}
+
+func (p *rtfParser) write(s string) {
+	switch p.state.dest {
+	case rtfBody:
+		p.line.WriteString(s)
+	case rtfInfoText:
+		p.field.WriteString(s)
+	}
+}
+
+func (p *rtfParser) endParagraph() {
+	if s := strings.TrimSpace(p.line.String()); s != "" {
+		p.out.WriteString(s)
+		p.out.WriteString("\n")
+	}
+	p.line.Reset()
+}
+
+// rtfCharsetCodepage maps a \fcharset value to a Windows codepage, 0 means the codepage of the document
+func rtfCharsetCodepage(charset int) int {
+	switch charset {
+	case 0:
+		return 1252
+	case 77:
+		return 10000
+	case 128:
+		return 932
+	case 129:
+		return 949
+	case 134:
+		return 936
+	case 136:
+		return 950
+	case 161:
+		return 1253
+	case 162:
+		return 1254
+	case 163:
+		return 1258
+	case 177:
+		return 1255
+	case 178:
+		return 1256
+	case 186:
+		return 1257
+	case 204:
+		return 1251
+	case 222:
+		return 874
+	case 238:
+		return 1250
+	case 254:
+		return 437
+	}
+	return 0
+}
+
+var codepages = map[int]encoding.Encoding{
+	437:   charmap.CodePage437,
+	850:   charmap.CodePage850,
+	852:   charmap.CodePage852,
+	866:   charmap.CodePage866,
+	874:   charmap.Windows874,
+	932:   japanese.ShiftJIS,
+	936:   simplifiedchinese.GBK,
+	949:   korean.EUCKR,
+	950:   traditionalchinese.Big5,
+	1250:  charmap.Windows1250,
+	1251:  charmap.Windows1251,
+	1252:  charmap.Windows1252,
+	1253:  charmap.Windows1253,
+	1254:  charmap.Windows1254,
+	1255:  charmap.Windows1255,
+	1256:  charmap.Windows1256,
+	1257:  charmap.Windows1257,
+	1258:  charmap.Windows1258,
+	10000: charmap.Macintosh,
+}
+
+// decodeCodepage converts bytes of a Windows codepage to UTF-8, unknown codepages are read as Windows-1252
+func decodeCodepage(b []byte, cpg int) string {
+	if cpg == 65001 && utf8.Valid(b) {
+		return string(b)
+	}
+	enc, ok := codepages[cpg]
+	if !ok {
+		enc = charmap.Windows1252
+	}
+	s, err := enc.NewDecoder().Bytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return string(s)
+}
@@ -0,0 +1,85 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRTF2Text(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		rtf  string
+		want string
+	}{
+		{"paragraphs", `{\rtf1\ansi\deff0{\fonttbl{\f0 Arial;}}\pard Hello\par World\par}`, "Hello\nWorld"},
+		{"escaped symbols", `{\rtf1 a\{b\}c\\d\~e\_f\tab g\emdash h}`, "a{b}c\\d\u00a0e-f\tg—h"},
+		{"codepage bytes", `{\rtf1\ansi\ansicpg1252 caf\'e9 \'80}`, "café €"},
+		{"font charset", `{\rtf1\ansi\ansicpg1252{\fonttbl{\f0\fcharset0 Arial;}{\f1\fcharset204 Arial Cyr;}}` +
+			`\f1\'cf\'f0\'e8\'e2\'e5\'f2 \f0\'e9}`, "Привет é"},
+		{"double byte codepage", `{\rtf1\ansi{\fonttbl{\f0\fcharset128 MS Mincho;}}\f0\'93\'fa\'96\'7b}`, "日本"},
+		{"unicode with fallback", `{\rtf1\uc1\u233?t\u8364\'80}`, "ét€"},
+		{"unicode fallback count", `{\rtf1\uc2\u26085??\u26412\'3f\'3f!}`, "日本!"},
+		{"negative unicode", `{\rtf1\u-21504?}`, "가"},
+		{"surrogate pair", `{\rtf1\uc0\u-10179\u-8704}`, "😀"},
+		{"fallback count restored", `{\rtf1{\uc3\u233 abc}\u233 de}`, "éée"},
+		{"skipped destinations", `{\rtf1{\colortbl;\red0\green0\blue0;}{\stylesheet{\s0 Normal;}}` +
+			`{\*\generator Writer;}{\*\unknown ignored}{\pict\wmetafile8 0102}text}`, "text"},
+		{"binary data", "{\\rtf1 a\\bin3 {}\\b}", "ab"},
+		{"field instructions", `{\rtf1{\field{\*\fldinst HYPERLINK "x"}{\fldrslt link}}}`, "link"},
+		{"table", `{\rtf1\trowd\cellx1000\cellx2000\pard\intbl A\cell B\cell\row\trowd\cellx1000\cellx2000` +
+			`\pard\intbl C\par D\cell E\cell\row\pard after\par}`, "A\tB\nC D\tE\nafter"},
+		{"trailing data", `{\rtf1 text}garbage`, "text"},
+	} {
+		text, _, err := RTF2Text(strings.NewReader(c.rtf))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if text != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, text, c.want)
+		}
+	}
+}
+
+func TestRTF2TextInfo(t *testing.T) {
+	rtf := `{\rtf1\ansi\ansicpg1252{\info{\title Annual report}{\subject Finance}{\author Jane Roe}` +
+		`{\operator John Doe}{\keywords money, report}{\doccomm First draft}{\category Reports}` +
+		`{\*\company ACME}{\version3}{\creatim\yr2021\mo3\dy14\hr9\min26}{\revtim\yr2022\mo1\dy2}}` +
+		`\pard Body caf\'e9\par}`
+	text, info, err := RTF2Text(strings.NewReader(rtf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Body café" {
+		t.Errorf("text %q", text)
+	}
+	want := RTFInfo{
+		Title:    "Annual report",
+		Subject:  "Finance",
+		Author:   "Jane Roe",
+		Operator: "John Doe",
+		Keywords: "money, report",
+		Comment:  "First draft",
+		Category: "Reports",
+		Company:  "ACME",
+		Version:  "3",
+		Created:  time.Date(2021, 3, 14, 9, 26, 0, 0, time.Local),
+		Revised:  time.Date(2022, 1, 2, 0, 0, 0, 0, time.Local),
+	}
+	if info != want {
+		t.Errorf("got %+v, want %+v", info, want)
+	}
+}
+
+func TestRTF2TextInvalid(t *testing.T) {
+	for _, data := range []string{"", "{\\rt", "PK\x03\x04 not RTF"} {
+		if _, _, err := RTF2Text(strings.NewReader(data)); err != errRTFInvalid {
+			t.Errorf("%q: %v", data, err)
+		}
+	}
+	// a truncated \'hh escape
+	if _, _, err := RTF2Text(strings.NewReader(`{\rtf1 a\'e`)); err == nil {
+		t.Error("truncated escape: no error")
+	}
+}
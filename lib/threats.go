@@ -0,0 +1,299 @@
+package lib
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ---- file threats.go ----
+// Indicators of active content (fields, macros, objects, actions) which a mail gateway may want to quarantine.
+
+// kinds of threat indicators
+const (
+	ThreatDDE                  = "dde"                   // DDE or DDEAUTO field, DDE link or formula
+	ThreatExternalRelationship = "external-relationship" // relationship with TargetMode="External" (hyperlinks excepted)
+	ThreatExternalTemplate     = "external-template"     // attached template loaded from outside the package
+	ThreatOLEObject            = "ole-object"            // embedded OLE object
+	ThreatPackage              = "package"               // file wrapped by the OLE Packager
+	ThreatActiveX              = "activex"               // ActiveX control
+	ThreatMacro                = "macro"                 // VBA project
+	ThreatAutoExec             = "auto-exec"             // macro entry point run without user action
+	ThreatPDFOpenAction        = "pdf-openaction"
+	ThreatPDFJavaScript        = "pdf-javascript"
+	ThreatPDFLaunch            = "pdf-launch"
+	ThreatPDFEmbeddedFile      = "pdf-embeddedfile"
+	ThreatPDFAdditionalActions = "pdf-aa"
+	ThreatEncrypted            = "encrypted" // encrypted content which the reader could open (or not, see Detail)
+)
+
+// ThreatIndicator is one piece of active content found in a document
+type ThreatIndicator struct {
+	Kind     string `json:"kind"`
+	Location string `json:"location"` // part, stream, storage or object holding it, e.g. "word/document.xml" or "12 0 obj"
+	Detail   string `json:"detail,omitempty"`
+}
+
+const relTypeHyperlink = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+
+var (
+	ddeFieldRE   = regexp.MustCompile(`(?i)^\s*DDE(AUTO)?\b`)
+	ddeFormulaRE = regexp.MustCompile(`[A-Za-z0-9_.]+\|'[^']*'!`) // =cmd|'/c calc'!A1
+)
+
+// ScanOOXMLThreats looks for DDE fields and formulas, external relationships and templates, ActiveX controls
+// and embedded objects in an OOXML package
+func ScanOOXMLThreats(r io.ReaderAt, size int64) ([]ThreatIndicator, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	var found []ThreatIndicator
+	for _, f := range z.File {
+		dir := path.Dir(f.Name)
+		switch {
+		case path.Ext(f.Name) == ".rels":
+			found = append(found, scanRelationships(f)...)
+		case strings.HasPrefix(f.Name, "word/") && dir == "word" && path.Ext(f.Name) == ".xml":
+			for _, code := range readWordFieldCodes(f) {
+				if ddeFieldRE.MatchString(code) {
+					found = append(found, ThreatIndicator{Kind: ThreatDDE, Location: f.Name, Detail: code})
+				}
+			}
+		case strings.HasPrefix(f.Name, "xl/externalLinks/") && path.Ext(f.Name) == ".xml":
+			found = append(found, scanDDELinks(f)...)
+		case strings.HasPrefix(f.Name, "xl/worksheets/") && path.Ext(f.Name) == ".xml":
+			found = append(found, scanDDEFormulas(f)...)
+		case path.Base(dir) == "activeX" && path.Ext(f.Name) == ".xml":
+			found = append(found, ThreatIndicator{Kind: ThreatActiveX, Location: f.Name})
+		}
+	}
+	objects, err := ExtractEmbeddedOOXML(r, size)
+	found = append(found, objectThreats(objects)...)
+	return found, err
+}
+
+// ScanCFBThreats looks for DDE fields and embedded objects in a legacy Word, Excel or PowerPoint file
+func ScanCFBThreats(r io.ReaderAt, size int64) ([]ThreatIndicator, error) {
+	var found []ThreatIndicator
+	if codes, err := DOCFieldCodes(io.NewSectionReader(r, 0, size)); err == nil {
+		for _, code := range codes {
+			if ddeFieldRE.MatchString(code) {
+				found = append(found, ThreatIndicator{Kind: ThreatDDE, Location: "WordDocument", Detail: code})
+			}
+		}
+	}
+	objects, err := ExtractEmbeddedCFB(r, size)
+	found = append(found, objectThreats(objects)...)
+	return found, err
+}
+
+// ScanPDFThreats looks for open actions, additional actions, JavaScript, Launch actions, embedded files
+// and encryption in a PDF file
+func ScanPDFThreats(r io.ReaderAt, size int64) ([]ThreatIndicator, error) {
+	return ScanEncryptedPDFThreats(r, size, nil)
+}
+
+// ScanEncryptedPDFThreats is ScanPDFThreats for files which may need a password to be opened
+func ScanEncryptedPDFThreats(r io.ReaderAt, size int64, passwords PasswordCallback) ([]ThreatIndicator, error) {
+	reader, _, err := OpenPDF(r, size, passwords)
+	if err == ErrPasswordRequired || err == ErrInvalidPassword {
+		return []ThreatIndicator{{Kind: ThreatEncrypted, Location: "trailer", Detail: err.Error()}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	kinds := map[string]string{
+		"OpenAction":   ThreatPDFOpenAction,
+		"AA":           ThreatPDFAdditionalActions,
+		"JavaScript":   ThreatPDFJavaScript,
+		"Launch":       ThreatPDFLaunch,
+		"EmbeddedFile": ThreatPDFEmbeddedFile,
+		"Encrypt":      ThreatEncrypted,
+	}
+	var found []ThreatIndicator
+	for _, a := range reader.ActiveContent() {
+		found = append(found, ThreatIndicator{Kind: kinds[a.Key], Location: a.Object, Detail: a.Detail})
+	}
+	return found, nil
+}
+
+// VBAThreats returns one indicator per VBA project and per auto-exec entry point
+func VBAThreats(projects []VBAProject) []ThreatIndicator {
+	var found []ThreatIndicator
+	for _, p := range projects {
+		var modules []string
+		for _, m := range p.Modules {
+			modules = append(modules, m.Name)
+		}
+		found = append(found, ThreatIndicator{Kind: ThreatMacro, Location: p.Location, Detail: strings.Join(modules, ", ")})
+		for _, flag := range p.AutoExec {
+			found = append(found, ThreatIndicator{Kind: ThreatAutoExec, Location: p.Location + "/" + flag.Module, Detail: flag.Keyword})
+		}
+	}
+	return found
+}
+
+func objectThreats(objects []EmbeddedObject) []ThreatIndicator {
+	var found []ThreatIndicator
+	for _, obj := range objects {
+		kind := ThreatOLEObject
+		if obj.Packaged {
+			kind = ThreatPackage
+		}
+		location, name, _ := strings.Cut(obj.Name, "!/")
+		found = append(found, ThreatIndicator{Kind: kind, Location: location, Detail: name})
+	}
+	return found
+}
+
+// scanRelationships reports the external targets of a .rels part, hyperlinks excepted
+func scanRelationships(f *zip.File) []ThreatIndicator {
+	rc, err := f.Open()
+	if err != nil {
+		return nil
+	}
+	defer rc.Close()
+	var rels struct {
+		Relationship []struct {
+			ID         string `xml:"Id,attr"`
+			Type       string `xml:"Type,attr"`
+			Target     string `xml:"Target,attr"`
+			TargetMode string `xml:"TargetMode,attr"`
+		} `xml:"Relationship"`
+	}
+	if xml.NewDecoder(rc).Decode(&rels) != nil {
+		return nil
+	}
+	var found []ThreatIndicator
+	for _, rel := range rels.Relationship {
+		if rel.TargetMode != "External" || rel.Type == relTypeHyperlink {
+			continue
+		}
+		kind := ThreatExternalRelationship
+		if strings.HasSuffix(rel.Type, "/attachedTemplate") {
+			kind = ThreatExternalTemplate
+		}
+		found = append(found, ThreatIndicator{
+			Kind:     kind,
+			Location: f.Name + "#" + rel.ID,
+			Detail:   fmt.Sprintf("%s %s", path.Base(rel.Type), rel.Target),
+		})
+	}
+	return found
+}
+
+// readWordFieldCodes returns the instructions of the complex fields (w:fldChar, w:instrText) and
+// simple fields (w:fldSimple) of a WordprocessingML part
+func readWordFieldCodes(f *zip.File) []string {
+	rc, err := f.Open()
+	if err != nil {
+		return nil
+	}
+	defer rc.Close()
+	var codes []string
+	var open []*strings.Builder // instructions of the nested fields being read
+	inInstr := false
+	d := xml.NewDecoder(rc)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "fldChar":
+				switch attrValue(t, "fldCharType") {
+				case "begin":
+					open = append(open, &strings.Builder{})
+				case "separate", "end":
+					if n := len(open); n > 0 {
+						codes = append(codes, strings.TrimSpace(open[n-1].String()))
+						open = open[:n-1]
+					}
+				}
+			case "instrText":
+				inInstr = true
+			case "fldSimple":
+				codes = append(codes, strings.TrimSpace(attrValue(t, "instr")))
+			}
+		case xml.EndElement:
+			if t.Name.Local == "instrText" {
+				inInstr = false
+			}
+		case xml.CharData:
+			if inInstr && len(open) > 0 {
+				open[len(open)-1].Write(t)
+			}
+		}
+	}
+	return codes
+}
+
+// scanDDELinks reports the DDE links of a SpreadsheetML external link part
+func scanDDELinks(f *zip.File) []ThreatIndicator {
+	rc, err := f.Open()
+	if err != nil {
+		return nil
+	}
+	defer rc.Close()
+	var found []ThreatIndicator
+	d := xml.NewDecoder(rc)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		if t, ok := tok.(xml.StartElement); ok && t.Name.Local == "ddeLink" {
+			found = append(found, ThreatIndicator{Kind: ThreatDDE, Location: f.Name,
+				Detail: attrValue(t, "ddeService") + "|" + attrValue(t, "ddeTopic")})
+		}
+	}
+	return found
+}
+
+// scanDDEFormulas reports the cell formulas of a worksheet which call a DDE server (application|'topic'!item)
+func scanDDEFormulas(f *zip.File) []ThreatIndicator {
+	rc, err := f.Open()
+	if err != nil {
+		return nil
+	}
+	defer rc.Close()
+	var found []ThreatIndicator
+	var cell string
+	var formula *bytes.Buffer
+	d := xml.NewDecoder(rc)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "c":
+				cell = attrValue(t, "r")
+			case "f":
+				formula = &bytes.Buffer{}
+			}
+		case xml.CharData:
+			if formula != nil {
+				formula.Write(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "f" && formula != nil {
+				if ddeFormulaRE.Match(formula.Bytes()) {
+					found = append(found, ThreatIndicator{Kind: ThreatDDE, Location: f.Name + "#" + cell, Detail: formula.String()})
+				}
+				formula = nil
+			}
+		}
+	}
+	return found
+}
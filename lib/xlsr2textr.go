@@ -18,6 +18,9 @@ func XLS2Text(reader io.ReadSeeker) (string, error) {
 	if err != nil || xlFile == nil {
 		return "", err
 	}
+	if xlFile.Encrypted {
+		return "", ErrEncrypted
+	}
 
 	extracted_text := ""
 	for n := 0; n < xlFile.NumSheets(); n++ {
@@ -105,6 +108,9 @@ func XLS2Cells(reader io.ReadSeeker) (cells []string, err error) {
 	if err != nil || xlFile == nil {
 		return nil, err
 	}
+	if xlFile.Encrypted {
+		return nil, ErrEncrypted
+	}
 
 	for n := 0; n < xlFile.NumSheets(); n++ {
 		if sheet1 := xlFile.GetSheet(n); sheet1 != nil {
@@ -0,0 +1,66 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+ Unless required by applicable law or agreed to in writing,
+ software distributed under the License is distributed on an
+ "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ KIND, either express or implied.  See the License for the
+ specific language governing permissions and limitations
+ under the License.
+*/
+
+package gh0ffice
+
+import (
+	"io"
+	"os"
+
+	"github.com/WhityGhost/gh0ffice/lib"
+)
+
+type MessageReader func(io.ReaderAt, int64) (*lib.Message, error)
+
+// Read the header and body of an email (*.msg, *.eml) and insert into the interface: the subject is the title,
+// the sender is the creator, the sending date is the creation time, and the content starts with the header lines.
+// The message is returned for its attachments, also when it could only be read in part.
+func insertMessageData(data *Document, reader MessageReader) (*lib.Message, error) {
+	file, err := os.Open(data.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	fileinfo, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	msg, err := reader(file, fileinfo.Size())
+	if err != nil {
+		return msg, err
+	}
+	if msg.Subject != "" {
+		data.Title = msg.Subject
+	}
+	data.Subject = msg.Subject
+	data.Creator = msg.From
+	if !msg.Date.IsZero() {
+		data.Createtime = msg.Date
+	}
+	data.Content = msg.Header() + "\n" + msg.Text()
+	return msg, nil
+}
+
+// attachments returns an EmbeddedReader of the attachments of a message already read, or of none if it is nil
+func attachments(msg *lib.Message) EmbeddedReader {
+	return func(io.ReaderAt, int64) ([]lib.EmbeddedObject, error) {
+		if msg == nil {
+			return nil, nil
+		}
+		return msg.Attachments, nil
+	}
+}
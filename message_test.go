@@ -0,0 +1,53 @@
+package gh0ffice
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/WhityGhost/gh0ffice/lib"
+)
+
+const testEML = "From: Ana <ana@example.com>\r\nTo: bo@example.com\r\nSubject: Report\r\n" +
+	"Date: Mon, 02 Jan 2006 15:04:05 +0000\r\nMIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"b1\"\r\n\r\n" +
+	"--b1\r\nContent-Type: text/plain\r\n\r\nSee the notes.\r\n" +
+	"--b1\r\nContent-Type: application/rtf\r\nContent-Disposition: attachment; filename=\"notes.rtf\"\r\n\r\n" +
+	"{\\rtf1 attached notes}\r\n--b1--\r\n"
+
+// the message is read once, for its content and its attachments
+func TestInsertMessageData(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "mail.eml")
+	if err := os.WriteFile(name, []byte(testEML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reads := 0
+	data := Document{path: name, RePath: "/mail.eml"}
+	msg, err := insertMessageData(&data, func(r io.ReaderAt, size int64) (*lib.Message, error) {
+		reads++
+		return lib.ReadEML(r, size)
+	})
+	if err != nil || data.Title != "Report" || data.Creator != "Ana <ana@example.com>" ||
+		!strings.Contains(data.Content, "See the notes.") {
+		t.Fatalf("%v, %+v", err, data)
+	}
+	if _, err := insertEmbeddedData(&data, newSettings(nil), 0, attachments(msg)); err != nil {
+		t.Fatal(err)
+	}
+	if reads != 1 || len(data.Children) != 1 {
+		t.Fatalf("%d reads, %d children", reads, len(data.Children))
+	}
+	if child := data.Children[0]; child.RePath != "/mail.eml!/notes.rtf" || strings.TrimSpace(child.Content) != "attached notes" {
+		t.Errorf("child %s: %q", child.RePath, child.Content)
+	}
+
+	data = Document{path: name, RePath: "/mail.eml"}
+	if err := inspectContent(&data, newSettings(nil), 0); err != nil || len(data.Children) != 1 {
+		t.Errorf("inspectContent: %v, %d children", err, len(data.Children))
+	}
+	if ok, err := insertEmbeddedData(&data, newSettings(nil), 0, attachments(nil)); ok || err != nil {
+		t.Errorf("no message: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+ Unless required by applicable law or agreed to in writing,
+ software distributed under the License is distributed on an
+ "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ KIND, either express or implied.  See the License for the
+ specific language governing permissions and limitations
+ under the License.
+*/
+
+package gh0ffice
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/WhityGhost/gh0ffice/lib"
+)
+
+// Errors returned for encrypted documents
+var (
+	ErrPasswordRequired = lib.ErrPasswordRequired
+	ErrInvalidPassword  = lib.ErrInvalidPassword
+	ErrEncrypted        = lib.ErrEncrypted // legacy *.doc, *.xls or *.ppt, also matches ErrPasswordRequired
+)
+
+// Option customises a single call of InspectDocument
+type Option func(*settings)
+
+type settings struct {
+	maxDepth      int                                    // maximum nesting level of containers (archives, attachments, embedded objects)
+	maxMembers    int                                    // maximum number of members read from one container
+	maxMemberSize int64                                  // maximum unpacked size of one member, in bytes
+	maxTotalSize  int64                                  // maximum unpacked size of all members of the inspected file, in bytes
+	unpacked      int64                                  // bytes unpacked from containers so far
+	imageData     bool                                   // include the bytes of the pictures in Document.Images
+	threatScan    bool                                   // look for active content and fill Document.Threats
+	passwords     func(path string) lib.PasswordCallback // passwords of encrypted documents
+}
+
+func newSettings(opts []Option) *settings {
+	cfg := &settings{
+		maxDepth:      4,
+		maxMembers:    10000,
+		maxMemberSize: 256 << 20,
+		maxTotalSize:  1 << 30,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithArchiveLimits bounds the recursion into containers to prevent archive bombs:
+// the nesting depth, the number of members of one container, and the unpacked size of one member and of all members together
+func WithArchiveLimits(depth int, members int, memberSize int64, totalSize int64) Option {
+	return func(cfg *settings) {
+		cfg.maxDepth = depth
+		cfg.maxMembers = members
+		cfg.maxMemberSize = memberSize
+		cfg.maxTotalSize = totalSize
+	}
+}
+
+// WithImageData includes the bytes of every picture in Document.Images, which otherwise only lists them
+func WithImageData() Option {
+	return func(cfg *settings) {
+		cfg.imageData = true
+	}
+}
+
+// WithThreatScan looks for active content (DDE fields, external templates, OLE objects and packages, ActiveX,
+// macros, pdf actions and scripts, encryption) and lists it in Document.Threats, see also Document.ThreatReport
+func WithThreatScan() Option {
+	return func(cfg *settings) {
+		cfg.threatScan = true
+	}
+}
+
+// WithPassword opens password protected documents (encrypted DOCX, XLSX, PPTX, PDF, RC4 or XOR encrypted DOC, XLS, PPT)
+// with the given password. Without it only workbooks encrypted with the default password of Excel and PDF files with
+// an empty user password are read, the others fail with ErrPasswordRequired (ErrEncrypted for the legacy formats).
+func WithPassword(password string) Option {
+	return func(cfg *settings) {
+		cfg.passwords = func(string) lib.PasswordCallback {
+			return lib.Password(password)
+		}
+	}
+}
+
+// WithPasswordCallback asks callback for the passwords of encrypted documents, attempt counting from 0, until
+// one fits or it returns false; ErrInvalidPassword is returned if none fits
+func WithPasswordCallback(callback func(attempt int) (password string, ok bool)) Option {
+	return func(cfg *settings) {
+		cfg.passwords = func(string) lib.PasswordCallback {
+			return callback
+		}
+	}
+}
+
+// PasswordProvider gives the passwords to try on an encrypted document, path being the one reported in Document,
+// e.g. "mail.eml!/report.docx" for a nested file
+type PasswordProvider func(path string) []string
+
+// WithPasswordProvider asks provider for the passwords of every encrypted document, see StaticPasswords and PasswordFile
+func WithPasswordProvider(provider PasswordProvider) Option {
+	return func(cfg *settings) {
+		cfg.passwords = func(path string) lib.PasswordCallback {
+			return lib.Passwords(provider(path))
+		}
+	}
+}
+
+// StaticPasswords tries the same passwords on every encrypted document
+func StaticPasswords(passwords ...string) PasswordProvider {
+	return func(string) []string {
+		return passwords
+	}
+}
+
+// PasswordFile reads the passwords to try from a text file holding one password per line, blank lines and lines
+// starting with # being skipped. A line "pattern<TAB>password" keeps the password for the documents whose name
+// matches the pattern (see path.Match), e.g. "*.pdf\ts3cret".
+func PasswordFile(name string) (PasswordProvider, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	type entry struct{ pattern, password string }
+	var entries []entry
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if pattern, password, ok := strings.Cut(line, "\t"); ok {
+			entries = append(entries, entry{pattern, password})
+		} else {
+			entries = append(entries, entry{"", line})
+		}
+	}
+	return func(pathname string) []string {
+		var passwords []string
+		for _, e := range entries {
+			if e.pattern != "" {
+				if ok, _ := path.Match(e.pattern, path.Base(pathname)); !ok {
+					continue
+				}
+			}
+			passwords = append(passwords, e.password)
+		}
+		return passwords
+	}, nil
+}
+
+// passwordsFor returns the passwords to try on the document at path. The answers are kept, so that a document
+// opened several times (content, threat scan) asks the provider or the callback only once per attempt.
+func (cfg *settings) passwordsFor(path string) lib.PasswordCallback {
+	if cfg.passwords == nil {
+		return nil
+	}
+	passwords := cfg.passwords(path)
+	var asked []string
+	done := false
+	return func(attempt int) (string, bool) {
+		for !done && len(asked) <= attempt {
+			password, ok := passwords(len(asked))
+			if !ok {
+				done = true
+				break
+			}
+			asked = append(asked, password)
+		}
+		if attempt < len(asked) {
+			return asked[attempt], true
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,114 @@
+package gh0ffice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/WhityGhost/gh0ffice/lib"
+)
+
+func TestPasswordFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "passwords.txt")
+	content := "# passwords of the archive\r\ncommon\r\n\r\n*.pdf\tpdf-only\nreport-*.docx\treports\nlast\n"
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := PasswordFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		path string
+		want string
+	}{
+		{"/docs/scan.pdf", "common|pdf-only|last"},
+		{"/mail.eml!/report-2024.docx", "common|reports|last"},
+		{"/docs/report-2024.docx.pdf", "common|pdf-only|last"},
+		{"/docs/notes.docx", "common|last"},
+	} {
+		if got := strings.Join(provider(c.path), "|"); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.path, got, c.want)
+		}
+	}
+	if _, err := PasswordFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("missing file: no error")
+	}
+}
+
+func TestPasswordsFor(t *testing.T) {
+	if newSettings(nil).passwordsFor("/a.pdf") != nil {
+		t.Error("passwords without option")
+	}
+	calls := 0
+	cfg := newSettings([]Option{WithPasswordCallback(func(attempt int) (string, bool) {
+		calls++
+		return []string{"one", "two"}[min(attempt, 1)], attempt < 2
+	})})
+	passwords := cfg.passwordsFor("/a.pdf")
+	for _, attempt := range []int{0, 1, 0, 2, 1, 3} {
+		password, ok := passwords(attempt)
+		if want := attempt < 2; ok != want || (ok && password != []string{"one", "two"}[attempt]) {
+			t.Errorf("attempt %d: %q, %v", attempt, password, ok)
+		}
+	}
+	if calls != 3 { // the first two attempts and the end of the list, asked once each
+		t.Errorf("callback called %d times", calls)
+	}
+
+	cfg = newSettings([]Option{WithPasswordProvider(func(path string) []string {
+		return []string{"", path}
+	})})
+	if password, ok := cfg.passwordsFor("/b.docx")(0); !ok || password != "/b.docx" {
+		t.Errorf("provider: %q, %v", password, ok)
+	}
+}
+
+// the file is encrypted with the user password "user" and the owner password "owner"
+func TestEncryptionReport(t *testing.T) {
+	for _, c := range []struct {
+		opts      []Option
+		err       error
+		encrypted bool
+		mechanism string
+	}{
+		{nil, lib.ErrPasswordRequired, true, ""},
+		{[]Option{WithPassword("wrong")}, lib.ErrInvalidPassword, true, ""},
+		{[]Option{WithPasswordProvider(StaticPasswords("wrong", "user"))}, nil, true, lib.EncryptionPDFAES128},
+		{[]Option{WithPasswordCallback(func(attempt int) (string, bool) { return "owner", attempt == 0 })}, nil, true, lib.EncryptionPDFAES128},
+	} {
+		data := Document{path: "lib/pdf/testdata/aes-128.pdf", RePath: "/aes-128.pdf"}
+		err := inspectContent(&data, newSettings(c.opts), 0)
+		if err != c.err || data.Encrypted != c.encrypted || data.Encryption != c.mechanism {
+			t.Errorf("%d options: %v, encrypted %v, %q", len(c.opts), err, data.Encrypted, data.Encryption)
+		}
+		if err == nil && !strings.Contains(data.Content, "Hello, encrypted world") {
+			t.Errorf("content %q", data.Content)
+		}
+	}
+
+	// the threat scan reports the encryption, even when the content cannot be read
+	for _, c := range []struct {
+		opts   []Option
+		err    error
+		detail string
+	}{
+		{[]Option{WithPassword("password"), WithThreatScan()}, nil, lib.EncryptionStandard},
+		{[]Option{WithThreatScan()}, lib.ErrPasswordRequired, lib.ErrPasswordRequired.Error()},
+		{[]Option{WithPassword("wrong"), WithThreatScan()}, lib.ErrInvalidPassword, lib.ErrInvalidPassword.Error()},
+	} {
+		data := Document{path: "lib/testdata/encryptAES.xlsx", RePath: "/encryptAES.xlsx"}
+		err := inspectContent(&data, newSettings(c.opts), 0)
+		if err != c.err || !data.Encrypted || data.Encryption != lib.EncryptionStandard {
+			t.Errorf("encryptAES.xlsx: %v, encrypted %v, %q", err, data.Encrypted, data.Encryption)
+		}
+		want := lib.ThreatIndicator{Kind: lib.ThreatEncrypted, Location: "EncryptionInfo", Detail: c.detail}
+		if len(data.Threats) != 1 || data.Threats[0] != want {
+			t.Errorf("encryptAES.xlsx threats %+v, want %+v", data.Threats, want)
+		}
+		if err == nil && data.Content == "" {
+			t.Error("encryptAES.xlsx: no content")
+		}
+	}
+}
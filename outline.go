@@ -0,0 +1,47 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+ Unless required by applicable law or agreed to in writing,
+ software distributed under the License is distributed on an
+ "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ KIND, either express or implied.  See the License for the
+ specific language governing permissions and limitations
+ under the License.
+*/
+
+package gh0ffice
+
+import "github.com/WhityGhost/gh0ffice/lib/pdf"
+
+// Bookmark is an entry of the outline (table of contents) of a pdf file
+type Bookmark struct {
+	Title    string     `json:"title"`
+	Page     int        `json:"page,omitempty"`  // page the entry leads to
+	Label    string     `json:"label,omitempty"` // label of that page, e.g. "iv" for a preface
+	URI      string     `json:"uri,omitempty"`   // target of an entry which opens a web page or another file
+	Children []Bookmark `json:"children,omitempty"`
+}
+
+// Read the outline and the page labels of a pdf file and insert into the interface
+func insertPDFOutline(data *Document, reader *pdf.Reader) {
+	data.PageLabels = reader.PageLabels()
+	var bookmarks func(entries []pdf.Outline) []Bookmark
+	bookmarks = func(entries []pdf.Outline) []Bookmark {
+		var list []Bookmark
+		for _, e := range entries {
+			b := Bookmark{Title: e.Title, Page: e.Page, URI: e.URI, Children: bookmarks(e.Child)}
+			if e.Page > 0 && e.Page <= len(data.PageLabels) {
+				b.Label = data.PageLabels[e.Page-1]
+			}
+			list = append(list, b)
+		}
+		return list
+	}
+	data.Outline = bookmarks(reader.Outline().Child)
+}
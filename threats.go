@@ -0,0 +1,99 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+ Unless required by applicable law or agreed to in writing,
+ software distributed under the License is distributed on an
+ "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ KIND, either express or implied.  See the License for the
+ specific language governing permissions and limitations
+ under the License.
+*/
+
+package gh0ffice
+
+import (
+	"io"
+	"os"
+	"path"
+
+	"github.com/WhityGhost/gh0ffice/lib"
+)
+
+type ThreatScanner func(io.ReaderAt, int64) ([]lib.ThreatIndicator, error)
+
+// ThreatReport lists the threat indicators of a document and of every file nested into it
+type ThreatReport struct {
+	Indicators []ThreatFinding `json:"indicators"`
+}
+
+// ThreatFinding is a threat indicator with the path of the (possibly nested) file holding it
+type ThreatFinding struct {
+	Path string `json:"path"`
+	lib.ThreatIndicator
+}
+
+// ThreatReport gathers the threat indicators found in the document and its children (requires WithThreatScan)
+func (data *Document) ThreatReport() *ThreatReport {
+	report := &ThreatReport{Indicators: []ThreatFinding{}}
+	var walk func(doc *Document)
+	walk = func(doc *Document) {
+		for _, indicator := range doc.Threats {
+			report.Indicators = append(report.Indicators, ThreatFinding{Path: doc.RePath, ThreatIndicator: indicator})
+		}
+		for _, child := range doc.Children {
+			walk(child)
+		}
+	}
+	walk(data)
+	return report
+}
+
+// Scan office and pdf files for active content (DDE, external templates and relationships, OLE objects,
+// ActiveX, macros, pdf actions and scripts, encryption) and insert the indicators into the interface
+func insertThreatData(data *Document, scanner ThreatScanner) (bool, error) {
+	data.Threats = append(data.Threats, lib.VBAThreats(data.Macros)...)
+	if scanner == nil {
+		return len(data.Threats) > 0, nil
+	}
+	file, err := os.Open(data.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	fileinfo, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	found, err := scanner(file, fileinfo.Size())
+	data.Threats = append(data.Threats, found...)
+	if err != nil {
+		return false, err
+	}
+	return len(data.Threats) > 0, nil
+}
+
+// streams of the legacy office files whose encryption is reported, EncryptionInfo being that of OOXML
+var encryptionLocations = map[string]string{".doc": "WordDocument", ".xls": "Workbook", ".ppt": "PowerPoint Document"}
+
+// Insert the encryption of an office file into the interface, with the mechanism or the error met when
+// decrypting it as detail (that of pdf files is reported by their scanner)
+func insertEncryptionThreat(data *Document, err error) {
+	if !data.Encrypted || path.Ext(data.path) == ".pdf" {
+		return
+	}
+	location, ok := encryptionLocations[path.Ext(data.path)]
+	if !ok {
+		location = "EncryptionInfo"
+	}
+	detail := data.Encryption
+	if err != nil {
+		detail = err.Error()
+	}
+	data.Threats = append(data.Threats, lib.ThreatIndicator{Kind: lib.ThreatEncrypted, Location: location, Detail: detail})
+}